@@ -0,0 +1,43 @@
+package kvtxt
+
+import (
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/api"
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+	"github.com/hritikkanojiya/kvtxt/internal/worker"
+)
+
+// registerAdminRoutes wires the /admin route group.
+// Every route requires the admin key.
+func registerAdminRoutes(
+	mux *api.Router,
+	adminKey string,
+	store *storage.Storage,
+	registry *namespace.Registry,
+	c *cache.Cache,
+	cleanup *worker.CleanupWorker,
+) {
+	api.RegisterAdminRoute(mux, "/admin/stats", adminKey,
+		api.Stats(store, c), http.MethodGet)
+
+	api.RegisterAdminRoute(mux, "/admin/usage", adminKey,
+		api.ListUsage(store, registry), http.MethodGet)
+
+	api.RegisterAdminRoute(mux, "/admin/quotas/", adminKey,
+		api.Quota(store, registry), http.MethodGet, http.MethodPut, http.MethodDelete)
+
+	api.RegisterAdminRoute(mux, "/admin/cleanup", adminKey,
+		api.RunCleanup(cleanup), http.MethodPost)
+
+	api.RegisterAdminRoute(mux, "/admin/checkpoint", adminKey,
+		api.Checkpoint(store), http.MethodPost)
+
+	api.RegisterAdminRoute(mux, "/admin/vacuum", adminKey,
+		api.Vacuum(store), http.MethodPost)
+
+	api.RegisterAdminRoute(mux, "/admin/cache/flush", adminKey,
+		api.FlushCache(c), http.MethodPost)
+}
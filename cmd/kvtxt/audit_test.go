@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/audit"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func TestAuditVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := audit.New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record(&audit.Event{Action: audit.ActionCreate, Outcome: audit.OutcomeOK}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runAudit([]string{"verify", "--db-path", path}); code != 0 {
+		t.Errorf("verify = %d, want 0", code)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Error("verify changed the database")
+	}
+}
+
+func TestAuditVerifyMissingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo.db")
+
+	if code := runAudit([]string{"verify", "--db-path", path}); code != 1 {
+		t.Errorf("verify = %d, want 1", code)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("verify created the database: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Client commands talk to a running kvtxt server.
+//
+// Usage:
+//
+//	echo secret | kvtxt put [--ttl 1h] [--content-type type] [--key key] [file]
+//	kvtxt get <key|url>
+//	kvtxt rm <key|url>
+//
+// The server URL and API key are read from the client config file
+// (KVTXT_CLIENT_CONFIG, default <user config dir>/kvtxt/client.yaml),
+// overridden by KVTXT_URL and KVTXT_API_KEY, then by --url.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes let scripts tell failures apart.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitExpired  = 4
+	exitAuth     = 5
+	exitConflict = 6
+)
+
+const (
+	defaultServerURL = "http://localhost:8080"
+	clientTimeout    = 30 * time.Second
+)
+
+type clientConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
+func newClientFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	serverURL := fs.String("url", "", "server URL (env KVTXT_URL)")
+	return fs, serverURL
+}
+
+// loadClientConfig resolves the server URL and API key.
+// Precedence is flag > environment > client config file.
+func loadClientConfig(flagURL string) (*clientConfig, error) {
+	cfg := clientConfig{URL: defaultServerURL}
+
+	path := os.Getenv("KVTXT_CLIENT_CONFIG")
+	explicit := path != ""
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "kvtxt", "client.yaml")
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				return nil, fmt.Errorf("client config %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("client config: %w", err)
+		}
+	}
+
+	if v := os.Getenv("KVTXT_URL"); v != "" {
+		cfg.URL = v
+	}
+	if v := os.Getenv("KVTXT_API_KEY"); v != "" {
+		cfg.APIKey = v
+	}
+	if flagURL != "" {
+		cfg.URL = flagURL
+	}
+
+	return &cfg, nil
+}
+
+// newClient returns a client for the configured server.
+func (cfg *clientConfig) newClient(baseURL string) *client.Client {
+	return client.New(baseURL,
+		client.WithAPIKey(cfg.APIKey),
+		client.WithHTTPClient(&http.Client{Timeout: clientTimeout}),
+	)
+}
+
+// target resolves a bare key or a full entry URL as printed by put.
+// A full URL overrides the configured server.
+func (cfg *clientConfig) target(keyOrURL string) (*client.Client, string, error) {
+	if !strings.Contains(keyOrURL, "://") {
+		if keyOrURL == "" {
+			return nil, "", fmt.Errorf("invalid key %q", keyOrURL)
+		}
+		return cfg.newClient(cfg.URL), keyOrURL, nil
+	}
+
+	u, err := url.Parse(keyOrURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid entry URL %q", keyOrURL)
+	}
+
+	base, key, ok := strings.Cut(u.EscapedPath(), "/v1/kv/")
+	if !ok || key == "" {
+		return nil, "", fmt.Errorf("invalid entry URL %q", keyOrURL)
+	}
+
+	key, err = url.PathUnescape(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid entry URL %q", keyOrURL)
+	}
+
+	return cfg.newClient(u.Scheme + "://" + u.Host + base), key, nil
+}
+
+func runPut(args []string) int {
+	fs, serverURL := newClientFlagSet("put")
+	ttl := fs.String("ttl", "", "time to live, e.g. 1h or 3600 (server default if unset)")
+	contentType := fs.String("content-type", "text/plain; charset=utf-8", "content type of the value")
+	key := fs.String("key", "", "store under this key instead of a generated one, e.g. team-a/config")
+	if err := fs.Parse(args); err != nil {
+		return exitCodeForFlagError(err)
+	}
+
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: kvtxt put [--ttl d] [--content-type type] [--key key] [file]")
+		return exitUsage
+	}
+
+	in := io.Reader(os.Stdin)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer f.Close()
+		in = f
+	}
+
+	value, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read value:", err)
+		return exitError
+	}
+
+	opts := []client.CreateOption{client.WithContentType(*contentType)}
+
+	if *key != "" {
+		opts = append(opts, client.WithKey(*key))
+	}
+
+	if *ttl != "" {
+		d, err := parseTTL(*ttl)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		opts = append(opts, client.WithTTL(d))
+	}
+
+	cfg, err := loadClientConfig(*serverURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	entry, err := cfg.newClient(cfg.URL).Create(context.Background(), value, opts...)
+	if err != nil {
+		return reportClientError(err)
+	}
+
+	fmt.Println(entry.URL)
+	return exitOK
+}
+
+func runGet(args []string) int {
+	fs, serverURL := newClientFlagSet("get")
+	if err := fs.Parse(args); err != nil {
+		return exitCodeForFlagError(err)
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kvtxt get <key|url>")
+		return exitUsage
+	}
+
+	cfg, err := loadClientConfig(*serverURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	c, key, err := cfg.target(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	value, err := c.Get(context.Background(), key)
+	if err != nil {
+		return reportClientError(err)
+	}
+
+	os.Stdout.Write(value.Data)
+	return exitOK
+}
+
+func runRm(args []string) int {
+	fs, serverURL := newClientFlagSet("rm")
+	if err := fs.Parse(args); err != nil {
+		return exitCodeForFlagError(err)
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kvtxt rm <key|url>")
+		return exitUsage
+	}
+
+	cfg, err := loadClientConfig(*serverURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	c, key, err := cfg.target(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	if err := c.Delete(context.Background(), key); err != nil {
+		return reportClientError(err)
+	}
+
+	return exitOK
+}
+
+// reportClientError prints err and maps it to an exit code.
+func reportClientError(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrExpired):
+		return exitExpired
+	case errors.Is(err, client.ErrUnauthorized):
+		return exitAuth
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	}
+	return exitError
+}
+
+// parseTTL accepts Go durations ("90s", "720h") or plain seconds.
+func parseTTL(s string) (time.Duration, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second || d%time.Second != 0 {
+		return 0, fmt.Errorf("invalid ttl %q", s)
+	}
+	return d, nil
+}
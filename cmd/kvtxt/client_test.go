@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/pkg/client"
+	"github.com/hritikkanojiya/kvtxt/pkg/client/clienttest"
+)
+
+func TestClientExitCodes(t *testing.T) {
+	const apiKey = "client-test-api-key-0123456789"
+
+	srv := clienttest.NewServer(apiKey)
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	configPath := filepath.Join(dir, "client.yaml")
+	if err := os.WriteFile(configPath, []byte("url: "+srv.URL+"\napi_key: "+apiKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KVTXT_CLIENT_CONFIG", configPath)
+	t.Setenv("KVTXT_URL", "")
+	t.Setenv("KVTXT_API_KEY", "")
+
+	valuePath := filepath.Join(dir, "value.txt")
+	if err := os.WriteFile(valuePath, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runPut([]string{"--key", "taken", valuePath}); code != exitOK {
+		t.Fatalf("put = %d, want %d", code, exitOK)
+	}
+
+	tests := []struct {
+		name  string
+		setup func()
+		run   func() int
+		want  int
+	}{
+		{"get", nil, func() int { return runGet([]string{"taken"}) }, exitOK},
+		{"get by URL", nil, func() int { return runGet([]string{srv.URL + "/v1/kv/taken"}) }, exitOK},
+		{"get missing", nil, func() int { return runGet([]string{"missing"}) }, exitNotFound},
+		{"get mistyped", func() { srv.FailNextError(http.StatusNotFound, client.CodeKeyMistyped) },
+			func() int { return runGet([]string{"taken"}) }, exitError},
+		{"put taken key", nil, func() int { return runPut([]string{"--key", "taken", valuePath}) }, exitConflict},
+		{"get wrong key", nil, func() int {
+			t.Setenv("KVTXT_API_KEY", "wrong")
+			defer t.Setenv("KVTXT_API_KEY", "")
+			return runGet([]string{"taken"})
+		}, exitAuth},
+		{"get expired", func() { srv.Expire("taken") }, func() int { return runGet([]string{"taken"}) }, exitExpired},
+		{"rm", nil, func() int { return runRm([]string{"taken"}) }, exitOK},
+		{"rm missing", nil, func() int { return runRm([]string{"taken"}) }, exitNotFound},
+		{"get without key", nil, func() int { return runGet(nil) }, exitUsage},
+		{"put bad ttl", nil, func() int { return runPut([]string{"--ttl", "soon", valuePath}) }, exitUsage},
+	}
+
+	for _, tt := range tests {
+		if tt.setup != nil {
+			tt.setup()
+		}
+		if got := tt.run(); got != tt.want {
+			t.Errorf("%s: exit code %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// stubServer is a minimal kvtxt API holding text entries in memory.
+type stubServer struct {
+	apiKey string
+
+	mu      sync.Mutex
+	entries map[string]string
+	ttls    map[string]int64
+}
+
+func (s *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fail := func(status int, code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"code": code, "message": code}})
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		fail(http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/kv":
+		var req struct {
+			Text       string `json:"text"`
+			TTLSeconds int64  `json:"ttl_seconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fail(http.StatusBadRequest, "BAD_REQUEST")
+			return
+		}
+
+		key = "k" + string(rune('0'+len(s.entries)))
+		s.entries[key] = req.Text
+		s.ttls[key] = req.TTLSeconds
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"key": key})
+
+	case r.Method == http.MethodGet:
+		text, ok := s.entries[key]
+		if !ok {
+			fail(http.StatusNotFound, "NOT_FOUND")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		json.NewEncoder(w).Encode(text)
+
+	case r.Method == http.MethodDelete:
+		if _, ok := s.entries[key]; !ok {
+			fail(http.StatusNotFound, "NOT_FOUND")
+			return
+		}
+		delete(s.entries, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		fail(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+	}
+}
+
+// captureStdout runs fn and returns what it wrote to stdout.
+func captureStdout(t *testing.T, fn func() int) (string, int) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	code := fn()
+	os.Stdout = stdout
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+	return string(out), code
+}
+
+func TestClientCommands(t *testing.T) {
+	const apiKey = "client-test-api-key-0123456789"
+
+	stub := &stubServer{apiKey: apiKey, entries: map[string]string{}, ttls: map[string]int64{}}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	// The file names a server that the environment overrides
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "client.yaml")
+	if err := os.WriteFile(configPath, []byte("url: http://127.0.0.1:1\napi_key: "+apiKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KVTXT_CLIENT_CONFIG", configPath)
+	t.Setenv("KVTXT_URL", srv.URL)
+	t.Setenv("KVTXT_API_KEY", "")
+
+	valuePath := filepath.Join(dir, "value.txt")
+	if err := os.WriteFile(valuePath, []byte("line one\nline two"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := captureStdout(t, func() int { return runPut([]string{"--ttl", "1h", valuePath}) })
+	if code != exitOK {
+		t.Fatalf("put = %d", code)
+	}
+
+	entryURL := strings.TrimSpace(out)
+	if entryURL != srv.URL+"/v1/kv/k0" {
+		t.Fatalf("put printed %q", out)
+	}
+	if stub.ttls["k0"] != 3600 {
+		t.Errorf("ttl_seconds = %d, want 3600", stub.ttls["k0"])
+	}
+
+	for _, target := range []string{"k0", entryURL} {
+		out, code := captureStdout(t, func() int { return runGet([]string{target}) })
+		if code != exitOK || out != "line one\nline two" {
+			t.Errorf("get %s = %d %q", target, code, out)
+		}
+	}
+
+	// --url takes precedence over the environment
+	t.Setenv("KVTXT_URL", "http://127.0.0.1:1")
+	if code := runRm([]string{"--url", srv.URL, "k0"}); code != exitOK {
+		t.Errorf("rm = %d", code)
+	}
+	t.Setenv("KVTXT_URL", srv.URL)
+
+	if code := runGet([]string{"k0"}); code != exitNotFound {
+		t.Errorf("get after rm = %d, want %d", code, exitNotFound)
+	}
+	if code := runRm([]string{"k0"}); code != exitNotFound {
+		t.Errorf("rm after rm = %d, want %d", code, exitNotFound)
+	}
+
+	t.Setenv("KVTXT_API_KEY", "wrong")
+	if code := runGet([]string{"k0"}); code != exitAuth {
+		t.Errorf("get with a wrong key = %d, want %d", code, exitAuth)
+	}
+}
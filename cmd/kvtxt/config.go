@@ -0,0 +1,44 @@
+// runConfig implements the "kvtxt config" command.
+//
+// Usage:
+//
+//	kvtxt config print [--config file] [flags]
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: kvtxt config print [--config file] [flags]")
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Print the effective configuration with secrets masked
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "encode config:", err)
+		return 1
+	}
+
+	os.Stdout.Write(out)
+	return 0
+}
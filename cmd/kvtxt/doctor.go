@@ -0,0 +1,193 @@
+// runDoctor implements the "kvtxt doctor" command.
+//
+// Usage:
+//
+//	kvtxt doctor [--config file] [flags]
+//
+// It checks the database file permissions, WAL mode, schema version,
+// whether the configured key decrypts a stored entry, and free disk
+// space. The database is opened read-only and never modified; only
+// the permission check opens the file for writing, and creates and
+// removes a temporary file next to it.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// minFreeDisk is the free space below which doctor warns.
+const minFreeDisk = 512 << 20
+
+type checkStatus string
+
+const (
+	statusOK   checkStatus = "ok"
+	statusWarn checkStatus = "warn"
+	statusFail checkStatus = "FAIL"
+)
+
+type doctor struct {
+	failed bool
+}
+
+func (d *doctor) report(status checkStatus, check, format string, args ...any) {
+	if status == statusFail {
+		d.failed = true
+	}
+	fmt.Printf("%-4s  %-14s %s\n", status, check, fmt.Sprintf(format, args...))
+}
+
+func runDoctor(args []string) int {
+	cfg, err := config.Load(args)
+	if err != nil {
+		if code := exitCodeForFlagError(err); code == 0 {
+			return 0
+		}
+		fmt.Printf("%-4s  %-14s %v\n", statusFail, "config", err)
+		return 1
+	}
+
+	d := &doctor{}
+	d.report(statusOK, "config", "valid")
+
+	d.checkPermissions(cfg.DatabaseFilePath)
+	d.checkDisk(filepath.Dir(cfg.DatabaseFilePath))
+
+	store, err := storage.OpenReadOnly(cfg.DatabaseFilePath)
+	if err != nil {
+		d.report(statusFail, "database", "%v", err)
+		return 1
+	}
+	defer store.Close()
+
+	d.checkJournalMode(store)
+	d.checkSchema(store)
+	d.checkKey(store, cfg)
+
+	if d.failed {
+		return 1
+	}
+	return 0
+}
+
+func (d *doctor) checkPermissions(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		d.report(statusFail, "db path", "%v", err)
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		d.report(statusFail, "db path", "not writable: %v", err)
+		return
+	}
+	f.Close()
+
+	// SQLite creates the -wal and -shm files next to the database
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".kvtxt-doctor-*")
+	if err != nil {
+		d.report(statusFail, "db path", "directory not writable: %v", err)
+		return
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	if info.Mode().Perm()&0o022 != 0 {
+		d.report(statusWarn, "db path", "%s is writable by other users (%s)", path, info.Mode().Perm())
+		return
+	}
+
+	d.report(statusOK, "db path", "%s (%s)", path, info.Mode().Perm())
+}
+
+func (d *doctor) checkDisk(dir string) {
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		d.report(statusWarn, "disk", "cannot determine free space: %v", err)
+		return
+	}
+
+	if free < minFreeDisk {
+		d.report(statusWarn, "disk", "only %d MB free in %s", free>>20, dir)
+		return
+	}
+
+	d.report(statusOK, "disk", "%d MB free", free>>20)
+}
+
+func (d *doctor) checkJournalMode(store *storage.Storage) {
+	mode, err := store.JournalMode()
+	if err != nil {
+		d.report(statusFail, "journal mode", "%v", err)
+		return
+	}
+
+	if mode != "wal" {
+		d.report(statusWarn, "journal mode", "%s, expected wal (set on next server start)", mode)
+		return
+	}
+
+	d.report(statusOK, "journal mode", "%s", mode)
+}
+
+func (d *doctor) checkSchema(store *storage.Storage) {
+	version, err := store.Version()
+	if err != nil {
+		d.report(statusFail, "schema", "%v", err)
+		return
+	}
+
+	switch {
+	case version > storage.SchemaVersion:
+		d.report(statusFail, "schema", "version %d is newer than this binary supports (%d)", version, storage.SchemaVersion)
+	case version < storage.SchemaVersion:
+		d.report(statusWarn, "schema", "version %d, run kvtxt migrate to upgrade to %d", version, storage.SchemaVersion)
+	default:
+		d.report(statusOK, "schema", "version %d", version)
+	}
+}
+
+func (d *doctor) checkKey(store *storage.Storage, cfg *config.Config) {
+	crypt, err := crypto.New(cfg.EncryptionKey)
+	if err != nil {
+		d.report(statusFail, "encryption key", "%v", err)
+		return
+	}
+
+	entry, err := store.SampleEntry(time.Now().Unix())
+	if err != nil {
+		d.report(statusFail, "encryption key", "read sample entry: %v", err)
+		return
+	}
+
+	if entry == nil {
+		d.report(statusOK, "encryption key", "valid, no entries to test against")
+		return
+	}
+
+	// Mirror the registry: only non-default namespaces use derived keys
+	nsCrypt := crypt
+	if entry.Namespace != constant.DefaultNamespace {
+		if nsCrypt, err = crypt.ForNamespace(entry.Namespace); err != nil {
+			d.report(statusFail, "encryption key", "%v", err)
+			return
+		}
+	}
+
+	if _, err := nsCrypt.Decrypt(entry.Payload); err != nil {
+		d.report(statusFail, "encryption key", "cannot decrypt entry %s/%s: wrong key?", entry.Namespace, entry.Hash)
+		return
+	}
+
+	d.report(statusOK, "encryption key", "decrypts entry %s/%s", entry.Namespace, entry.Hash)
+}
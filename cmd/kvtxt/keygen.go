@@ -0,0 +1,35 @@
+// runKeygen implements the "kvtxt keygen" command.
+//
+// Usage:
+//
+//	kvtxt keygen
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+)
+
+func runKeygen(args []string) int {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kvtxt keygen")
+		fmt.Fprintln(fs.Output(), "Prints a random base64 32-byte key for KVTXT_ENCRYPTION_KEY.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitCodeForFlagError(err)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "generate key:", err)
+		return 1
+	}
+
+	fmt.Println(key)
+	return 0
+}
@@ -1,157 +1,87 @@
 // Package main is the entry point of the kvtxt application.
-// It wires together configuration, storage, crypto layer,
-// middleware stack, and HTTP router, then starts the server.
-//
-// High-level flow:
-// 1. Load configuration
-// 2. Initialize storage and crypto
-// 3. Register routes
-// 4. Wrap with middlewares
-// 5. Start HTTP server
+// It dispatches to subcommands; running without one, or with
+// only flags, starts the server for backward compatibility.
 
 package main
 
 import (
 	"errors"
-
-	"github.com/hritikkanojiya/kvtxt/internal/api"
-	"github.com/hritikkanojiya/kvtxt/internal/cache"
-	"github.com/hritikkanojiya/kvtxt/internal/config"
-	"github.com/hritikkanojiya/kvtxt/internal/constant"
-	"github.com/hritikkanojiya/kvtxt/internal/crypto"
-	"github.com/hritikkanojiya/kvtxt/internal/storage"
-	"github.com/hritikkanojiya/kvtxt/internal/worker"
-
-	"context"
+	"flag"
+	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 )
 
-// main initializes application dependencies and starts the HTTP server.
-// The function is intentionally kept orchestration-only.
-// Business logic must never be placed here.
-func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
-
-	cfg, err := config.Load()
-	if err != nil {
-		slog.Error("configuration error", "error", err)
-		os.Exit(1)
-	}
-
-	crypt, err := crypto.New(cfg.EncryptionKey)
-	if err != nil {
-		slog.Error("crypto init error", "error", err)
-		os.Exit(1)
-	}
-
-	c := cache.New(constant.DefaultCacheSize)
-
-	store, err := storage.Open(cfg.DatabaseFilePath)
-	if err != nil {
-		slog.Error("storage init failed", "error", err)
-		os.Exit(1)
-	}
-	defer store.Close()
-
-	ctx, appCancel := context.WithCancel(context.Background())
-	defer appCancel()
+// logLevel is shared by all commands so that the server can
+// adjust it at runtime through config reloads.
+var logLevel = new(slog.LevelVar)
 
-	worker.StartCleanupWorker(
-		ctx,
-		store,
-		constant.CleanupInterval,
-	)
+const usage = `usage: kvtxt <command> [flags]
 
-	mux := http.NewServeMux()
+Commands:
+  serve    run the HTTP server (default)
+  keygen   print a new base64 encryption key
+  migrate  apply pending database schema changes, or show status
+  doctor   check the database, key and disk for problems
+  config   print the effective configuration
+  audit    verify the audit log
 
-	api.RegisterRoute(
-		mux,
-		"/liveness",
-		http.MethodGet,
-		api.Liveness(),
-	)
+Client commands:
+  put      store stdin or a file and print its URL
+  get      write a value to stdout
+  rm       delete a value
 
-	api.RegisterRoute(
-		mux,
-		"/readiness",
-		http.MethodGet,
-		api.Readiness(store),
-	)
+Run "kvtxt <command> -h" for command flags.
+`
 
-	mux.Handle(
-		"/v1/kv",
-		api.Adapter(
-			api.AllowHttpMethods(http.MethodPost)(
-				api.CreateKV(store, crypt, c),
-			),
-		),
-	)
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: logLevel,
+	}))
+	slog.SetDefault(logger)
 
-	mux.Handle(
-		"/v1/kv/",
-		api.Adapter(
-			api.AllowHttpMethods(http.MethodGet)(
-				api.GetKV(store, crypt, c),
-			),
-		),
-	)
+	os.Exit(run(os.Args[1:]))
+}
 
-	maxSizeMB := cfg.MaxPayloadSize
-	if maxSizeMB <= 0 {
-		slog.Warn("invalid max payload size, using default", "value", cfg.MaxPayloadSize)
-		maxSizeMB = constant.DefaultMaxPayloadSizeMB
+func run(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		return runServe(args)
 	}
 
-	maxPayloadSize := int64(maxSizeMB) * constant.MB
-
-	var handler http.Handler = mux
-	handler = api.MaxPayloadSize(maxPayloadSize)(handler)
-	handler = api.Logging(handler)
-	handler = api.RequestID(handler)
-
-	srv := &http.Server{
-		Addr:         cfg.AppPort,
-		Handler:      handler,
-		ReadTimeout:  constant.ReadTimeout,
-		WriteTimeout: constant.WriteTimeout,
-		IdleTimeout:  constant.IdleTimeout,
+	switch args[0] {
+	case "serve":
+		return runServe(args[1:])
+	case "keygen":
+		return runKeygen(args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
+	case "doctor":
+		return runDoctor(args[1:])
+	case "config":
+		return runConfig(args[1:])
+	case "audit":
+		return runAudit(args[1:])
+	case "put":
+		return runPut(args[1:])
+	case "get":
+		return runGet(args[1:])
+	case "rm":
+		return runRm(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
 	}
 
-	go func() {
-		slog.Info("server listening", "addr", cfg.AppPort)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("server error", "error", err)
-			os.Exit(1)
-		}
-	}()
-
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-
-	<-stop
-	slog.Info("shutdown signal received")
-
-	appCancel()
-
-	shutdownCtx, shutdownCancel := context.WithTimeout(
-		context.Background(),
-		constant.ShutdownTimeout,
-	)
-	defer shutdownCancel()
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		slog.Error("graceful shutdown failed", "error", err)
-	} else {
-		slog.Info("server stopped gracefully")
+// exitCodeForFlagError maps a flag parsing error to an exit code.
+// Asking for help is not a failure.
+func exitCodeForFlagError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
 	}
-
-	signal.Stop(stop)
-	close(stop)
+	return 2
 }
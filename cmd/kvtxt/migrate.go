@@ -0,0 +1,138 @@
+// runMigrate implements the "kvtxt migrate" command.
+//
+// Usage:
+//
+//	kvtxt migrate [--config file] [flags] [-dry-run]
+//	kvtxt migrate status [--config file] [flags]
+//
+// The database path is resolved like "kvtxt serve" does, from
+// --db-path, KVTXT_DB_PATH or the config file. Other settings are not
+// required, and a database that does not exist yet is created.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func runMigrate(args []string) int {
+	status := len(args) > 0 && args[0] == "status"
+	if status {
+		args = args[1:]
+	}
+
+	var dryRun bool
+	cfg, err := config.LoadDatabase("migrate", args, func(fs *flag.FlagSet) {
+		if !status {
+			fs.BoolVar(&dryRun, "dry-run", false, "list pending migrations without applying them")
+		}
+	})
+	if err != nil {
+		if code := exitCodeForFlagError(err); code == 0 {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	dbPath := cfg.DatabaseFilePath
+
+	if status || dryRun {
+		list, err := migrationStatus(dbPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate:", err)
+			return 1
+		}
+
+		if status {
+			printMigrationStatus(list)
+			return 0
+		}
+
+		if !printPending(list) {
+			return 1
+		}
+		return 0
+	}
+
+	store, err := storage.Open(dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		if errors.Is(err, storage.ErrSchemaTooNew) {
+			fmt.Fprintln(os.Stderr, "upgrade kvtxt to a release that supports this database")
+		}
+		return 1
+	}
+	defer store.Close()
+
+	for _, m := range store.Migrated() {
+		fmt.Printf("applied %04d %s\n", m.Version, m.Name)
+	}
+
+	fmt.Printf("schema at version %d\n", storage.SchemaVersion)
+	return 0
+}
+
+// migrationStatus inspects the database read-only. A database that
+// does not exist yet has every migration pending.
+func migrationStatus(path string) ([]storage.MigrationStatus, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return storage.KnownMigrations(), nil
+	}
+
+	store, err := storage.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	return store.Migrations()
+}
+
+func printMigrationStatus(list []storage.MigrationStatus) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+
+	for _, m := range list {
+		applied := "pending"
+		switch {
+		case m.Version > storage.SchemaVersion:
+			applied = time.Unix(m.AppliedAt, 0).UTC().Format(time.RFC3339) + " (unknown to this binary)"
+		case !m.Pending():
+			applied = time.Unix(m.AppliedAt, 0).UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+
+	tw.Flush()
+}
+
+// printPending lists what a migration would apply and reports
+// whether the database can be migrated at all.
+func printPending(list []storage.MigrationStatus) bool {
+	pending := 0
+	for _, m := range list {
+		if m.Version > storage.SchemaVersion {
+			fmt.Printf("database has migration %04d %s unknown to this binary, refusing to migrate\n", m.Version, m.Name)
+			return false
+		}
+		if m.Pending() {
+			fmt.Printf("would apply %04d %s\n", m.Version, m.Name)
+			pending++
+		}
+	}
+
+	if pending == 0 {
+		fmt.Printf("schema up to date at version %d\n", storage.SchemaVersion)
+	}
+	return true
+}
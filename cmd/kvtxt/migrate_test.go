@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// TestMigrateNewDatabase checks that migrate needs only the database
+// path, and that only applying migrations creates the file.
+func TestMigrateNewDatabase(t *testing.T) {
+	t.Setenv("KVTXT_DB_PATH", "")
+	t.Setenv("KVTXT_ENCRYPTION_KEY", "")
+
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	for _, args := range [][]string{
+		{"status", "--db-path", path},
+		{"--db-path", path, "-dry-run"},
+	} {
+		if code := runMigrate(args); code != 0 {
+			t.Errorf("migrate %v = %d, want 0", args, code)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("migrate %v created the database: %v", args, err)
+		}
+	}
+
+	list, err := migrationStatus(path)
+	if err != nil || len(list) != storage.SchemaVersion {
+		t.Fatalf("status of a missing database = %v, %v", list, err)
+	}
+	for _, m := range list {
+		if !m.Pending() {
+			t.Errorf("%04d %s applied in a missing database", m.Version, m.Name)
+		}
+	}
+
+	if code := runMigrate([]string{"--db-path", path}); code != 0 {
+		t.Fatalf("migrate = %d, want 0", code)
+	}
+
+	list, err = migrationStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range list {
+		if m.Pending() {
+			t.Errorf("%04d %s pending after migrate", m.Version, m.Name)
+		}
+	}
+
+	if code := runMigrate(nil); code != 1 {
+		t.Errorf("migrate without a database path = %d, want 1", code)
+	}
+}
+
+// TestMigrateDryRunOldDatabase checks that status and dry-run leave an
+// existing database untouched.
+func TestMigrateDryRunOldDatabase(t *testing.T) {
+	t.Setenv("KVTXT_DB_PATH", "")
+	t.Setenv("KVTXT_ENCRYPTION_KEY", "")
+
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Forget the last migration, as if the database predated it
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`DELETE FROM schema_migrations WHERE version = ?`, storage.SchemaVersion)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"status", "--db-path", path},
+		{"--db-path", path, "-dry-run"},
+	} {
+		if code := runMigrate(args); code != 0 {
+			t.Errorf("migrate %v = %d, want 0", args, code)
+		}
+
+		after, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(before, after) {
+			t.Errorf("migrate %v changed the database", args)
+		}
+	}
+
+	list, err := migrationStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := list[len(list)-1]; !last.Pending() {
+		t.Errorf("%04d %s applied by a dry run", last.Version, last.Name)
+	}
+}
@@ -0,0 +1,85 @@
+// reloader applies configuration changes on SIGHUP without
+// restarting the server or dropping connections.
+//
+// Reloadable: log level, payload limits, cache size, cleanup
+// interval, TTL bounds, key length, the content type policy and
+// namespaces (API keys, limits and quotas). Other settings only
+// take effect on restart.
+
+package main
+
+import (
+	"log/slog"
+
+	"github.com/hritikkanojiya/kvtxt"
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+)
+
+type reloader struct {
+	args     []string
+	started  *config.Config
+	logLevel *slog.LevelVar
+	app      *kvtxt.Server
+}
+
+// reload re-reads all configuration sources. An invalid
+// configuration is logged and the running one is kept.
+func (rl *reloader) reload() {
+	slog.Info("reloading configuration")
+
+	cfg, err := config.Load(rl.args)
+	if err != nil {
+		slog.Error("config reload rejected", "error", err)
+		return
+	}
+
+	if err := rl.app.Reload(cfg); err != nil {
+		slog.Error("config reload rejected", "error", err)
+		return
+	}
+
+	rl.logLevel.Set(cfg.Level())
+
+	for _, name := range restartRequired(rl.started, cfg) {
+		slog.Warn("setting changed but requires a restart", "setting", name)
+	}
+
+	slog.Info("configuration reloaded",
+		"log_level", cfg.LogLevel,
+		"cache_size", cfg.CacheSize,
+		"cleanup_interval", cfg.CleanupInterval.String(),
+		"namespaces", len(cfg.Namespaces),
+	)
+}
+
+// restartRequired lists non-reloadable settings that differ
+// between the running and the new configuration.
+func restartRequired(prev, next *config.Config) []string {
+	checks := []struct {
+		name    string
+		changed bool
+	}{
+		{"port", prev.AppPort != next.AppPort},
+		{"db_path", prev.DatabaseFilePath != next.DatabaseFilePath},
+		{"encryption_key", prev.EncryptionKey != next.EncryptionKey},
+		{"trusted_proxies", prev.TrustedProxies.String() != next.TrustedProxies.String()},
+		{"admin_key", prev.AdminKey != next.AdminKey},
+		{"audit_enabled", prev.AuditEnabled != next.AuditEnabled},
+		{"otlp_endpoint", prev.OTLPEndpoint != next.OTLPEndpoint},
+		{"service_name", prev.ServiceName != next.ServiceName},
+		{"error_format", prev.ErrorFormat != next.ErrorFormat},
+		{"read_timeout", prev.ReadTimeout != next.ReadTimeout},
+		{"write_timeout", prev.WriteTimeout != next.WriteTimeout},
+		{"idle_timeout", prev.IdleTimeout != next.IdleTimeout},
+		{"shutdown_timeout", prev.ShutdownTimeout != next.ShutdownTimeout},
+	}
+
+	var names []string
+	for _, c := range checks {
+		if c.changed {
+			names = append(names, c.name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+)
+
+// newReloader starts a server from a config file at path, holding
+// content, and returns a reloader reading the same file.
+func newReloader(t *testing.T, path, content string) *reloader {
+	t.Helper()
+
+	db := filepath.Join(t.TempDir(), "kv.db")
+	store, err := kvtxt.OpenStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	key, err := kvtxt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KVTXT_DB_PATH", db)
+	t.Setenv("KVTXT_ENCRYPTION_KEY", key)
+
+	writeConfig(t, path, content)
+
+	args := []string{"--config", path}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crypt, err := kvtxt.NewCrypto(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := kvtxt.NewServer(kvtxt.Options{Storage: store, Crypto: crypt, Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.Level())
+
+	return &reloader{args: args, started: cfg, logLevel: logLevel, app: app}
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// status returns the status of a request for a missing entry made
+// with apiKey.
+func status(rl *reloader, apiKey string) int {
+	req := httptest.NewRequest(http.MethodGet, "/v1/kv/missing", nil)
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	rec := httptest.NewRecorder()
+	rl.app.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kvtxt.yaml")
+
+	rl := newReloader(t, path, `
+log_level: info
+namespaces:
+  - name: team
+    api_keys: [old-api-key-0123456789]
+`)
+
+	if code := status(rl, "old-api-key-0123456789"); code != http.StatusNotFound {
+		t.Fatalf("old key before reload = %d", code)
+	}
+
+	// Keys rotate and the log level changes without a restart
+	writeConfig(t, path, `
+log_level: debug
+namespaces:
+  - name: team
+    api_keys: [new-api-key-0123456789]
+`)
+	rl.reload()
+
+	if code := status(rl, "old-api-key-0123456789"); code != http.StatusUnauthorized {
+		t.Errorf("old key after reload = %d, want 401", code)
+	}
+	if code := status(rl, "new-api-key-0123456789"); code != http.StatusNotFound {
+		t.Errorf("new key after reload = %d, want 404", code)
+	}
+	if rl.logLevel.Level() != slog.LevelDebug {
+		t.Errorf("log level = %s, want debug", rl.logLevel.Level())
+	}
+
+	// An invalid configuration leaves the running one in place
+	writeConfig(t, path, `
+log_level: warn
+namespaces:
+  - name: team
+    api_keys: [short]
+`)
+	rl.reload()
+
+	if code := status(rl, "new-api-key-0123456789"); code != http.StatusNotFound {
+		t.Errorf("key after rejected reload = %d, want 404", code)
+	}
+	if rl.logLevel.Level() != slog.LevelDebug {
+		t.Errorf("log level after rejected reload = %s, want debug", rl.logLevel.Level())
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	prev := config.Defaults()
+
+	next := config.Defaults()
+	next.CacheSize++
+	next.LogLevel = "debug"
+	if names := restartRequired(prev, next); len(names) != 0 {
+		t.Errorf("reloadable changes need a restart: %v", names)
+	}
+
+	next.AppPort = ":9090"
+	next.ReadTimeout++
+	next.TrustedProxies.Set("10.0.0.0/8")
+	want := []string{"port", "trusted_proxies", "read_timeout"}
+	if names := restartRequired(prev, next); !reflect.DeepEqual(names, want) {
+		t.Errorf("restartRequired = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,148 @@
+// runServe implements the "kvtxt serve" command.
+// It wires together configuration, storage, crypto layer,
+// middleware stack, and HTTP router, then starts the server.
+//
+// High-level flow:
+// 1. Load configuration
+// 2. Initialize storage and crypto
+// 3. Build the kvtxt handler (routes and middleware)
+// 4. Start HTTP server
+
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/hritikkanojiya/kvtxt"
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runServe initializes application dependencies and runs the HTTP
+// server until SIGINT or SIGTERM.
+// The function is intentionally kept orchestration-only.
+// Business logic must never be placed here.
+func runServe(args []string) int {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		slog.Error("configuration error", "error", err)
+		return 1
+	}
+
+	logLevel.Set(cfg.Level())
+
+	crypt, err := crypto.New(cfg.EncryptionKey)
+	if err != nil {
+		slog.Error("crypto init error", "error", err)
+		return 1
+	}
+
+	c := cache.New(cfg.CacheSize)
+
+	store, err := storage.Open(cfg.DatabaseFilePath)
+	if err != nil {
+		slog.Error("storage init failed", "error", err)
+		return 1
+	}
+	defer store.Close()
+
+	for _, m := range store.Migrated() {
+		slog.Info("schema migration applied", "version", m.Version, "name", m.Name)
+	}
+
+	var traceExporter *kvtxt.TraceExporter
+	if cfg.OTLPEndpoint != "" {
+		traceExporter = kvtxt.NewTraceExporter(cfg.OTLPEndpoint, cfg.ServiceName)
+		slog.Info("tracing enabled", "endpoint", cfg.OTLPEndpoint)
+	}
+
+	ctx, appCancel := context.WithCancel(context.Background())
+	defer appCancel()
+
+	app, err := kvtxt.NewServer(kvtxt.Options{
+		Storage: store,
+		Crypto:  crypt,
+		Cache:   c,
+		Config:  cfg,
+		Tracing: traceExporter,
+	})
+	if err != nil {
+		slog.Error("server init failed", "error", err)
+		return 1
+	}
+
+	app.StartCleanup(ctx)
+
+	srv := &http.Server{
+		Addr:         cfg.AppPort,
+		Handler:      app,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	go func() {
+		slog.Info("server listening", "addr", cfg.AppPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server error", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	reload := &reloader{
+		args:     args,
+		started:  cfg,
+		logLevel: logLevel,
+		app:      app,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	// SIGHUP reloads configuration; any other signal shuts down
+	for sig := range stop {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		reload.reload()
+	}
+	slog.Info("shutdown signal received")
+
+	appCancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(
+		context.Background(),
+		cfg.ShutdownTimeout,
+	)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown failed", "error", err)
+	} else {
+		slog.Info("server stopped gracefully")
+	}
+
+	if traceExporter != nil {
+		if err := traceExporter.Shutdown(shutdownCtx); err != nil {
+			slog.Error("trace flush failed", "error", err)
+		}
+	}
+
+	signal.Stop(stop)
+	close(stop)
+
+	return 0
+}
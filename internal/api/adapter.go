@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"runtime/debug"
 
+	"github.com/hritikkanojiya/kvtxt/internal/tracing"
 	"golang.org/x/exp/slog"
 )
 
@@ -17,6 +18,9 @@ type HandlerFunc func(w http.ResponseWriter, r *http.Request) *APIError
 // into a standardized HTTP response.
 func Adapter(h HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		span := tracing.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + r.Pattern)
+		span.SetAttribute("http.route", r.Pattern)
 
 		defer func() {
 			if rec := recover(); rec != nil {
@@ -24,8 +28,10 @@ func Adapter(h HandlerFunc) http.HandlerFunc {
 
 				slog.Error("panic recovered",
 					"request_id", reqID,
+					"trace_id", tracing.TraceIDFromContext(r.Context()),
 					"method", r.Method,
 					"path", r.URL.Path,
+					"client_ip", GetClientIP(r.Context()),
 					"remote_addr", r.RemoteAddr,
 					"panic", fmt.Sprintf("%v", rec),
 					"stack", string(debug.Stack()),
@@ -42,8 +48,14 @@ func Adapter(h HandlerFunc) http.HandlerFunc {
 		if err := h(w, r); err != nil {
 			reqID := GetRequestID(r.Context())
 
+			span.SetAttribute("kvtxt.error_code", string(err.Code))
+			if err.Status >= http.StatusInternalServerError {
+				span.SetError(err.Message)
+			}
+
 			slog.Error("handler error",
 				"request_id", reqID,
+				"trace_id", tracing.TraceIDFromContext(r.Context()),
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", err.Status,
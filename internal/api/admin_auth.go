@@ -0,0 +1,28 @@
+// AdminAuth guards operator-only routes with a dedicated admin key.
+// The admin key is independent of namespace API keys.
+
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+func AdminAuth(adminKey string) func(HandlerFunc) HandlerFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) *APIError {
+
+			token := bearerToken(r)
+			if adminKey == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kvtxt-admin"`)
+				return &APIError{
+					Status:  http.StatusUnauthorized,
+					Code:    ErrUnauthorized,
+					Message: "Invalid or missing admin key",
+				}
+			}
+
+			return next(w, r)
+		}
+	}
+}
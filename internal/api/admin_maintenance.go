@@ -0,0 +1,137 @@
+// Admin maintenance endpoints let operators inspect the instance
+// and run housekeeping without shell access to the container.
+
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+	"github.com/hritikkanojiya/kvtxt/internal/worker"
+)
+
+type statsResponse struct {
+	Entries        int64      `json:"entries"`
+	ExpiredPending int64      `json:"expired_pending"`
+	DBBytes        int64      `json:"db_bytes"`
+	WALBytes       int64      `json:"wal_bytes"`
+	Cache          cacheStats `json:"cache"`
+}
+
+type cacheStats struct {
+	Entries   int    `json:"entries"`
+	MaxSize   int    `json:"max_size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+func Stats(store *storage.Storage, c *cache.Cache) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		entries, err := store.EntryCount()
+		if err != nil {
+			return adminStorageError("entry count failed", err)
+		}
+
+		expired, err := store.CountExpired(time.Now().Unix())
+		if err != nil {
+			return adminStorageError("expired count failed", err)
+		}
+
+		dbBytes, walBytes, err := store.Size()
+		if err != nil {
+			return adminStorageError("size lookup failed", err)
+		}
+
+		cs := c.Stats()
+
+		WriteJSON(w, http.StatusOK, statsResponse{
+			Entries:        entries,
+			ExpiredPending: expired,
+			DBBytes:        dbBytes,
+			WALBytes:       walBytes,
+			Cache: cacheStats{
+				Entries:   cs.Size,
+				MaxSize:   cs.MaxSize,
+				Hits:      cs.Hits,
+				Misses:    cs.Misses,
+				Evictions: cs.Evictions,
+			},
+		})
+		return nil
+	}
+}
+
+// RunCleanup deletes expired entries now instead of waiting
+// for the next scheduled run.
+func RunCleanup(cw *worker.CleanupWorker) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		deleted, err := cw.RunOnce()
+		if err != nil {
+			return adminStorageError("cleanup failed", err)
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]int64{
+			"deleted": deleted,
+		})
+		return nil
+	}
+}
+
+func Checkpoint(store *storage.Storage) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		res, err := store.Checkpoint()
+		if err != nil {
+			return adminStorageError("checkpoint failed", err)
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]any{
+			"busy":         res.Busy,
+			"log_pages":    res.LogPages,
+			"checkpointed": res.Checkpointed,
+		})
+		return nil
+	}
+}
+
+func Vacuum(store *storage.Storage) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		start := time.Now()
+
+		if err := store.Vacuum(); err != nil {
+			return adminStorageError("vacuum failed", err)
+		}
+
+		dbBytes, _, err := store.Size()
+		if err != nil {
+			return adminStorageError("size lookup failed", err)
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]int64{
+			"db_bytes":    dbBytes,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		return nil
+	}
+}
+
+func FlushCache(c *cache.Cache) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		WriteJSON(w, http.StatusOK, map[string]int{
+			"flushed": c.Flush(),
+		})
+		return nil
+	}
+}
+
+func adminStorageError(msg string, err error) *APIError {
+	slog.Error(msg, "error", err)
+	return &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    ErrStorage,
+		Message: "Storage error",
+	}
+}
@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+)
+
+const testAdminKey = "admin-key-0123456789abcdef"
+
+func withAdminKey(cfg *kvtxt.Config) { cfg.AdminKey = testAdminKey }
+
+// admin sends a request with the admin key.
+func (s *testServer) admin(method, path string) *httptest.ResponseRecorder {
+	return s.do(method, path, nil, "Authorization", "Bearer "+testAdminKey)
+}
+
+func TestAdminAuth(t *testing.T) {
+	s := newServer(t, withAdminKey)
+
+	for _, token := range []string{"", testAPIKey, testAdminKey + "x"} {
+		rec := s.do(http.MethodGet, "/admin/stats", nil, "Authorization", "Bearer "+token)
+		expectError(t, rec, http.StatusUnauthorized, "UNAUTHORIZED")
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: no WWW-Authenticate header", token)
+		}
+	}
+
+	if rec := s.admin(http.MethodGet, "/admin/stats"); rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/stats = %d %s", rec.Code, rec.Body)
+	}
+
+	// Without an admin key the routes are not registered
+	s = newServer(t, nil)
+	if rec := s.admin(http.MethodGet, "/admin/stats"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /admin/stats without an admin key = %d", rec.Code)
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	s := newServer(t, withAdminKey)
+
+	key := s.create(`{"text":"a"}`)
+	s.create(`{"text":"b"}`)
+	get(t, s, key)
+
+	rec := s.admin(http.MethodGet, "/admin/stats")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/stats = %d %s", rec.Code, rec.Body)
+	}
+
+	var stats struct {
+		Entries        int64 `json:"entries"`
+		ExpiredPending int64 `json:"expired_pending"`
+		DBBytes        int64 `json:"db_bytes"`
+		Cache          struct {
+			Entries int    `json:"entries"`
+			MaxSize int    `json:"max_size"`
+			Hits    uint64 `json:"hits"`
+		} `json:"cache"`
+	}
+	decode(t, rec, &stats)
+
+	if stats.Entries != 2 || stats.ExpiredPending != 0 || stats.DBBytes == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.Cache.Entries != 2 || stats.Cache.Hits != 1 || stats.Cache.MaxSize != kvtxt.DefaultConfig().CacheSize {
+		t.Errorf("cache stats = %+v", stats.Cache)
+	}
+}
+
+func TestAdminMaintenance(t *testing.T) {
+	s := newServer(t, withAdminKey)
+
+	key := s.create(`{"text":"a"}`)
+
+	var cleanup struct {
+		Deleted *int64 `json:"deleted"`
+	}
+	rec := s.admin(http.MethodPost, "/admin/cleanup")
+	decode(t, rec, &cleanup)
+	if rec.Code != http.StatusOK || cleanup.Deleted == nil || *cleanup.Deleted != 0 {
+		t.Errorf("POST /admin/cleanup = %d %s", rec.Code, rec.Body)
+	}
+
+	var checkpoint struct {
+		Busy     *bool `json:"busy"`
+		LogPages *int  `json:"log_pages"`
+	}
+	rec = s.admin(http.MethodPost, "/admin/checkpoint")
+	decode(t, rec, &checkpoint)
+	if rec.Code != http.StatusOK || checkpoint.Busy == nil || checkpoint.LogPages == nil {
+		t.Errorf("POST /admin/checkpoint = %d %s", rec.Code, rec.Body)
+	}
+
+	var vacuum struct {
+		DBBytes int64 `json:"db_bytes"`
+	}
+	rec = s.admin(http.MethodPost, "/admin/vacuum")
+	decode(t, rec, &vacuum)
+	if rec.Code != http.StatusOK || vacuum.DBBytes == 0 {
+		t.Errorf("POST /admin/vacuum = %d %s", rec.Code, rec.Body)
+	}
+
+	var flush struct {
+		Flushed int `json:"flushed"`
+	}
+	rec = s.admin(http.MethodPost, "/admin/cache/flush")
+	decode(t, rec, &flush)
+	if rec.Code != http.StatusOK || flush.Flushed != 1 {
+		t.Errorf("POST /admin/cache/flush = %d %s", rec.Code, rec.Body)
+	}
+
+	// Entries outlive the cache and the maintenance above
+	if got := get(t, s, key); got != `"a"` {
+		t.Errorf("GET after maintenance = %q", got)
+	}
+
+	if rec := s.admin(http.MethodGet, "/admin/vacuum"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /admin/vacuum = %d", rec.Code)
+	}
+}
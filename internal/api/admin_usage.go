@@ -0,0 +1,169 @@
+// Admin usage endpoints expose per-namespace storage consumption
+// and allow operators to adjust quota limits at runtime.
+//
+// Quota overrides are persisted and take precedence over the
+// limits from the namespaces file until they are deleted.
+
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+type usageResponse struct {
+	Namespace  string `json:"namespace"`
+	Bytes      int64  `json:"bytes"`
+	Entries    int64  `json:"entries"`
+	MaxBytes   int64  `json:"max_bytes"`
+	MaxEntries int64  `json:"max_entries"`
+	Override   bool   `json:"override"`
+}
+
+type quotaRequest struct {
+	MaxBytes   *int64 `json:"max_bytes"`
+	MaxEntries *int64 `json:"max_entries"`
+}
+
+// ListUsage reports usage and effective quota of every namespace.
+func ListUsage(store *storage.Storage, reg *namespace.Registry) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		usage, err := store.ListUsage()
+		if err != nil {
+			slog.Error("list usage failed", "error", err)
+			return &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrStorage,
+				Message: "Storage error",
+			}
+		}
+
+		used := make(map[string]storage.Usage, len(usage))
+		for _, u := range usage {
+			used[u.Namespace] = u
+		}
+
+		resp := make([]usageResponse, 0, len(reg.Names()))
+		for _, name := range reg.Names() {
+			ns, _ := reg.Lookup(name)
+
+			item, apiErr := namespaceUsage(store, ns, used[name])
+			if apiErr != nil {
+				return apiErr
+			}
+			resp = append(resp, item)
+		}
+
+		WriteJSON(w, http.StatusOK, resp)
+		return nil
+	}
+}
+
+// Quota handles GET, PUT and DELETE on /admin/quotas/{namespace}.
+func Quota(store *storage.Storage, reg *namespace.Registry) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		name := strings.TrimPrefix(r.URL.Path, "/admin/quotas/")
+
+		ns, ok := reg.Lookup(name)
+		if !ok {
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Namespace not found",
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var req quotaRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return &APIError{
+					Status:  http.StatusBadRequest,
+					Code:    ErrInvalidJSON,
+					Message: "Invalid JSON body",
+				}
+			}
+
+			if req.MaxBytes == nil || req.MaxEntries == nil ||
+				*req.MaxBytes < 0 || *req.MaxEntries < 0 {
+				return &APIError{
+					Status:  http.StatusBadRequest,
+					Code:    ErrBadRequest,
+					Message: "max_bytes and max_entries must be non-negative integers",
+				}
+			}
+
+			err := store.SetQuotaOverride(ns.Name, storage.Quota{
+				MaxBytes:   *req.MaxBytes,
+				MaxEntries: *req.MaxEntries,
+			})
+			if err != nil {
+				slog.Error("set quota failed", "error", err)
+				return &APIError{
+					Status:  http.StatusInternalServerError,
+					Code:    ErrStorage,
+					Message: "Storage error",
+				}
+			}
+
+		case http.MethodDelete:
+			if err := store.DeleteQuotaOverride(ns.Name); err != nil {
+				slog.Error("delete quota failed", "error", err)
+				return &APIError{
+					Status:  http.StatusInternalServerError,
+					Code:    ErrStorage,
+					Message: "Storage error",
+				}
+			}
+		}
+
+		used, err := store.Usage(ns.Name)
+		if err != nil {
+			slog.Error("usage lookup failed", "error", err)
+			return &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrStorage,
+				Message: "Storage error",
+			}
+		}
+
+		item, apiErr := namespaceUsage(store, ns, used)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		WriteJSON(w, http.StatusOK, item)
+		return nil
+	}
+}
+
+func namespaceUsage(store *storage.Storage, ns *namespace.Namespace, used storage.Usage) (usageResponse, *APIError) {
+	quota := ns.Quota
+
+	override, err := store.QuotaOverride(ns.Name)
+	if err != nil {
+		slog.Error("quota lookup failed", "error", err)
+		return usageResponse{}, &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrStorage,
+			Message: "Storage error",
+		}
+	}
+	if override != nil {
+		quota = *override
+	}
+
+	return usageResponse{
+		Namespace:  ns.Name,
+		Bytes:      used.Bytes,
+		Entries:    used.Entries,
+		MaxBytes:   quota.MaxBytes,
+		MaxEntries: quota.MaxEntries,
+		Override:   override != nil,
+	}, nil
+}
@@ -2,33 +2,72 @@ package api
 
 import (
 	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
 )
 
+// discardBody drains and closes the request body so that the
+// connection can be reused after an early rejection.
+func discardBody(r *http.Request) {
+	if r.Body != nil {
+		io.Copy(io.Discard, r.Body)
+		r.Body.Close()
+	}
+}
+
+// methodNotAllowed rejects a request, listing the methods the route
+// serves in the Allow header as RFC 9110 requires.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allow string) *APIError {
+	discardBody(r)
+
+	w.Header().Set("Allow", allow)
+	return &APIError{
+		Status:  http.StatusMethodNotAllowed,
+		Code:    ErrMethodNotAllowed,
+		Message: "Method not allowed",
+	}
+}
+
+// allowHeader joins methods in a stable order.
+func allowHeader(methods []string) string {
+	methods = slices.Clone(methods)
+	slices.Sort(methods)
+	return strings.Join(slices.Compact(methods), ", ")
+}
+
 func AllowHttpMethods(methods ...string) func(HandlerFunc) HandlerFunc {
 	allowed := make(map[string]struct{}, len(methods))
 	for _, m := range methods {
 		allowed[m] = struct{}{}
 	}
+	allow := allowHeader(methods)
 
 	return func(next HandlerFunc) HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) *APIError {
 
 			if _, ok := allowed[r.Method]; !ok {
-
-				if r.Body != nil {
-					io.Copy(io.Discard, r.Body)
-					r.Body.Close()
-				}
-
-				return &APIError{
-					Status:  http.StatusMethodNotAllowed,
-					Code:    ErrMethodNotAllowed,
-					Message: "Method not allowed",
-				}
+				return methodNotAllowed(w, r, allow)
 			}
 
 			return next(w, r)
 		}
 	}
 }
+
+// ByMethod dispatches each request to the handler registered for its
+// method, so that one path can carry differently wrapped handlers.
+// Other methods are rejected like AllowHttpMethods does.
+func ByMethod(handlers map[string]HandlerFunc) HandlerFunc {
+	allow := allowHeader(slices.Collect(maps.Keys(handlers)))
+
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		h, ok := handlers[r.Method]
+		if !ok {
+			return methodNotAllowed(w, r, allow)
+		}
+
+		return h(w, r)
+	}
+}
@@ -0,0 +1,37 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestMethodNotAllowed checks that 405 responses list the methods
+// the route serves.
+func TestMethodNotAllowed(t *testing.T) {
+	s := newServer(t, withAdminKey)
+
+	tests := []struct {
+		method, path, allow string
+	}{
+		{http.MethodDelete, "/openapi.json", "GET"},
+		{http.MethodGet, "/v1/kv", "POST"},
+		{http.MethodPut, "/v1/kv:batch", "POST"},
+		{http.MethodPost, "/admin/stats", "GET"},
+		{http.MethodPost, "/admin/quotas/team", "DELETE, GET, PUT"},
+		{"TRACE", "/v1/kv/abc", "DELETE, GET, HEAD, PATCH, POST, PUT"},
+	}
+
+	for _, tt := range tests {
+		rec := s.do(tt.method, tt.path, nil, "Authorization", "Bearer "+testAdminKey)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s = %d, want 405", tt.method, tt.path, rec.Code)
+			continue
+		}
+		if got := errorCode(t, rec); got != "METHOD_NOT_ALLOWED" {
+			t.Errorf("%s %s code = %s", tt.method, tt.path, got)
+		}
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
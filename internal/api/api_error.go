@@ -7,12 +7,17 @@
 
 package api
 
+import "github.com/hritikkanojiya/kvtxt/internal/jsonschema"
+
 // APIError contains HTTP status, internal error code,
 // and a human-readable message.
 type APIError struct {
 	Status  int
 	Code    ErrorCode
 	Message string
+
+	// Violations lists the schema violations of a rejected value
+	Violations []jsonschema.Violation
 }
 
 func (e *APIError) Error() string {
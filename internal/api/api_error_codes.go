@@ -1,13 +1,79 @@
+// ErrorCode values are stable, machine-readable identifiers for
+// each kind of failure. Clients should branch on the code, never on
+// the message. Every code is listed in the README and openapi.json.
+
 package api
 
 type ErrorCode string
 
 const (
-	ErrBadRequest       ErrorCode = "BAD_REQUEST"
-	ErrInvalidJSON      ErrorCode = "INVALID_JSON"
-	ErrPayloadTooLarge  ErrorCode = "PAYLOAD_TOO_LARGE"
-	ErrMethodNotAllowed ErrorCode = "BAD_REQUEST"
-	ErrNotFound         ErrorCode = "NOT_FOUND"
-	ErrConflict         ErrorCode = "CONFLICT"
-	ErrInternal         ErrorCode = "INTERNAL_ERROR"
+	ErrBadRequest          ErrorCode = "BAD_REQUEST"
+	ErrInvalidJSON         ErrorCode = "INVALID_JSON"
+	ErrTextRequired        ErrorCode = "TEXT_REQUIRED"
+	ErrInvalidText         ErrorCode = "INVALID_TEXT"
+	ErrInvalidKey          ErrorCode = "INVALID_KEY"
+	ErrInvalidPath         ErrorCode = "INVALID_PATH"
+	ErrInvalidPatch        ErrorCode = "INVALID_PATCH"
+	ErrInvalidSchema       ErrorCode = "INVALID_SCHEMA"
+	ErrKeyMistyped         ErrorCode = "KEY_MISTYPED"
+	ErrTTLOutOfRange       ErrorCode = "TTL_OUT_OF_RANGE"
+	ErrPayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrUnsupportedType     ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrBatchTooLarge       ErrorCode = "BATCH_TOO_LARGE"
+	ErrMethodNotAllowed    ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrNotAcceptable       ErrorCode = "NOT_ACCEPTABLE"
+	ErrUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrNotFound            ErrorCode = "NOT_FOUND"
+	ErrPathNotFound        ErrorCode = "PATH_NOT_FOUND"
+	ErrKeyExpired          ErrorCode = "KEY_EXPIRED"
+	ErrConflict            ErrorCode = "CONFLICT"
+	ErrTypeMismatch        ErrorCode = "TYPE_MISMATCH"
+	ErrPatchFailed         ErrorCode = "PATCH_FAILED"
+	ErrSchemaViolation     ErrorCode = "SCHEMA_VIOLATION"
+	ErrPreconditionFailed  ErrorCode = "PRECONDITION_FAILED"
+	ErrPreconditionNeeded  ErrorCode = "PRECONDITION_REQUIRED"
+	ErrQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	ErrEncryptionFailed    ErrorCode = "ENCRYPTION_FAILED"
+	ErrDecryptionFailed    ErrorCode = "DECRYPTION_FAILED"
+	ErrKeyGenerationFailed ErrorCode = "KEY_GENERATION_FAILED"
+	ErrStorage             ErrorCode = "STORAGE_ERROR"
+	ErrNotReady            ErrorCode = "NOT_READY"
+	ErrInternal            ErrorCode = "INTERNAL_ERROR"
 )
+
+// errorTitles are the short, fixed summaries used as the problem
+// details "title" member.
+var errorTitles = map[ErrorCode]string{
+	ErrBadRequest:          "Bad request",
+	ErrInvalidJSON:         "Invalid JSON",
+	ErrTextRequired:        "Text is required",
+	ErrInvalidText:         "Invalid text",
+	ErrInvalidKey:          "Invalid key",
+	ErrInvalidPath:         "Invalid path",
+	ErrInvalidPatch:        "Invalid patch",
+	ErrInvalidSchema:       "Invalid schema",
+	ErrKeyMistyped:         "Key mistyped",
+	ErrTTLOutOfRange:       "TTL out of range",
+	ErrPayloadTooLarge:     "Payload too large",
+	ErrUnsupportedType:     "Unsupported media type",
+	ErrBatchTooLarge:       "Batch too large",
+	ErrMethodNotAllowed:    "Method not allowed",
+	ErrNotAcceptable:       "Not acceptable",
+	ErrUnauthorized:        "Unauthorized",
+	ErrNotFound:            "Not found",
+	ErrPathNotFound:        "Path not found",
+	ErrKeyExpired:          "Key expired",
+	ErrConflict:            "Conflict",
+	ErrTypeMismatch:        "Type mismatch",
+	ErrPatchFailed:         "Patch failed",
+	ErrSchemaViolation:     "Schema violation",
+	ErrPreconditionFailed:  "Precondition failed",
+	ErrPreconditionNeeded:  "Precondition required",
+	ErrQuotaExceeded:       "Quota exceeded",
+	ErrEncryptionFailed:    "Encryption failed",
+	ErrDecryptionFailed:    "Decryption failed",
+	ErrKeyGenerationFailed: "Key generation failed",
+	ErrStorage:             "Storage error",
+	ErrNotReady:            "Not ready",
+	ErrInternal:            "Internal error",
+}
@@ -23,7 +23,7 @@ func Readiness(store *storage.Storage) HandlerFunc {
 		if err := store.Ping(); err != nil {
 			return &APIError{
 				Status:  http.StatusServiceUnavailable,
-				Code:    ErrInternal,
+				Code:    ErrNotReady,
 				Message: "Storage not ready",
 			}
 		}
@@ -9,6 +9,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/tracing"
 )
 
 func Logging(next http.Handler) http.Handler {
@@ -22,9 +24,11 @@ func Logging(next http.Handler) http.Handler {
 
 		slog.Info("request",
 			"request_id", reqID,
+			"trace_id", tracing.TraceIDFromContext(r.Context()),
 			"method", r.Method,
 			"path", r.URL.Path,
 			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", GetClientIP(r.Context()),
 			"remote_addr", r.RemoteAddr,
 		)
 	})
@@ -0,0 +1,71 @@
+// Metrics is a middleware that records request counts and latency
+// by route, method, status and error code.
+//
+// It must wrap the mux directly: the mux sets r.Pattern on the
+// request it receives, which is how the route label is resolved
+// without leaking raw paths into label values.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/metrics"
+)
+
+func Metrics(m *metrics.HTTP) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+
+			labels := []string{route, r.Method, strconv.Itoa(rec.status), string(rec.code)}
+
+			m.Requests.Inc(labels...)
+			m.Duration.Observe(time.Since(start).Seconds(), labels...)
+		})
+	}
+}
+
+// MetricsHandler serves a registry in Prometheus text format.
+func MetricsHandler(reg *metrics.Registry) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		reg.Write(w)
+		return nil
+	}
+}
+
+// statusRecorder captures the response status and the API error code.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	code        ErrorCode
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) recordErrorCode(code ErrorCode) {
+	r.code = code
+}
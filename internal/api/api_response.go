@@ -6,13 +6,18 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
 )
 
 type errorResponse struct {
-	Error struct {
-		Code    ErrorCode `json:"code"`
-		Message string    `json:"message"`
-	} `json:"error"`
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code       ErrorCode              `json:"code"`
+	Message    string                 `json:"message"`
+	Violations []jsonschema.Violation `json:"violations,omitempty"`
 }
 
 func WriteJSON(w http.ResponseWriter, status int, v any) {
@@ -21,16 +26,29 @@ func WriteJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// errorCodeRecorder is implemented by response writers that
+// track the error code of a response, such as the metrics recorder.
+type errorCodeRecorder interface {
+	recordErrorCode(code ErrorCode)
+}
+
 func WriteError(w http.ResponseWriter, r *http.Request, err *APIError) {
 	reqID := GetRequestID(r.Context())
 
-	resp := errorResponse{}
-	resp.Error.Code = err.Code
-	resp.Error.Message = err.Message
+	if rec, ok := w.(errorCodeRecorder); ok {
+		rec.recordErrorCode(err.Code)
+	}
 
 	if reqID != "" {
 		w.Header().Set("X-Request-ID", reqID)
 	}
 
-	WriteJSON(w, err.Status, resp)
+	if GetErrorFormat(r.Context()) == ErrorFormatProblem {
+		writeProblem(w, err, reqID)
+		return
+	}
+
+	WriteJSON(w, err.Status, errorResponse{
+		Error: errorBody{Code: err.Code, Message: err.Message, Violations: err.Violations},
+	})
 }
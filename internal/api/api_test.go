@@ -0,0 +1,146 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+)
+
+// testAPIKey authenticates requests to the "team" namespace of
+// servers started by newServer.
+const testAPIKey = "team-api-key-0123456789"
+
+// testServer is a kvtxt server on temporary storage.
+type testServer struct {
+	t   *testing.T
+	srv *kvtxt.Server
+}
+
+// newServer starts a server with a "team" namespace allowing chosen
+// keys. edit, if not nil, adjusts the configuration first.
+func newServer(t *testing.T, edit func(cfg *kvtxt.Config)) *testServer {
+	t.Helper()
+
+	store, err := kvtxt.OpenStorage(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("OpenStorage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	key, err := kvtxt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypt, err := kvtxt.NewCrypto(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := kvtxt.DefaultConfig()
+	cfg.Namespaces = []kvtxt.Namespace{{Name: "team", APIKeys: []string{testAPIKey}}}
+	if edit != nil {
+		edit(cfg)
+	}
+
+	srv, err := kvtxt.NewServer(kvtxt.Options{Storage: store, Crypto: crypt, Config: cfg})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	return &testServer{t: t, srv: srv}
+}
+
+// do sends an authenticated request. A body that is not a string or
+// []byte is encoded as JSON. Headers are given as name, value pairs.
+func (s *testServer) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = bytes.NewBufferString(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	return s.serve(req)
+}
+
+// serve sends req as is.
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.srv.ServeHTTP(rec, req)
+	return rec
+}
+
+// create stores an entry and fails the test unless it is created.
+func (s *testServer) create(body any) string {
+	s.t.Helper()
+
+	rec := s.do(http.MethodPost, "/v1/kv", body)
+	if rec.Code != http.StatusCreated {
+		s.t.Fatalf("create %v = %d %s", body, rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Key string `json:"key"`
+	}
+	decode(s.t, rec, &resp)
+	return resp.Key
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+}
+
+// errorCode returns the code of an error response.
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	decode(t, rec, &body)
+	return body.Error.Code
+}
+
+// expectError fails the test unless rec is an error response with
+// the given status and code.
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if got := errorCode(t, rec); got != code {
+		t.Fatalf("code = %s, want %s", got, code)
+	}
+}
@@ -0,0 +1,49 @@
+// Tracing is a middleware that starts a server span per request,
+// continuing the caller's trace when a traceparent header is sent.
+//
+// Spans go to the exporter given; with a nil exporter requests are
+// not traced. It must wrap Logging so that request logs carry the
+// trace ID.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/tracing"
+)
+
+func Tracing(e *tracing.Exporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remote, _ := tracing.ParseTraceparent(r.Header.Get("traceparent"))
+
+			ctx, span := tracing.StartServer(r.Context(), e, "HTTP "+r.Method, remote)
+			if span == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			defer span.End()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			span.SetAttribute("request_id", GetRequestID(r.Context()))
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttribute("http.response.status_code", rec.status)
+			if rec.status >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+// traceSpan starts a child span of the request span.
+// It is used to time middleware steps and the cache, crypto and
+// storage calls in handlers.
+func traceSpan(r *http.Request, name string) *tracing.Span {
+	_, span := tracing.Start(r.Context(), name)
+	return span
+}
@@ -0,0 +1,61 @@
+// Audit records the outcome of create, read and delete operations.
+//
+// The middleware places an event into context; Authenticate fills in
+// the caller identity and handlers fill in the affected key.
+
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/audit"
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+)
+
+func Audit(logger *audit.Logger, action audit.Action) func(HandlerFunc) HandlerFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) *APIError {
+
+			ev := &audit.Event{
+				RequestID: GetRequestID(r.Context()),
+				Identity:  "unauthenticated",
+				ClientIP:  GetClientIP(r.Context()),
+				Action:    action,
+			}
+
+			ctx := context.WithValue(r.Context(), constant.AuditEventKey, ev)
+			err := next(w, r.WithContext(ctx))
+
+			ev.Outcome = audit.OutcomeOK
+			if err != nil {
+				ev.Outcome = string(err.Code)
+			}
+
+			if recErr := logger.Record(ev); recErr != nil {
+				slog.Error("audit record failed",
+					"request_id", ev.RequestID,
+					"action", ev.Action,
+					"error", recErr,
+				)
+			}
+
+			return err
+		}
+	}
+}
+
+func getAuditEvent(ctx context.Context) *audit.Event {
+	if v, ok := ctx.Value(constant.AuditEventKey).(*audit.Event); ok {
+		return v
+	}
+	return nil
+}
+
+// auditKey attaches the affected key to the current audit event.
+func auditKey(ctx context.Context, key string) {
+	if ev := getAuditEvent(ctx); ev != nil {
+		ev.Key = key
+	}
+}
@@ -0,0 +1,78 @@
+// Authenticate resolves the caller's namespace from the bearer token
+// and injects it into context for downstream handlers.
+//
+// When no namespaces are configured every request is served from
+// the default namespace and no credentials are required.
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+)
+
+func Authenticate(reg *namespace.Registry) func(HandlerFunc) HandlerFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) *APIError {
+
+			token := bearerToken(r)
+
+			span := traceSpan(r, "middleware.Authenticate")
+			ns, ok := reg.Authenticate(token)
+			if ok {
+				span.SetAttribute("namespace", ns.Name)
+			} else {
+				span.SetError("invalid or missing API key")
+			}
+			span.End()
+
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kvtxt"`)
+				return &APIError{
+					Status:  http.StatusUnauthorized,
+					Code:    ErrUnauthorized,
+					Message: "Invalid or missing API key",
+				}
+			}
+
+			if ev := getAuditEvent(r.Context()); ev != nil {
+				ev.Namespace = ns.Name
+				ev.Identity = "anonymous"
+				if reg.AuthEnabled() {
+					ev.Identity = ns.Name + ":" + keyID(token)
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), constant.NamespaceKey, ns)
+			return next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+func GetNamespace(ctx context.Context) *namespace.Namespace {
+	if v, ok := ctx.Value(constant.NamespaceKey).(*namespace.Namespace); ok {
+		return v
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// keyID returns a short, non-reversible identifier of an API key
+// suitable for audit trails and logs.
+func keyID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:4])
+}
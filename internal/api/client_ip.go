@@ -0,0 +1,124 @@
+// ClientIP middleware resolves the real client address and injects
+// it into context next to the request ID.
+//
+// Forwarding headers are honored only when the direct peer is a
+// trusted proxy. Untrusted peers cannot spoof their address.
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+)
+
+func ClientIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted)
+
+			ctx := context.WithValue(r.Context(), constant.ClientIPKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func GetClientIP(ctx context.Context) string {
+	if v, ok := ctx.Value(constant.ClientIPKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// resolveClientIP walks the forwarding chain from right to left,
+// skipping trusted hops. The first untrusted address is the client.
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := parseHost(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+
+	// Forwarded (RFC 7239) takes precedence over X-Forwarded-For
+	chain := forwardedFor(r.Header.Values("Forwarded"))
+	if len(chain) == 0 {
+		chain = xForwardedFor(r.Header.Values("X-Forwarded-For"))
+	}
+
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHost(chain[i])
+		if !ok {
+			// Unparseable or obfuscated hop, stop at the last known address
+			break
+		}
+
+		client = addr
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHost extracts an IP from "ip", "ip:port", "[ipv6]" or "[ipv6]:port".
+func parseHost(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func xForwardedFor(values []string) []string {
+	var chain []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				chain = append(chain, part)
+			}
+		}
+	}
+	return chain
+}
+
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(name, "for") {
+					continue
+				}
+				chain = append(chain, strings.Trim(value, `"`))
+			}
+		}
+	}
+	return chain
+}
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/api"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers []string
+		want    string
+	}{
+		{"direct peer", "203.0.113.7:4711", nil, "203.0.113.7"},
+		{"untrusted peer ignores headers", "203.0.113.7:4711", []string{"X-Forwarded-For", "198.51.100.1"}, "203.0.113.7"},
+		{"untrusted peer ignores Forwarded", "203.0.113.7:4711", []string{"Forwarded", "for=198.51.100.1"}, "203.0.113.7"},
+		{"trusted peer without headers", "10.0.0.1:4711", nil, "10.0.0.1"},
+		{"one hop", "10.0.0.1:4711", []string{"X-Forwarded-For", "203.0.113.7"}, "203.0.113.7"},
+
+		// A client may send any X-Forwarded-For; only the entries
+		// appended by trusted proxies count.
+		{"spoofed left-most entry", "10.0.0.1:4711", []string{"X-Forwarded-For", "1.1.1.1, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed trusted entry", "10.0.0.1:4711", []string{"X-Forwarded-For", "10.9.9.9, 203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"spoofed header line", "10.0.0.1:4711", []string{"X-Forwarded-For", "1.1.1.1", "X-Forwarded-For", "203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"all trusted", "10.0.0.1:4711", []string{"X-Forwarded-For", "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"ports in chain", "10.0.0.1:4711", []string{"X-Forwarded-For", "203.0.113.7:5000, 10.0.0.2:80"}, "203.0.113.7"},
+		{"empty entries", "10.0.0.1:4711", []string{"X-Forwarded-For", " , 203.0.113.7,,"}, "203.0.113.7"},
+		{"unparseable hop stops the walk", "10.0.0.1:4711", []string{"X-Forwarded-For", "203.0.113.7, bogus, 10.0.0.2"}, "10.0.0.2"},
+		{"IPv4-mapped IPv6", "[::ffff:10.0.0.1]:4711", []string{"X-Forwarded-For", "::ffff:203.0.113.7"}, "203.0.113.7"},
+
+		// IPv6
+		{"IPv6 peer", "[2001:db8::1]:4711", []string{"X-Forwarded-For", "203.0.113.7"}, "2001:db8::1"},
+		{"trusted IPv6 peer", "[2001:db8:ffff::1]:4711", []string{"X-Forwarded-For", "2001:db8::7"}, "2001:db8::7"},
+		{"bracketed IPv6 with port", "10.0.0.1:4711", []string{"X-Forwarded-For", "[2001:db8::7]:5000"}, "2001:db8::7"},
+		{"bracketed IPv6 without port", "10.0.0.1:4711", []string{"X-Forwarded-For", "[2001:db8::7]"}, "2001:db8::7"},
+
+		// Forwarded (RFC 7239)
+		{"Forwarded", "10.0.0.1:4711", []string{"Forwarded", "for=203.0.113.7;proto=https"}, "203.0.113.7"},
+		{"Forwarded wins over X-Forwarded-For", "10.0.0.1:4711", []string{"Forwarded", "for=203.0.113.7", "X-Forwarded-For", "198.51.100.1"}, "203.0.113.7"},
+		{"Forwarded spoofed left-most", "10.0.0.1:4711", []string{"Forwarded", "for=1.1.1.1, for=203.0.113.7;by=10.0.0.1"}, "203.0.113.7"},
+		{"Forwarded case-insensitive name", "10.0.0.1:4711", []string{"Forwarded", "For=203.0.113.7"}, "203.0.113.7"},
+		{"Forwarded quoted IPv6 with port", "10.0.0.1:4711", []string{"Forwarded", `for="[2001:db8::7]:4711"`}, "2001:db8::7"},
+		{"Forwarded quoted IPv4 with port", "10.0.0.1:4711", []string{"Forwarded", `for="203.0.113.7:4711"`}, "203.0.113.7"},
+		{"Forwarded across lines", "10.0.0.1:4711", []string{"Forwarded", "for=1.1.1.1", "Forwarded", "for=203.0.113.7, for=10.0.0.2"}, "203.0.113.7"},
+
+		// Malformed Forwarded values
+		{"Forwarded obfuscated", "10.0.0.1:4711", []string{"Forwarded", "for=_hidden"}, "10.0.0.1"},
+		{"Forwarded unknown", "10.0.0.1:4711", []string{"Forwarded", "for=203.0.113.7, for=unknown"}, "10.0.0.1"},
+		{"Forwarded empty value", "10.0.0.1:4711", []string{"Forwarded", "for="}, "10.0.0.1"},
+		{"Forwarded without for", "10.0.0.1:4711", []string{"Forwarded", "proto=https;by=10.0.0.1", "X-Forwarded-For", "203.0.113.7"}, "203.0.113.7"},
+		{"Forwarded garbage", "10.0.0.1:4711", []string{"Forwarded", ";;,=,for"}, "10.0.0.1"},
+		{"Forwarded unterminated quote", "10.0.0.1:4711", []string{"Forwarded", `for="203.0.113.7`}, "203.0.113.7"},
+
+		// An unparseable peer is reported as is
+		{"unparseable peer", "pipe", []string{"X-Forwarded-For", "203.0.113.7"}, "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for i := 0; i+1 < len(tt.headers); i += 2 {
+				req.Header.Add(tt.headers[i], tt.headers[i+1])
+			}
+
+			var got string
+			h := api.ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = api.GetClientIP(r.Context())
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
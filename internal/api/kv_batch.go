@@ -0,0 +1,240 @@
+// Batch endpoints create or read many entries in one round trip.
+//
+// Every item gets its own status and error, using the same codes
+// as the single-item handlers. Batch creates are written in a single
+// transaction: an item failing validation, quota or key uniqueness
+// does not affect the others, while a storage failure fails all.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+type batchCreateRequest struct {
+	Items []createRequest `json:"items"`
+}
+
+type batchCreateResult struct {
+	Status    int        `json:"status"`
+	Key       string     `json:"key,omitempty"`
+	ExpiresAt *int64     `json:"expires_at,omitempty"`
+	Error     *errorBody `json:"error,omitempty"`
+}
+
+type batchGetRequest struct {
+	Keys []string `json:"keys"`
+}
+
+type batchGetResult struct {
+	Key         string          `json:"key"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Text        json.RawMessage `json:"text,omitempty"`
+	Error       *errorBody      `json:"error,omitempty"`
+}
+
+// CreateBatch handles POST /v1/kv:batch.
+func CreateBatch(store *storage.Storage, c *cache.Cache, locks *keylock.Locker, maxItems func() int) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		ns := GetNamespace(r.Context())
+
+		var req batchCreateRequest
+		if apiErr := decodeBody(w, r, ns, &req); apiErr != nil {
+			return apiErr
+		}
+
+		if apiErr := checkBatchSize(len(req.Items), maxItems()); apiErr != nil {
+			return apiErr
+		}
+
+		results := make([]batchCreateResult, len(req.Items))
+
+		// Items that passed validation, by position in the request
+		var (
+			inserts []storage.BatchInsert
+			indexes []int
+		)
+
+		for i := range req.Items {
+			item := &req.Items[i]
+
+			entry, apiErr := newEntry(r, ns, item)
+			if apiErr != nil {
+				results[i] = batchCreateError(apiErr)
+				continue
+			}
+
+			check, apiErr := createCheck(r, store, c, locks, ns, item)
+			if apiErr == nil {
+				apiErr = check(item.Text)
+			}
+			if apiErr != nil {
+				results[i] = batchCreateError(apiErr)
+				continue
+			}
+
+			insert := storage.BatchInsert{Entry: entry}
+
+			if item.Key == "" {
+				hash, err := ns.Keys.Generate()
+				if err != nil {
+					slog.Error("key generation failed", "error", err)
+					return &APIError{
+						Status:  http.StatusInternalServerError,
+						Code:    ErrKeyGenerationFailed,
+						Message: "Key generation failed",
+					}
+				}
+				entry.Hash = hash
+				insert.Rekey = ns.Keys.Generate
+			}
+
+			inserts = append(inserts, insert)
+			indexes = append(indexes, i)
+		}
+
+		// Hold chosen keys until the cache agrees with storage, as
+		// CreateKV does
+		var chosen []string
+		for _, i := range indexes {
+			if req.Items[i].Key != "" {
+				chosen = append(chosen, cacheKey(ns.Name, req.Items[i].Key))
+			}
+		}
+		unlock := locks.LockAll(chosen)
+		defer unlock()
+
+		span := traceSpan(r, "storage.InsertBatch")
+		errs, err := store.InsertBatch(inserts, ns.Quota)
+		span.End()
+		if err != nil {
+			slog.Error("batch insert failed", "error", err)
+			return &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrStorage,
+				Message: "Storage error",
+			}
+		}
+
+		var keys []string
+
+		span = traceSpan(r, "cache.Set")
+		for n, insert := range inserts {
+			i := indexes[n]
+
+			if errs[n] != nil {
+				results[i] = batchCreateError(insertError(errs[n], req.Items[i].Key != ""))
+				continue
+			}
+
+			entry := insert.Entry
+			c.Set(cacheKey(ns.Name, entry.Hash), string(req.Items[i].Text), entry.ContentType, entry.ExpiresAtPtr())
+			keys = append(keys, entry.Hash)
+
+			results[i] = batchCreateResult{
+				Status:    http.StatusCreated,
+				Key:       entry.Hash,
+				ExpiresAt: entry.ExpiresAtPtr(),
+			}
+		}
+		span.End()
+
+		auditKey(r.Context(), strings.Join(keys, ","))
+
+		WriteJSON(w, http.StatusOK, map[string]any{"results": results})
+		return nil
+	}
+}
+
+// GetBatch handles POST /v1/kv:batchGet.
+func GetBatch(store *storage.Storage, c *cache.Cache, locks *keylock.Locker, maxItems func() int) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		ns := GetNamespace(r.Context())
+
+		var req batchGetRequest
+		if apiErr := decodeBody(w, r, ns, &req); apiErr != nil {
+			return apiErr
+		}
+
+		if apiErr := checkBatchSize(len(req.Keys), maxItems()); apiErr != nil {
+			return apiErr
+		}
+
+		auditKey(r.Context(), strings.Join(req.Keys, ","))
+
+		results := make([]batchGetResult, len(req.Keys))
+
+		for i, key := range req.Keys {
+			results[i].Key = key
+
+			if key == "" {
+				results[i].Status = http.StatusNotFound
+				results[i].Error = &errorBody{Code: ErrNotFound, Message: "Not found"}
+				continue
+			}
+
+			val, ct, apiErr := loadValue(r, store, c, locks, ns, key)
+			if apiErr != nil {
+				results[i].Status = apiErr.Status
+				results[i].Error = &errorBody{Code: apiErr.Code, Message: apiErr.Message}
+				continue
+			}
+
+			results[i].Status = http.StatusOK
+			results[i].ContentType = ct
+			results[i].Text = batchText(val, ct)
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]any{"results": results})
+		return nil
+	}
+}
+
+func checkBatchSize(n, limit int) *APIError {
+	if n == 0 {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Batch must not be empty",
+		}
+	}
+
+	if n > limit {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBatchTooLarge,
+			Message: fmt.Sprintf("Batch exceeds %d items", limit),
+		}
+	}
+
+	return nil
+}
+
+func batchCreateError(err *APIError) batchCreateResult {
+	return batchCreateResult{
+		Status: err.Status,
+		Error:  &errorBody{Code: err.Code, Message: err.Message, Violations: err.Violations},
+	}
+}
+
+// batchText returns a value in the form it was sent on create:
+// YAML and XML documents as a JSON string, other values as stored.
+func batchText(val, contentType string) json.RawMessage {
+	switch contenttype.KindOf(contenttype.MediaType(contentType)) {
+	case contenttype.YAML, contenttype.XML:
+		b, _ := json.Marshal(val)
+		return b
+	}
+
+	return json.RawMessage(val)
+}
@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+)
+
+type batchResult struct {
+	Status int    `json:"status"`
+	Key    string `json:"key"`
+	Error  *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func batchCreate(t *testing.T, s *testServer, items ...map[string]any) []batchResult {
+	t.Helper()
+
+	rec := s.do(http.MethodPost, "/v1/kv:batch", map[string]any{"items": items})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch = %d %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	decode(t, rec, &resp)
+	if len(resp.Results) != len(items) {
+		t.Fatalf("%d results for %d items", len(resp.Results), len(items))
+	}
+	return resp.Results
+}
+
+func TestBatchCreateResults(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(map[string]any{"key": "taken", "text": "x"})
+
+	results := batchCreate(t, s,
+		map[string]any{"text": "generated"},
+		map[string]any{"key": "chosen", "text": "y"},
+		map[string]any{"key": "taken", "text": "z"},
+		map[string]any{"text": "ttl", "ttl_seconds": -1},
+		map[string]any{"key": "twice", "text": "1"},
+		map[string]any{"key": "twice", "text": "2"},
+	)
+
+	tests := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusCreated, ""},
+		{http.StatusCreated, ""},
+		{http.StatusConflict, "CONFLICT"},
+		{http.StatusBadRequest, "TTL_OUT_OF_RANGE"},
+		{http.StatusCreated, ""},
+		{http.StatusConflict, "CONFLICT"},
+	}
+
+	for i, tt := range tests {
+		got := results[i]
+		if got.Status != tt.status {
+			t.Errorf("item %d: status = %d, want %d", i, got.Status, tt.status)
+		}
+		code := ""
+		if got.Error != nil {
+			code = got.Error.Code
+		}
+		if code != tt.code {
+			t.Errorf("item %d: code = %q, want %q", i, code, tt.code)
+		}
+		if (got.Key != "") != (tt.status == http.StatusCreated) {
+			t.Errorf("item %d: key = %q", i, got.Key)
+		}
+	}
+
+	if results[1].Key != "chosen" {
+		t.Errorf("chosen key = %q", results[1].Key)
+	}
+
+	// Created items read back, failed ones left nothing behind. Text
+	// is stored as the JSON string it was sent as.
+	for key, want := range map[string]string{results[0].Key: `"generated"`, "chosen": `"y"`, "taken": `"x"`, "twice": `"1"`} {
+		rec := s.do(http.MethodGet, "/v1/kv/"+key, nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want %q", key, rec.Code, rec.Body, want)
+		}
+	}
+}
+
+func TestBatchCreateSize(t *testing.T) {
+	s := newServer(t, func(cfg *kvtxt.Config) { cfg.MaxBatchSize = 2 })
+
+	items := []map[string]any{{"text": "a"}, {"text": "b"}, {"text": "c"}}
+
+	expectError(t, s.do(http.MethodPost, "/v1/kv:batch", map[string]any{"items": items}), http.StatusBadRequest, "BATCH_TOO_LARGE")
+	expectError(t, s.do(http.MethodPost, "/v1/kv:batch", map[string]any{"items": items[:0]}), http.StatusBadRequest, "BAD_REQUEST")
+	expectError(t, s.do(http.MethodPost, "/v1/kv:batchGet", map[string]any{"keys": []string{"a", "b", "c"}}), http.StatusBadRequest, "BATCH_TOO_LARGE")
+
+	if results := batchCreate(t, s, items[:2]...); results[0].Status != http.StatusCreated || results[1].Status != http.StatusCreated {
+		t.Errorf("batch at the limit = %+v", results)
+	}
+}
+
+// TestBatchCreateRacesCreate checks that a chosen key is created once
+// when batch and single creates race for it.
+func TestBatchCreateRacesCreate(t *testing.T) {
+	s := newServer(t, nil)
+
+	for n := 0; n < 20; n++ {
+		key := fmt.Sprintf("race-%d", n)
+
+		var (
+			wg            sync.WaitGroup
+			batch, single *httptest.ResponseRecorder
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			batch = s.do(http.MethodPost, "/v1/kv:batch", map[string]any{"items": []any{map[string]any{"key": key, "text": "batch"}}})
+		}()
+		go func() {
+			defer wg.Done()
+			single = s.do(http.MethodPost, "/v1/kv", map[string]any{"key": key, "text": "single"})
+		}()
+		wg.Wait()
+
+		var resp struct {
+			Results []batchResult `json:"results"`
+		}
+		decode(t, batch, &resp)
+
+		byBatch := resp.Results[0].Status == http.StatusCreated
+		bySingle := single.Code == http.StatusCreated
+		if byBatch == bySingle {
+			t.Fatalf("%s: created by batch %v, by single create %v", key, byBatch, bySingle)
+		}
+
+		want := `"batch"`
+		if bySingle {
+			want = `"single"`
+		}
+		if rec := s.do(http.MethodGet, "/v1/kv/"+key, nil); rec.Body.String() != want {
+			t.Errorf("GET %s = %q, want %q", key, rec.Body, want)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -19,14 +21,22 @@ import (
 
 	"github.com/hritikkanojiya/kvtxt/internal/cache"
 	"github.com/hritikkanojiya/kvtxt/internal/constant"
-	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
 	"github.com/hritikkanojiya/kvtxt/internal/storage"
 )
 
 type createRequest struct {
+	Key         string          `json:"key"`
 	Text        json.RawMessage `json:"text"`
 	ContentType string          `json:"content_type"`
 	TTLSeconds  *int64          `json:"ttl_seconds"`
+
+	// Schema, inline or as the key of a schema entry, that the
+	// value must conform to
+	Schema    json.RawMessage `json:"schema"`
+	SchemaKey string          `json:"schema_key"`
 }
 
 type createResponse struct {
@@ -34,177 +44,383 @@ type createResponse struct {
 	ExpiresAt *int64 `json:"expires_at,omitempty"`
 }
 
-func CreateKV(store *storage.Storage, crypt *crypto.Crypto, c *cache.Cache) HandlerFunc {
+func CreateKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) *APIError {
 		// Decode and validate request payload
 		if r.Method != http.MethodPost {
 			return &APIError{
 				Status:  http.StatusMethodNotAllowed,
-				Code:    ErrBadRequest,
-				Message: "Invalid Method",
+				Code:    ErrMethodNotAllowed,
+				Message: "Method not allowed",
 			}
 		}
 
-		defer r.Body.Close()
+		ns := GetNamespace(r.Context())
 
 		var req createRequest
+		if apiErr := decodeBody(w, r, ns, &req); apiErr != nil {
+			return apiErr
+		}
 
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			if errors.Is(err, http.ErrBodyReadAfterClose) ||
-				strings.Contains(err.Error(), "request body too large") {
-				return &APIError{
-					Status:  http.StatusRequestEntityTooLarge,
-					Code:    ErrPayloadTooLarge,
-					Message: "Request body exceeds allowed size",
+		entry, apiErr := newEntry(r, ns, &req)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		check, apiErr := createCheck(r, store, c, locks, ns, &req)
+		if apiErr != nil {
+			return apiErr
+		}
+		if apiErr := check(req.Text); apiErr != nil {
+			return apiErr
+		}
+
+		// A chosen key is tried once, a generated one until unique
+		maxAttempts := 5
+		if req.Key != "" {
+			maxAttempts = 1
+
+			// Others may update a chosen key as soon as it is stored;
+			// hold it until the cache agrees with storage
+			unlock := locks.Lock(cacheKey(ns.Name, req.Key))
+			defer unlock()
+		}
+
+		var created *storage.Entry
+
+		for i := 0; i < maxAttempts; i++ {
+			if req.Key == "" {
+				hash, err := ns.Keys.Generate()
+				if err != nil {
+					slog.Error("key generation failed", "error", err)
+					return &APIError{
+						Status:  http.StatusInternalServerError,
+						Code:    ErrKeyGenerationFailed,
+						Message: "Key generation failed",
+					}
 				}
+				entry.Hash = hash
 			}
 
-			return &APIError{
-				Status:  http.StatusBadRequest,
-				Code:    ErrInvalidJSON,
-				Message: "Invalid JSON body",
+			span := traceSpan(r, "storage.Insert")
+			err := store.Insert(entry, ns.Quota)
+			span.End()
+			if err == nil {
+				created = entry
+				break
 			}
+
+			if storage.IsUniqueConstraintError(err) && req.Key == "" {
+				continue
+			}
+
+			return insertError(err, req.Key != "")
 		}
 
-		if len(req.Text) == 0 {
+		if created == nil {
+			slog.Error("key collision retries exhausted")
 			return &APIError{
-				Status:  http.StatusBadRequest,
-				Code:    ErrInvalidJSON,
-				Message: "Text is required",
+				Status:  http.StatusInternalServerError,
+				Code:    ErrKeyGenerationFailed,
+				Message: "Could not generate unique key",
 			}
 		}
 
-		if req.ContentType == "" {
-			req.ContentType = "text/plain; charset=utf-8"
+		auditKey(r.Context(), created.Hash)
+
+		span := traceSpan(r, "cache.Set")
+		c.Set(cacheKey(ns.Name, created.Hash), string(req.Text), created.ContentType, created.ExpiresAtPtr())
+		span.End()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		w.WriteHeader(http.StatusCreated)
+
+		json.NewEncoder(w).Encode(createResponse{
+			Key:       created.Hash,
+			ExpiresAt: created.ExpiresAtPtr(),
+		})
+
+		return nil
+	}
+}
+
+// decodeBody decodes a JSON request body within the namespace
+// payload limit.
+func decodeBody(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace, v any) *APIError {
+	return decodeJSON(w, r, ns, v, false)
+}
+
+// decodeOptionalBody is decodeBody for requests whose body may be
+// empty, leaving v unchanged. Chunked requests carry no
+// Content-Length, so emptiness is only known once the body is read.
+func decodeOptionalBody(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace, v any) *APIError {
+	return decodeJSON(w, r, ns, v, true)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace, v any, optional bool) *APIError {
+	// Enforce the namespace limit, which may be below the global one
+	r.Body = http.MaxBytesReader(w, r.Body, ns.MaxPayloadSize)
+	defer r.Body.Close()
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || (optional && err == io.EOF) {
+		return nil
+	}
+
+	if errors.Is(err, http.ErrBodyReadAfterClose) ||
+		strings.Contains(err.Error(), "request body too large") {
+		return &APIError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    ErrPayloadTooLarge,
+			Message: "Request body exceeds allowed size",
 		}
+	}
 
-		switch {
-		case req.ContentType == "application/json":
-			if !json.Valid(req.Text) {
-				return &APIError{
-					Status:  http.StatusBadRequest,
-					Code:    ErrInvalidJSON,
-					Message: "Invalid JSON body",
-				}
-			}
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    ErrInvalidJSON,
+		Message: "Invalid JSON body",
+	}
+}
 
-		case len(req.ContentType) >= 5 && req.ContentType[:5] == "text/":
-			if !utf8.Valid(req.Text) {
-				return &APIError{
-					Status:  http.StatusBadRequest,
-					Code:    ErrBadRequest,
-					Message: "Invalid utf-8 text",
-				}
-			}
+// newEntry validates a create request and encrypts its value.
+// The key is only set when the client chose one. req.Text is
+// replaced by the value as stored.
+func newEntry(r *http.Request, ns *namespace.Namespace, req *createRequest) (*storage.Entry, *APIError) {
+	if len(req.Text) == 0 {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrTextRequired,
+			Message: "Text is required",
+		}
+	}
+
+	if req.Key != "" {
+		if apiErr := validateCustomKey(ns, req.Key); apiErr != nil {
+			return nil, apiErr
 		}
+	}
+
+	if req.ContentType == "" {
+		req.ContentType = "text/plain; charset=utf-8"
+	}
+
+	contentType, value, limit, apiErr := checkContent(ns, req.ContentType, req.Text)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	req.ContentType, req.Text = contentType, value
 
-		var ttl int64
+	ttlDuration, apiErr := entryTTL(ns, limit, req.TTLSeconds)
+	if apiErr != nil {
+		return nil, apiErr
+	}
 
-		if req.TTLSeconds != nil {
-			ttl = *req.TTLSeconds
-		} else {
-			ttl = int64(constant.DefaultTTL)
+	span := traceSpan(r, "crypto.Encrypt")
+	encrypted, err := ns.Crypto.Encrypt([]byte(req.Text))
+	span.End()
+	if err != nil {
+		slog.Error("encryption failed", "error", err)
+		return nil, &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrEncryptionFailed,
+			Message: "Encryption failed",
 		}
+	}
 
-		ttlDuration := time.Duration(ttl) * time.Second
+	now := time.Now()
 
-		if ttlDuration < constant.MinTTL {
-			return &APIError{
-				Status:  http.StatusBadRequest,
-				Code:    ErrBadRequest,
-				Message: "TTL must be greater than zero",
-			}
+	return &storage.Entry{
+		Namespace:   ns.Name,
+		Hash:        req.Key,
+		Payload:     encrypted,
+		ContentType: req.ContentType,
+		CreatedAt:   now.Unix(),
+		ExpiresAt: sql.NullInt64{
+			Int64: now.Add(ttlDuration).Unix(),
+			Valid: true,
+		},
+	}, nil
+}
+
+// checkContent normalizes a content type and checks it, and the
+// value, against the namespace content type policy. It returns the
+// normalized type, the value to store and the limit that applies.
+func checkContent(ns *namespace.Namespace, contentType string, text []byte) (string, []byte, contenttype.Limit, *APIError) {
+	normalized, mediaType, err := contenttype.Normalize(contentType)
+	if err != nil {
+		return "", nil, contenttype.Limit{}, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Invalid content type",
 		}
+	}
 
-		if ttlDuration > constant.MaxTTL {
-			return &APIError{
+	if !ns.ContentTypes.Allowed(mediaType) {
+		return "", nil, contenttype.Limit{}, &APIError{
+			Status:  http.StatusUnsupportedMediaType,
+			Code:    ErrUnsupportedType,
+			Message: "Content type " + mediaType + " is not allowed",
+		}
+	}
+
+	value, apiErr := validateText(mediaType, text)
+	if apiErr != nil {
+		return "", nil, contenttype.Limit{}, apiErr
+	}
+
+	limit := ns.ContentTypes.Limit(mediaType)
+	if limit.MaxSize > 0 && int64(len(value)) > limit.MaxSize {
+		return "", nil, contenttype.Limit{}, &APIError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    ErrPayloadTooLarge,
+			Message: "Value exceeds allowed size for " + mediaType,
+		}
+	}
+
+	return normalized, value, limit, nil
+}
+
+// validateText checks that a value is well-formed for its media
+// type and returns the value to store. YAML and XML values must be
+// sent as a JSON string, and the document it holds is stored.
+func validateText(mediaType string, text []byte) ([]byte, *APIError) {
+	kind := contenttype.KindOf(mediaType)
+
+	switch kind {
+	case contenttype.Other:
+		return text, nil
+
+	case contenttype.JSON:
+		if !json.Valid(text) {
+			return nil, &APIError{
 				Status:  http.StatusBadRequest,
-				Code:    ErrBadRequest,
-				Message: "TTL exceeds maximum allowed",
+				Code:    ErrInvalidJSON,
+				Message: "Invalid JSON body",
 			}
 		}
+		return text, nil
+	}
 
-		// Delegate persistence to storage layer
-		encrypted, err := crypt.Encrypt([]byte(req.Text))
-		if err != nil {
-			slog.Error("encryption failed", "error", err)
-			return &APIError{
-				Status:  http.StatusInternalServerError,
-				Code:    ErrInternal,
-				Message: "Encryption failed",
-			}
+	if !utf8.Valid(text) {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidText,
+			Message: "Invalid utf-8 text",
 		}
+	}
 
-		now := time.Now().Unix()
+	if kind == contenttype.Text {
+		return text, nil
+	}
 
-		var expires sql.NullInt64
-		expiryTime := time.Now().Add(ttlDuration)
+	var doc string
+	if err := json.Unmarshal(text, &doc); err != nil {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidText,
+			Message: "Text must be a string for " + mediaType,
+		}
+	}
 
-		expires = sql.NullInt64{
-			Int64: expiryTime.Unix(),
-			Valid: true,
+	if err := contenttype.Validate(mediaType, []byte(doc)); err != nil {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidText,
+			Message: err.Error(),
 		}
+	}
 
-		var entry *storage.Entry
+	return []byte(doc), nil
+}
 
-		const maxAttempts = 5
-		for i := 0; i < maxAttempts; i++ {
-			hash, err := storage.GenerateHash()
-			if err != nil {
-				slog.Error("hash generation failed", "error", err)
-				return &APIError{
-					Status:  http.StatusInternalServerError,
-					Code:    ErrInternal,
-					Message: "Hash generation failed",
-				}
-			}
+// entryTTL returns the requested TTL, or the namespace default,
+// within the namespace bounds and the maximum for the content type.
+func entryTTL(ns *namespace.Namespace, limit contenttype.Limit, ttlSeconds *int64) (time.Duration, *APIError) {
+	maxTTL := ns.MaxTTL
+	if limit.MaxTTL > 0 {
+		maxTTL = min(maxTTL, limit.MaxTTL)
+	}
 
-			entry = &storage.Entry{
-				Hash:        hash,
-				Payload:     encrypted,
-				ContentType: req.ContentType,
-				CreatedAt:   now,
-				ExpiresAt:   expires,
-			}
+	ttlDuration := min(ns.DefaultTTL, maxTTL)
 
-			err = store.Insert(entry)
-			if err == nil {
-				break
-			}
+	if ttlSeconds != nil {
+		ttlDuration = time.Duration(*ttlSeconds) * time.Second
+	}
 
-			if storage.IsUniqueConstraintError(err) {
-				continue
-			}
+	if ttlDuration < ns.MinTTL {
+		return 0, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrTTLOutOfRange,
+			Message: "TTL is below minimum allowed",
+		}
+	}
 
-			slog.Error("insert failed", "error", err)
-			return &APIError{
-				Status:  http.StatusInternalServerError,
-				Code:    ErrInternal,
-				Message: "Storage Error",
-			}
+	if ttlDuration > maxTTL {
+		return 0, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrTTLOutOfRange,
+			Message: "TTL exceeds maximum allowed",
 		}
+	}
 
-		if entry == nil {
-			slog.Error("hash collision retries exhausted")
-			return &APIError{
-				Status:  http.StatusInternalServerError,
-				Code:    ErrInternal,
-				Message: "Could not generate unique key",
-			}
+	return ttlDuration, nil
+}
+
+// insertError maps a failed insert to an API error. A collision is
+// a conflict for a chosen key and exhausted retries otherwise.
+func insertError(err error, chosenKey bool) *APIError {
+	switch {
+	case storage.IsUniqueConstraintError(err) && chosenKey:
+		return &APIError{
+			Status:  http.StatusConflict,
+			Code:    ErrConflict,
+			Message: "Key already exists",
 		}
 
-		c.Set(entry.Hash, string(req.Text), entry.ContentType, entry.ExpiresAtPtr())
+	case storage.IsUniqueConstraintError(err):
+		slog.Error("key collision retries exhausted")
+		return &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrKeyGenerationFailed,
+			Message: "Could not generate unique key",
+		}
 
-		w.Header().Set("Content-Type", "application/json")
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return &APIError{
+			Status:  http.StatusForbidden,
+			Code:    ErrQuotaExceeded,
+			Message: "Namespace storage quota exceeded",
+		}
+	}
 
-		w.WriteHeader(http.StatusCreated)
+	slog.Error("insert failed", "error", err)
+	return &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    ErrStorage,
+		Message: "Storage error",
+	}
+}
 
-		json.NewEncoder(w).Encode(createResponse{
-			Key:       entry.Hash,
-			ExpiresAt: entry.ExpiresAtPtr(),
-		})
+// validateCustomKey checks a client-chosen key against the
+// namespace policy and the allowed charset and length.
+func validateCustomKey(ns *namespace.Namespace, key string) *APIError {
+	if !ns.CustomKeys {
+		return &APIError{
+			Status:  http.StatusUnauthorized,
+			Code:    ErrUnauthorized,
+			Message: "Custom keys require an API key",
+		}
+	}
 
-		return nil
+	if len(key) > constant.MaxCustomKeyLength || !constant.CustomKeyPattern.MatchString(key) {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidKey,
+			Message: fmt.Sprintf("Key must be up to %d letters, digits, '.', '_' or '-', in segments separated by '/'", constant.MaxCustomKeyLength),
+		}
 	}
+
+	return nil
 }
@@ -0,0 +1,146 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// roundTrips lists a value of each kind, as sent in text and as
+// served by GET.
+var roundTrips = []struct {
+	contentType, text, want string
+}{
+	{"text/plain; charset=utf-8", `"he said \"hi\""`, `"he said \"hi\""`},
+	{"application/json", `{"a": [1, 2.50]}`, `{"a": [1, 2.50]}`},
+	{"application/vnd.api+json", `"s"`, `"s"`},
+	{"application/yaml", `"a: 1\nb: [x, \"y\"]\n"`, "a: 1\nb: [x, \"y\"]\n"},
+	{"text/x-yaml", `"\"quoted\""`, `"quoted"`},
+	{"application/ld+yaml", `"- 1\n"`, "- 1\n"},
+	{"application/xml", `"<a href=\"x\">&amp;</a>"`, `<a href="x">&amp;</a>`},
+	{"image/svg+xml", `"<svg/>"`, `<svg/>`},
+	{"application/octet-stream", `"raw"`, `"raw"`},
+}
+
+func TestCreateRoundTrip(t *testing.T) {
+	s := newServer(t, nil)
+
+	for _, tt := range roundTrips {
+		key := s.create(`{"content_type":"` + tt.contentType + `","text":` + tt.text + `}`)
+
+		rec := s.do(http.MethodGet, "/v1/kv/"+key, nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("%s: GET = %d %q, want %q", tt.contentType, rec.Code, rec.Body, tt.want)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: Content-Type = %s", tt.contentType, ct)
+		}
+
+		// The ETag of the created value holds for an update
+		etag := rec.Header().Get("ETag")
+		put := s.do(http.MethodPut, "/v1/kv/"+key, `{"text":`+tt.text+`}`, "If-Match", etag)
+		if put.Code != http.StatusOK || put.Header().Get("ETag") != etag {
+			t.Errorf("%s: PUT with the ETag of GET = %d %s, ETag %s", tt.contentType, put.Code, put.Body, put.Header().Get("ETag"))
+		}
+		if got := get(t, s, key); got != tt.want {
+			t.Errorf("%s: GET after PUT = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestBatchCreateRoundTrip(t *testing.T) {
+	s := newServer(t, nil)
+
+	// Built by hand, as json.Marshal would compact the JSON values
+	body := `{"items":[`
+	for i, tt := range roundTrips {
+		if i > 0 {
+			body += ","
+		}
+		body += `{"content_type":"` + tt.contentType + `","text":` + tt.text + `}`
+	}
+	body += "]}"
+
+	rec := s.do(http.MethodPost, "/v1/kv:batch", body)
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	decode(t, rec, &resp)
+	if len(resp.Results) != len(roundTrips) {
+		t.Fatalf("batch = %d %s", rec.Code, rec.Body)
+	}
+
+	for i, res := range resp.Results {
+		want := roundTrips[i].want
+		if res.Status != http.StatusCreated {
+			t.Errorf("%s: status = %d", roundTrips[i].contentType, res.Status)
+			continue
+		}
+		if got := get(t, s, res.Key); got != want {
+			t.Errorf("%s: GET = %q, want %q", roundTrips[i].contentType, got, want)
+		}
+	}
+}
+
+// TestBatchGetRoundTrip checks that batch reads return values as
+// they were sent, even YAML or XML documents that are valid JSON.
+func TestBatchGetRoundTrip(t *testing.T) {
+	s := newServer(t, nil)
+
+	keys := make([]string, len(roundTrips))
+	for i, tt := range roundTrips {
+		keys[i] = s.create(`{"content_type":"` + tt.contentType + `","text":` + tt.text + `}`)
+	}
+
+	rec := s.do(http.MethodPost, "/v1/kv:batchGet", map[string]any{"keys": keys})
+	var resp struct {
+		Results []struct {
+			Status int             `json:"status"`
+			Text   json.RawMessage `json:"text"`
+		} `json:"results"`
+	}
+	decode(t, rec, &resp)
+	if len(resp.Results) != len(roundTrips) {
+		t.Fatalf("batchGet = %d %s", rec.Code, rec.Body)
+	}
+
+	// Compared decoded, as responses escape HTML characters
+	for i, res := range resp.Results {
+		var got, want any
+		if err := json.Unmarshal([]byte(roundTrips[i].text), &want); err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != http.StatusOK || json.Unmarshal(res.Text, &got) != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: batchGet = %d %s, want %s", roundTrips[i].contentType, res.Status, res.Text, roundTrips[i].text)
+		}
+	}
+}
+
+func TestCreateInvalidDocuments(t *testing.T) {
+	s := newServer(t, nil)
+
+	for _, tt := range []struct {
+		contentType, text string
+	}{
+		{"application/yaml", `"a: [1"`},
+		{"application/yaml", `{"a":1}`},
+		{"application/xml", `"<a><b></a>"`},
+		{"application/xml", `"<a/><b/>"`},
+		{"text/xml", `1`},
+	} {
+		expectError(t, s.do(http.MethodPost, "/v1/kv", `{"content_type":"`+tt.contentType+`","text":`+tt.text+`}`), http.StatusBadRequest, "INVALID_TEXT")
+	}
+}
+
+// TestDocumentAsText checks that YAML served as text/plain is the
+// document, not the JSON string it was sent in.
+func TestDocumentAsText(t *testing.T) {
+	s := newServer(t, nil)
+	key := s.create(`{"content_type":"application/yaml","text":"\"quoted\"\n"}`)
+
+	rec := s.do(http.MethodGet, "/v1/kv/"+key, nil, "Accept", "text/plain")
+	if rec.Body.String() != "\"quoted\"\n" {
+		t.Errorf("GET as text/plain = %q", rec.Body)
+	}
+}
@@ -0,0 +1,55 @@
+// DeleteKV removes a stored value by key.
+// Expired entries not yet cleaned up can still be deleted.
+
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func DeleteKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		hash := keyFromPath(r)
+		if hash == "" {
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Not found",
+			}
+		}
+
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		unlock := locks.Lock(cacheKey(ns.Name, hash))
+		defer unlock()
+
+		span := traceSpan(r, "storage.Delete")
+		deleted, err := store.Delete(ns.Name, hash)
+		span.End()
+
+		if err != nil {
+			slog.Error("storage error", "error", err)
+			return &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrStorage,
+				Message: "Storage error",
+			}
+		}
+
+		// Evict even when storage had no row; the cache may be stale
+		c.Delete(cacheKey(ns.Name, hash))
+
+		if !deleted {
+			return keyNotFound(ns, hash)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
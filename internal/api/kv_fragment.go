@@ -0,0 +1,108 @@
+// Fragment reads return part of a JSON entry, selected by the
+// pointer (RFC 6901) or path (JSONPath subset) query parameter of
+// GET /v1/kv/{key}. A pointer yields one value, a path the list of
+// all values it matches.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/jsondoc"
+)
+
+// selectFragment returns the part of val selected by the request,
+// and whether a selection was requested at all.
+func selectFragment(r *http.Request, val, contentType string) (string, bool, *APIError) {
+	q := r.URL.Query()
+	if !q.Has("pointer") && !q.Has("path") {
+		return "", false, nil
+	}
+
+	if q.Has("pointer") && q.Has("path") {
+		return "", true, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Use either pointer or path, not both",
+		}
+	}
+
+	if !isJSONContentType(contentType) {
+		return "", true, &APIError{
+			Status:  http.StatusConflict,
+			Code:    ErrTypeMismatch,
+			Message: "Fragments can only be read from JSON entries",
+		}
+	}
+
+	var (
+		fragment []byte
+		err      error
+	)
+
+	if q.Has("pointer") {
+		var p jsondoc.Pointer
+		if p, err = jsondoc.ParsePointer(q.Get("pointer")); err == nil {
+			fragment, err = p.Get([]byte(val))
+		}
+	} else {
+		var p jsondoc.Path
+		if p, err = jsondoc.ParsePath(q.Get("path")); err == nil {
+			var nodes []json.RawMessage
+			if nodes, err = p.Select([]byte(val)); err == nil {
+				fragment = rawArray(nodes)
+			}
+		}
+	}
+
+	switch {
+	case err == nil:
+		return string(fragment), true, nil
+
+	case errors.Is(err, jsondoc.ErrInvalidPath):
+		return "", true, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidPath,
+			Message: err.Error(),
+		}
+
+	case errors.Is(err, jsondoc.ErrNotFound):
+		return "", true, &APIError{
+			Status:  http.StatusNotFound,
+			Code:    ErrPathNotFound,
+			Message: "No value at pointer",
+		}
+	}
+
+	return "", true, &APIError{
+		Status:  http.StatusConflict,
+		Code:    ErrTypeMismatch,
+		Message: "Stored value is not a JSON document",
+	}
+}
+
+// rawArray joins values into a JSON array. Unlike json.Marshal it
+// keeps their bytes as stored, without compacting them or escaping
+// '<', '>' and '&'.
+func rawArray(values []json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// isJSONContentType reports whether contentType is application/json
+// or a +json type such as application/merge-patch+json.
+func isJSONContentType(contentType string) bool {
+	return contenttype.KindOf(contenttype.MediaType(contentType)) == contenttype.JSON
+}
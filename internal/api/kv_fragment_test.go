@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFragments(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","content_type":"application/json","text":{"html": "<b>a & b</b>", "list": [ {"n": 1.50}, {"n": 2} ]}}`)
+	s.create(`{"key":"text","text":"{\"a\":1}"}`)
+
+	tests := []struct {
+		query, want string
+	}{
+		{"pointer=/html", `"<b>a & b</b>"`},
+		{"pointer=/list/0", `{"n": 1.50}`},
+		{"path=$.html", `["<b>a & b</b>"]`},
+		{"path=$.list[*].n", `[1.50,2]`},
+		{"path=$.list[-1]", `[{"n": 2}]`},
+		{"path=$.missing", `[]`},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		rec := s.do(http.MethodGet, "/v1/kv/doc?"+q.Encode(), nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("GET ?%s = %d %s, want %s", tt.query, rec.Code, rec.Body, tt.want)
+		}
+	}
+
+	errs := []struct {
+		key, query string
+		status     int
+		code       string
+	}{
+		{"doc", "pointer=/list/2", http.StatusNotFound, "PATH_NOT_FOUND"},
+		{"doc", "pointer=/list/01", http.StatusNotFound, "PATH_NOT_FOUND"},
+		{"doc", "pointer=list", http.StatusBadRequest, "INVALID_PATH"},
+		{"doc", "path=$..n", http.StatusBadRequest, "INVALID_PATH"},
+		{"doc", "pointer=/html&path=$.html", http.StatusBadRequest, "BAD_REQUEST"},
+		{"text", "pointer=/a", http.StatusConflict, "TYPE_MISMATCH"},
+	}
+
+	for _, tt := range errs {
+		q, _ := url.ParseQuery(tt.query)
+		expectError(t, s.do(http.MethodGet, "/v1/kv/"+tt.key+"?"+q.Encode(), nil), tt.status, tt.code)
+	}
+}
@@ -3,7 +3,9 @@
 // 1. Validate key
 // 2. Fetch from storage
 // 3. Decrypt (if required)
-// 4. Return response
+// 4. Select a fragment of JSON entries (if requested)
+// 5. Negotiate the format with the Accept header
+// 6. Return response
 
 package api
 
@@ -14,30 +16,22 @@ import (
 	"time"
 
 	"github.com/hritikkanojiya/kvtxt/internal/cache"
-	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
 	"github.com/hritikkanojiya/kvtxt/internal/storage"
 )
 
-func GetKV(store *storage.Storage, crypt *crypto.Crypto, c *cache.Cache) HandlerFunc {
+func GetKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) *APIError {
 		if r.Method != http.MethodGet {
 			return &APIError{
 				Status:  http.StatusMethodNotAllowed,
-				Code:    ErrBadRequest,
-				Message: "Invalid Method",
+				Code:    ErrMethodNotAllowed,
+				Message: "Method not allowed",
 			}
 		}
 
-		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-		if len(parts) != 3 {
-			return &APIError{
-				Status:  http.StatusNotFound,
-				Code:    ErrNotFound,
-				Message: "Not found",
-			}
-		}
-
-		hash := parts[2]
+		hash := keyFromPath(r)
 		if hash == "" {
 			return &APIError{
 				Status:  http.StatusNotFound,
@@ -46,56 +40,132 @@ func GetKV(store *storage.Storage, crypt *crypto.Crypto, c *cache.Cache) Handler
 			}
 		}
 
-		if val, ct, ok := c.Get(hash); ok {
-			w.Header().Set("Content-Type", ct)
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(val))
-			return nil
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		val, ct, apiErr := loadValue(r, store, c, locks, ns, hash)
+		if apiErr != nil {
+			return apiErr
 		}
 
-		entry, err := store.Get(hash)
-		if err != nil {
-			slog.Error("storage error", "error", err)
-			return &APIError{
-				Status:  http.StatusInternalServerError,
-				Code:    ErrInternal,
-				Message: "Storage error",
-			}
+		fragment, selected, apiErr := selectFragment(r, val, ct)
+		if apiErr != nil {
+			return apiErr
 		}
-		if entry == nil {
-			return &APIError{
-				Status:  http.StatusNotFound,
-				Code:    ErrNotFound,
-				Message: "Not found",
-			}
+		if selected {
+			val, ct = fragment, "application/json"
 		}
 
-		now := time.Now().Unix()
-		if entry.ExpiresAt.Valid && entry.ExpiresAt.Int64 <= now {
-			return &APIError{
-				Status:  http.StatusGone,
-				Code:    ErrConflict,
-				Message: "Key expired",
-			}
-		}
+		w.Header().Set("Vary", "Accept")
 
-		plaintext, err := crypt.Decrypt(entry.Payload)
-		if err != nil {
-			slog.Error("decryption failed", "error", err)
-			return &APIError{
-				Status:  http.StatusInternalServerError,
-				Code:    ErrInternal,
-				Message: "Decryption failed",
-			}
+		repr, apiErr := negotiate(r, []byte(val), ct)
+		if apiErr != nil {
+			return apiErr
 		}
 
-		c.Set(entry.Hash, string(plaintext), entry.ContentType, entry.ExpiresAtPtr())
+		// The ETag describes the stored value, not a fragment or
+		// conversion of it
+		if repr.stored && !selected {
+			w.Header().Set("ETag", entityTag(repr.body))
+		}
 
-		w.Header().Set("Content-Type", entry.ContentType)
+		w.Header().Set("Content-Type", repr.contentType)
 		w.WriteHeader(http.StatusOK)
-		w.Write(plaintext)
+		w.Write(repr.body)
 
 		return nil
 	}
+}
+
+// loadValue returns the decrypted value and content type of a key,
+// from the cache if possible. Values read from storage are cached
+// under the key lock, so that a concurrent update is never
+// overwritten by the value it replaced.
+func loadValue(r *http.Request, store *storage.Storage, c *cache.Cache, locks *keylock.Locker, ns *namespace.Namespace, hash string) (string, string, *APIError) {
+	span := traceSpan(r, "cache.Get")
+	val, ct, ok := c.Get(cacheKey(ns.Name, hash))
+	span.End()
+
+	if ok {
+		return val, ct, nil
+	}
+
+	unlock := locks.Lock(cacheKey(ns.Name, hash))
+	defer unlock()
+
+	span = traceSpan(r, "storage.Get")
+	entry, err := store.Get(ns.Name, hash)
+	span.End()
+
+	if err != nil {
+		slog.Error("storage error", "error", err)
+		return "", "", &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrStorage,
+			Message: "Storage error",
+		}
+	}
+	if entry == nil {
+		return "", "", keyNotFound(ns, hash)
+	}
+
+	now := time.Now().Unix()
+	if entry.ExpiresAt.Valid && entry.ExpiresAt.Int64 <= now {
+		return "", "", &APIError{
+			Status:  http.StatusGone,
+			Code:    ErrKeyExpired,
+			Message: "Key expired",
+		}
+	}
+
+	span = traceSpan(r, "crypto.Decrypt")
+	plaintext, err := ns.Crypto.Decrypt(entry.Payload)
+	span.End()
+
+	if err != nil {
+		slog.Error("decryption failed", "error", err)
+		return "", "", &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrDecryptionFailed,
+			Message: "Decryption failed",
+		}
+	}
+
+	span = traceSpan(r, "cache.Set")
+	c.Set(cacheKey(ns.Name, entry.Hash), string(plaintext), entry.ContentType, entry.ExpiresAtPtr())
+	span.End()
+
+	return string(plaintext), entry.ContentType, nil
+}
+
+// keyFromPath extracts the key from /v1/kv/{key}. Client-chosen
+// keys may contain slashes, so everything after the prefix counts.
+func keyFromPath(r *http.Request) string {
+	key, _ := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	return key
+}
+
+// keyNotFound reports a missing key. A key failing its check
+// character was most likely mistyped, which is worth telling the
+// caller instead of a plain 404.
+func keyNotFound(ns *namespace.Namespace, key string) *APIError {
+	if ns.Keys.Mistyped(key) {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrKeyMistyped,
+			Message: "Key check character does not match, the key is probably mistyped",
+		}
+	}
+
+	return &APIError{
+		Status:  http.StatusNotFound,
+		Code:    ErrNotFound,
+		Message: "Not found",
+	}
+}
 
+// cacheKey scopes cache entries by namespace so that
+// tenants can never observe each other's values.
+func cacheKey(namespace, hash string) string {
+	return namespace + "/" + hash
 }
@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+)
+
+func TestChosenKey(t *testing.T) {
+	s := newServer(t, nil)
+
+	key := s.create(`{"key":"team-a/staging-db.creds_1","text":"v1"}`)
+	if key != "team-a/staging-db.creds_1" {
+		t.Fatalf("created key = %q", key)
+	}
+	if got := get(t, s, key); got != `"v1"` {
+		t.Errorf("GET = %s", got)
+	}
+
+	// A taken key is not overwritten
+	rec := s.do(http.MethodPost, "/v1/kv", `{"key":"team-a/staging-db.creds_1","text":"v2"}`)
+	expectError(t, rec, http.StatusConflict, "CONFLICT")
+	if got := get(t, s, key); got != `"v1"` {
+		t.Errorf("GET after conflict = %s", got)
+	}
+
+	// Random keys stay the default
+	if generated := s.create(`{"text":"x"}`); len(generated) != 16 {
+		t.Errorf("generated key = %q", generated)
+	}
+}
+
+func TestChosenKeyValidation(t *testing.T) {
+	s := newServer(t, nil)
+
+	for _, key := range []string{
+		"/leading",
+		"trailing/",
+		"a//b",
+		".hidden",
+		"a/-b",
+		"with space",
+		"percent%20",
+		"ünicode",
+		"a?b",
+		strings.Repeat("k", 129),
+	} {
+		body := map[string]string{"key": key, "text": "x"}
+		rec := s.do(http.MethodPost, "/v1/kv", body)
+		expectError(t, rec, http.StatusBadRequest, "INVALID_KEY")
+	}
+
+	s.create(map[string]string{"key": strings.Repeat("k", 128), "text": "x"})
+}
+
+// TestChosenKeyNamespaces checks that chosen keys are scoped to a
+// namespace and need an API key.
+func TestChosenKeyNamespaces(t *testing.T) {
+	const otherKey = "other-key-0123456789abcdef"
+
+	s := newServer(t, func(cfg *kvtxt.Config) {
+		cfg.Namespaces = append(cfg.Namespaces, kvtxt.Namespace{Name: "other", APIKeys: []string{otherKey}})
+	})
+
+	s.create(`{"key":"shared","text":"team"}`)
+
+	rec := s.do(http.MethodPost, "/v1/kv", `{"key":"shared","text":"other"}`, "Authorization", "Bearer "+otherKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create in another namespace = %d %s", rec.Code, rec.Body)
+	}
+
+	if got := get(t, s, "shared"); got != `"team"` {
+		t.Errorf("GET in team = %s", got)
+	}
+	rec = s.do(http.MethodGet, "/v1/kv/shared", nil, "Authorization", "Bearer "+otherKey)
+	if rec.Body.String() != `"other"` {
+		t.Errorf("GET in other = %d %s", rec.Code, rec.Body)
+	}
+
+	// Without namespaces requests are anonymous and cannot claim keys
+	anon := newServer(t, func(cfg *kvtxt.Config) { cfg.Namespaces = nil })
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/kv", strings.NewReader(`{"key":"claimed","text":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	expectError(t, anon.serve(req), http.StatusUnauthorized, "UNAUTHORIZED")
+}
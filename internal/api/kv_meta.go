@@ -0,0 +1,71 @@
+// MetaKV answers HEAD requests with entry metadata in headers.
+// The value is neither decrypted nor returned.
+//
+// Headers:
+// - Content-Type, Content-Length: as a GET would return them
+// - X-Kvtxt-Created-At: Unix timestamp
+// - X-Kvtxt-Expires-At: Unix timestamp, absent for entries without expiry
+// - X-Kvtxt-Version: incremented by every update
+
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func MetaKV(store *storage.Storage) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		hash := keyFromPath(r)
+		if hash == "" {
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Not found",
+			}
+		}
+
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		span := traceSpan(r, "storage.Get")
+		entry, err := store.Get(ns.Name, hash)
+		span.End()
+
+		if err != nil {
+			slog.Error("storage error", "error", err)
+			return &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrStorage,
+				Message: "Storage error",
+			}
+		}
+		if entry == nil {
+			return keyNotFound(ns, hash)
+		}
+
+		if entry.ExpiresAt.Valid && entry.ExpiresAt.Int64 <= time.Now().Unix() {
+			return &APIError{
+				Status:  http.StatusGone,
+				Code:    ErrKeyExpired,
+				Message: "Key expired",
+			}
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", entry.ContentType)
+		h.Set("Content-Length", strconv.Itoa(ns.Crypto.PlaintextSize(len(entry.Payload))))
+		h.Set("X-Kvtxt-Created-At", strconv.FormatInt(entry.CreatedAt, 10))
+		h.Set("X-Kvtxt-Version", strconv.FormatInt(entry.Version, 10))
+		if entry.ExpiresAt.Valid {
+			h.Set("X-Kvtxt-Expires-At", strconv.FormatInt(entry.ExpiresAt.Int64, 10))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+// Content negotiation lets GET /v1/kv/{key} serve an entry in
+// another format than it was stored in, chosen by the Accept header:
+// - JSON entries as YAML, or pretty-printed with application/json; indent=N
+// - text, YAML and XML entries as text/plain, or HTML-escaped as a
+//   text/html fragment
+//
+// The stored form is preferred whenever the client accepts it, and
+// only it is served with an ETag. Text entries are stored as a JSON
+// string, so a client naming a text type gets the text instead. If
+// no form is acceptable the request fails with 406.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/jsondoc"
+)
+
+// maxIndent bounds the indent parameter of pretty-printed JSON.
+const maxIndent = 8
+
+type format int
+
+const (
+	formatStored format = iota
+	formatJSON
+	formatYAML
+	formatText
+	formatHTML
+)
+
+// offer is a form an entry can be served in.
+type offer struct {
+	mediaType string
+	format    format
+}
+
+type acceptRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// representation is a negotiated response body.
+type representation struct {
+	body        []byte
+	contentType string
+
+	// stored is set when body is the value exactly as stored
+	stored bool
+}
+
+// negotiate picks the form of a value to serve for the request's
+// Accept header.
+func negotiate(r *http.Request, val []byte, contentType string) (*representation, *APIError) {
+	mediaType := contenttype.MediaType(contentType)
+	kind := contenttype.KindOf(mediaType)
+
+	ranges := parseAccept(r.Header.Values("Accept"))
+	if len(ranges) == 0 {
+		return &representation{body: val, contentType: contentType, stored: true}, nil
+	}
+
+	chosen, matched, ok := bestOffer(offersFor(mediaType, kind), ranges)
+	if !ok {
+		return nil, notAcceptable("No acceptable representation of " + mediaType)
+	}
+
+	if chosen.format == formatStored && kind == contenttype.Text && matched.mediaType != "*/*" {
+		chosen.format = formatText
+	}
+
+	indent := 0
+	if n, err := strconv.Atoi(matched.params["indent"]); err == nil && n > 0 {
+		indent = min(n, maxIndent)
+	}
+
+	switch chosen.format {
+	case formatStored:
+		if kind == contenttype.JSON && indent > 0 {
+			return prettyJSON(val, contentType, indent)
+		}
+		return &representation{body: val, contentType: contentType, stored: true}, nil
+
+	case formatJSON:
+		if indent > 0 {
+			return prettyJSON(val, chosen.mediaType, indent)
+		}
+		return &representation{body: val, contentType: chosen.mediaType}, nil
+
+	case formatYAML:
+		body, err := jsondoc.ToYAML(val)
+		if err != nil {
+			return nil, notAcceptable("Value cannot be converted to " + chosen.mediaType)
+		}
+		return &representation{body: body, contentType: chosen.mediaType}, nil
+
+	case formatText:
+		textType := "text/plain; charset=utf-8"
+		if chosen.mediaType == mediaType {
+			textType = contentType
+		}
+		return &representation{body: []byte(textContent(val, kind)), contentType: textType}, nil
+	}
+
+	return &representation{body: []byte(html.EscapeString(textContent(val, kind))), contentType: "text/html; charset=utf-8"}, nil
+}
+
+// offersFor lists the forms of an entry, the stored one first.
+func offersFor(mediaType string, kind contenttype.Kind) []offer {
+	offers := []offer{{mediaType: mediaType, format: formatStored}}
+
+	add := func(mt string, f format) {
+		if mt != mediaType {
+			offers = append(offers, offer{mediaType: mt, format: f})
+		}
+	}
+
+	switch kind {
+	case contenttype.JSON:
+		add("application/json", formatJSON)
+		add("application/yaml", formatYAML)
+		add("application/x-yaml", formatYAML)
+		add("text/yaml", formatYAML)
+		add("text/x-yaml", formatYAML)
+
+	case contenttype.Text, contenttype.YAML, contenttype.XML:
+		add("text/plain", formatText)
+		add("text/html", formatHTML)
+	}
+
+	return offers
+}
+
+// bestOffer returns the offer with the highest quality, preferring
+// earlier offers on ties, and the range that matched it.
+func bestOffer(offers []offer, ranges []acceptRange) (offer, acceptRange, bool) {
+	var (
+		best      offer
+		bestRange acceptRange
+		bestQ     float64
+	)
+
+	for _, o := range offers {
+		specificity := 0
+		var matched *acceptRange
+
+		for i := range ranges {
+			if s := matchRange(ranges[i].mediaType, o.mediaType); s > specificity {
+				specificity, matched = s, &ranges[i]
+			}
+		}
+
+		if matched != nil && matched.q > bestQ {
+			best, bestRange, bestQ = o, *matched, matched.q
+		}
+	}
+
+	return best, bestRange, bestQ > 0
+}
+
+// matchRange reports how specifically a media range matches a media
+// type: 3 for the type itself, 2 for type/*, 1 for */* and 0 for no
+// match.
+func matchRange(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 3
+	case pattern == "*/*":
+		return 1
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")):
+		return 2
+	}
+	return 0
+}
+
+// parseAccept parses Accept header values. Problem details only
+// choose the error format, so they take no part in negotiation.
+func parseAccept(values []string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil || mediaType == problemContentType {
+				continue
+			}
+
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(raw, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+
+			ranges = append(ranges, acceptRange{mediaType: mediaType, params: params, q: q})
+		}
+	}
+
+	return ranges
+}
+
+func prettyJSON(val []byte, contentType string, indent int) (*representation, *APIError) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, val, "", strings.Repeat(" ", indent)); err != nil {
+		return nil, notAcceptable("Value cannot be pretty-printed")
+	}
+	buf.WriteByte('\n')
+
+	return &representation{body: buf.Bytes(), contentType: contentType}, nil
+}
+
+// textContent returns a value as text. Text values are stored as the
+// JSON string they were sent as and lose its quoting; YAML and XML
+// documents are stored as they are.
+func textContent(val []byte, kind contenttype.Kind) string {
+	if kind == contenttype.YAML || kind == contenttype.XML {
+		return string(val)
+	}
+
+	var text string
+	if err := json.Unmarshal(val, &text); err == nil {
+		return text
+	}
+	return string(val)
+}
+
+func notAcceptable(message string) *APIError {
+	return &APIError{
+		Status:  http.StatusNotAcceptable,
+		Code:    ErrNotAcceptable,
+		Message: message,
+	}
+}
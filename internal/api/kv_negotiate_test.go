@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiation(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","content_type":"application/json","text":{"b":1,"a":[1, 2]}}`)
+	s.create(`{"key":"text","text":"a <b>"}`)
+	s.create(`{"key":"notes","content_type":"text/markdown","text":"# a"}`)
+
+	const (
+		stored  = `{"b":1,"a":[1, 2]}`
+		yamlDoc = "b: 1\na:\n  - 1\n  - 2\n"
+		indent2 = "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}\n"
+	)
+
+	tests := []struct {
+		key, accept string
+
+		contentType, body string
+		etag              bool
+	}{
+		{"doc", "", "application/json", stored, true},
+		{"doc", "application/json", "application/json", stored, true},
+		{"doc", "*/*", "application/json", stored, true},
+		{"doc", "application/*", "application/json", stored, true},
+		{"doc", "application/yaml", "application/yaml", yamlDoc, false},
+		{"doc", "text/x-yaml", "text/x-yaml", yamlDoc, false},
+
+		// Higher quality wins, the stored form on ties
+		{"doc", "application/json;q=0.5, application/yaml", "application/yaml", yamlDoc, false},
+		{"doc", "application/yaml;q=0.5, application/json", "application/json", stored, true},
+		{"doc", "application/yaml, application/json", "application/json", stored, true},
+		{"doc", "text/yaml;q=0.9, application/x-yaml;q=0.9", "application/x-yaml", yamlDoc, false},
+
+		// The most specific range sets the quality of a type
+		{"doc", "application/*;q=0.1, application/yaml;q=0.2", "application/yaml", yamlDoc, false},
+		{"doc", "application/json;q=0, */*", "application/yaml", yamlDoc, false},
+		{"doc", "*/*;q=0.1, text/*;q=0.5", "text/yaml", yamlDoc, false},
+
+		// indent pretty-prints the stored form, capped at 8
+		{"doc", "application/json; indent=2", "application/json", indent2, false},
+		{"doc", "application/json;indent=20", "application/json", "{\n        \"b\": 1,\n        \"a\": [\n                1,\n                2\n        ]\n}\n", false},
+		{"doc", "application/json; indent=0", "application/json", stored, true},
+		{"doc", "application/json; indent=two", "application/json", stored, true},
+
+		// Problem details and malformed ranges take no part
+		{"doc", "application/problem+json", "application/json", stored, true},
+		{"doc", "application/yaml;q=2", "application/json", stored, true},
+		{"doc", "application/yaml;q=-1, text/yaml", "text/yaml", yamlDoc, false},
+		{"doc", "not a type, application/yaml", "application/yaml", yamlDoc, false},
+
+		{"text", "", "text/plain; charset=utf-8", `"a <b>"`, true},
+		{"text", "*/*", "text/plain; charset=utf-8", `"a <b>"`, true},
+
+		// Naming a text type serves the text, not the JSON string
+		{"text", "text/*", "text/plain; charset=utf-8", "a <b>", false},
+		{"text", "text/plain", "text/plain; charset=utf-8", "a <b>", false},
+		{"text", "text/plain;q=0.5, application/json;q=0.1", "text/plain; charset=utf-8", "a <b>", false},
+		{"notes", "text/markdown", "text/markdown", "# a", false},
+		{"notes", "text/plain", "text/plain; charset=utf-8", "# a", false},
+		{"notes", "", "text/markdown", `"# a"`, true},
+		{"text", "text/html", "text/html; charset=utf-8", "a &lt;b&gt;", false},
+		{"text", "text/html, text/plain;q=0.5", "text/html; charset=utf-8", "a &lt;b&gt;", false},
+	}
+
+	for _, tt := range tests {
+		headers := []string{}
+		if tt.accept != "" {
+			headers = []string{"Accept", tt.accept}
+		}
+
+		rec := s.do(http.MethodGet, "/v1/kv/"+tt.key, nil, headers...)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s, Accept %q: status = %d %s", tt.key, tt.accept, rec.Code, rec.Body)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s, Accept %q: Content-Type = %q, want %q", tt.key, tt.accept, ct, tt.contentType)
+		}
+		if rec.Body.String() != tt.body {
+			t.Errorf("%s, Accept %q: body = %q, want %q", tt.key, tt.accept, rec.Body, tt.body)
+		}
+		if etag := rec.Header().Get("ETag") != ""; etag != tt.etag {
+			t.Errorf("%s, Accept %q: ETag sent %v, want %v", tt.key, tt.accept, etag, tt.etag)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s, Accept %q: Vary = %q", tt.key, tt.accept, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestNegotiationNotAcceptable(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","content_type":"application/json","text":{"a":1}}`)
+	s.create(`{"key":"text","text":"a"}`)
+
+	for _, tt := range []struct{ key, accept string }{
+		{"doc", "text/plain"},
+		{"doc", "application/xml"},
+		{"doc", "application/json;q=0"},
+		{"doc", "application/*;q=0, text/*;q=0"},
+		{"doc", "application/problem+json, text/html"},
+		{"text", "application/json"},
+		{"text", "application/yaml"},
+		{"text", "text/*;q=0"},
+	} {
+		rec := s.do(http.MethodGet, "/v1/kv/"+tt.key, nil, "Accept", tt.accept)
+		if rec.Code != http.StatusNotAcceptable {
+			t.Errorf("%s, Accept %q: status = %d %s, want 406", tt.key, tt.accept, rec.Code, rec.Body)
+		}
+	}
+
+	rec := s.do(http.MethodGet, "/v1/kv/doc", nil, "Accept", "text/plain")
+	expectError(t, rec, http.StatusNotAcceptable, "NOT_ACCEPTABLE")
+}
+
+func TestNegotiationHeaderValues(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","content_type":"application/json","text":{"a":1}}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/kv/doc", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Add("Accept", "application/json;q=0.1")
+	req.Header.Add("Accept", "application/yaml")
+
+	rec := s.serve(req)
+	if rec.Body.String() != "a: 1\n" {
+		t.Errorf("two Accept headers = %q, want YAML", rec.Body)
+	}
+}
+
+func TestNegotiationFragments(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","content_type":"application/vnd.cfg+json","text":{"a":[1,"true"]}}`)
+
+	rec := s.do(http.MethodGet, "/v1/kv/doc?pointer=/a", nil, "Accept", "application/yaml")
+	if rec.Body.String() != "- 1\n- \"true\"\n" || rec.Header().Get("ETag") != "" {
+		t.Errorf("fragment as YAML = %q, ETag %q", rec.Body, rec.Header().Get("ETag"))
+	}
+
+	// The stored form of a +json entry is preferred to plain JSON
+	rec = s.do(http.MethodGet, "/v1/kv/doc", nil, "Accept", "application/json, application/vnd.cfg+json")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.cfg+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
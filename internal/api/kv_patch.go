@@ -0,0 +1,158 @@
+// PatchKV applies a patch to a JSON entry on the server:
+// - application/merge-patch+json: RFC 7396 JSON Merge Patch
+// - application/json-patch+json: RFC 6902 JSON Patch
+//
+// The patch runs on the decrypted document like any other update,
+// atomically and under the key lock, and honours If-Match.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/jsondoc"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+func PatchKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		hash := keyFromPath(r)
+		if hash == "" {
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Not found",
+			}
+		}
+
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		var apply func(doc, patch []byte) ([]byte, error)
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case mergePatchType:
+			apply = jsondoc.MergePatch
+		case jsonPatchType:
+			apply = jsondoc.JSONPatch
+		default:
+			discardBody(r)
+			w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+			return &APIError{
+				Status:  http.StatusUnsupportedMediaType,
+				Code:    ErrUnsupportedType,
+				Message: "Content-Type must be " + mergePatchType + " or " + jsonPatchType,
+			}
+		}
+
+		patch, apiErr := readBody(w, r, ns)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		if !json.Valid(patch) {
+			return &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrInvalidJSON,
+				Message: "Invalid JSON body",
+			}
+		}
+
+		check := policyCheck(ns, hash)
+
+		entry, val, apiErr := updateEntry(r, store, c, locks, ns, hash, withCheck(check, func(current []byte, e *storage.Entry) ([]byte, *APIError) {
+			if !isJSONContentType(e.ContentType) {
+				return nil, &APIError{
+					Status:  http.StatusConflict,
+					Code:    ErrTypeMismatch,
+					Message: "Only JSON entries can be patched",
+				}
+			}
+
+			span := traceSpan(r, "jsondoc.Patch")
+			next, err := apply(current, patch)
+			span.End()
+
+			switch {
+			case errors.Is(err, jsondoc.ErrInvalidPatch):
+				return nil, &APIError{
+					Status:  http.StatusBadRequest,
+					Code:    ErrInvalidPatch,
+					Message: err.Error(),
+				}
+
+			case errors.Is(err, jsondoc.ErrPatchFailed):
+				return nil, &APIError{
+					Status:  http.StatusConflict,
+					Code:    ErrPatchFailed,
+					Message: err.Error(),
+				}
+
+			case err != nil:
+				return nil, &APIError{
+					Status:  http.StatusConflict,
+					Code:    ErrTypeMismatch,
+					Message: "Stored value is not a JSON document",
+				}
+			}
+
+			if !json.Valid(next) {
+				slog.Error("patch produced invalid JSON", "key", hash)
+				return nil, &APIError{
+					Status:  http.StatusInternalServerError,
+					Code:    ErrInternal,
+					Message: "Patch produced invalid JSON",
+				}
+			}
+
+			return next, nil
+		}))
+		if apiErr != nil {
+			return apiErr
+		}
+
+		writeUpdate(w, entry, val, updateResponse{})
+		return nil
+	}
+}
+
+// readBody reads a raw request body within the namespace payload
+// limit.
+func readBody(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace) ([]byte, *APIError) {
+	r.Body = http.MaxBytesReader(w, r.Body, ns.MaxPayloadSize)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &APIError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    ErrPayloadTooLarge,
+				Message: "Request body exceeds allowed size",
+			}
+		}
+
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Could not read request body",
+		}
+	}
+
+	return body, nil
+}
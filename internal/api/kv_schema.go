@@ -0,0 +1,157 @@
+// Schema validation rejects values that do not conform to a JSON
+// Schema. A create request may carry one inline ("schema") or name
+// an entry holding one ("schema_key"), and a namespace may require
+// one for every key under a prefix.
+//
+// Schema entries are loaded before the key lock is taken, as loading
+// an entry may take its own lock. The schemas of the namespace policy
+// are compiled from the configuration, out of reach of its clients.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// valueCheck validates a value before it is stored.
+type valueCheck func(val []byte) *APIError
+
+// createCheck returns the checks for a create request: the
+// namespace policy and the schema of the request, if any.
+func createCheck(r *http.Request, store *storage.Storage, c *cache.Cache, locks *keylock.Locker, ns *namespace.Namespace, req *createRequest) (valueCheck, *APIError) {
+	policy := policyCheck(ns, req.Key)
+
+	schema, apiErr := requestSchema(r, store, c, locks, ns, req)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return func(val []byte) *APIError {
+		if policy != nil {
+			if apiErr := policy(val); apiErr != nil {
+				return apiErr
+			}
+		}
+		if schema != nil {
+			return conform(schema, val)
+		}
+		return nil
+	}, nil
+}
+
+// requestSchema compiles the schema a create request carries inline
+// or names by key.
+func requestSchema(r *http.Request, store *storage.Storage, c *cache.Cache, locks *keylock.Locker, ns *namespace.Namespace, req *createRequest) (*jsonschema.Schema, *APIError) {
+	var raw []byte
+
+	switch {
+	case len(req.Schema) > 0 && req.SchemaKey != "":
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Use either schema or schema_key, not both",
+		}
+
+	case len(req.Schema) > 0:
+		raw = req.Schema
+
+	case req.SchemaKey != "":
+		val, _, apiErr := loadValue(r, store, c, locks, ns, req.SchemaKey)
+		if apiErr != nil {
+			if apiErr.Status != http.StatusNotFound && apiErr.Status != http.StatusGone {
+				return nil, apiErr
+			}
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrInvalidSchema,
+				Message: "Schema key " + req.SchemaKey + " does not exist",
+			}
+		}
+		raw = []byte(val)
+
+	default:
+		return nil, nil
+	}
+
+	span := traceSpan(r, "jsonschema.Compile")
+	schema, err := jsonschema.Compile(raw)
+	span.End()
+	if err != nil {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrInvalidSchema,
+			Message: err.Error(),
+		}
+	}
+
+	return schema, nil
+}
+
+// policyCheck returns the check the namespace policy requires for a
+// key, or nil. Generated keys are only covered by the "" prefix.
+func policyCheck(ns *namespace.Namespace, key string) valueCheck {
+	var schema *jsonschema.Schema
+	var prefix string
+
+	for p, sch := range ns.Schemas {
+		if strings.HasPrefix(key, p) && (schema == nil || len(p) > len(prefix)) {
+			schema, prefix = sch, p
+		}
+	}
+
+	if schema == nil {
+		return nil
+	}
+
+	return func(val []byte) *APIError {
+		return conform(schema, val)
+	}
+}
+
+// conform validates a value against schema.
+func conform(schema *jsonschema.Schema, val []byte) *APIError {
+	violations, err := schema.Validate(val)
+	if err != nil {
+		return &APIError{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    ErrSchemaViolation,
+			Message: "Value is not a JSON document",
+		}
+	}
+
+	if len(violations) > 0 {
+		return &APIError{
+			Status:     http.StatusUnprocessableEntity,
+			Code:       ErrSchemaViolation,
+			Message:    "Value does not conform to the schema",
+			Violations: violations,
+		}
+	}
+
+	return nil
+}
+
+// withCheck runs check on the value modify produces.
+func withCheck(check valueCheck, modify modifyFunc) modifyFunc {
+	if check == nil {
+		return modify
+	}
+
+	return func(current []byte, e *storage.Entry) ([]byte, *APIError) {
+		next, apiErr := modify(current, e)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if apiErr := check(next); apiErr != nil {
+			return nil, apiErr
+		}
+		return next, nil
+	}
+}
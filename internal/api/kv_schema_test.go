@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt"
+)
+
+func newPolicyServer(t *testing.T) *testServer {
+	return newServer(t, func(cfg *kvtxt.Config) {
+		cfg.Namespaces[0].Schemas = map[string]any{"flags/": json.RawMessage(flagSchema)}
+	})
+}
+
+const flagSchema = `{"type":"object","required":["enabled"],"properties":{"enabled":{"type":"boolean"},"rollout":{"type":"integer","minimum":0,"maximum":100}}}`
+
+func TestSchemaPolicy(t *testing.T) {
+	s := newPolicyServer(t)
+
+	rec := s.do(http.MethodPost, "/v1/kv", `{"key":"flags/a","content_type":"application/json","text":{"enabled":"yes","rollout":150}}`)
+	expectError(t, rec, http.StatusUnprocessableEntity, "SCHEMA_VIOLATION")
+
+	var body struct {
+		Error struct {
+			Violations []struct {
+				Path string `json:"path"`
+			} `json:"violations"`
+		} `json:"error"`
+	}
+	decode(t, rec, &body)
+	if v := body.Error.Violations; len(v) != 2 || v[0].Path != "/enabled" || v[1].Path != "/rollout" {
+		t.Errorf("violations = %+v, want /enabled and /rollout", v)
+	}
+
+	s.create(`{"key":"flags/a","content_type":"application/json","text":{"enabled":true,"rollout":50}}`)
+
+	// Updates are checked too, and other keys are not
+	expectError(t, s.do(http.MethodPut, "/v1/kv/flags/a", `{"text":{"rollout":10}}`, "If-Match", "*"), http.StatusUnprocessableEntity, "SCHEMA_VIOLATION")
+	expectError(t, s.do(http.MethodPatch, "/v1/kv/flags/a", `{"enabled":null}`, "Content-Type", "application/merge-patch+json"), http.StatusUnprocessableEntity, "SCHEMA_VIOLATION")
+	s.create(`{"key":"other","content_type":"application/json","text":{"enabled":"yes"}}`)
+
+	if got := get(t, s, "flags/a"); got != `{"enabled":true,"rollout":50}` {
+		t.Errorf("flags/a = %s after rejected updates", got)
+	}
+}
+
+// TestSchemaPolicyPrefixes checks that the longest prefix applies,
+// and that "" covers generated keys.
+func TestSchemaPolicyPrefixes(t *testing.T) {
+	s := newServer(t, func(cfg *kvtxt.Config) {
+		cfg.Namespaces[0].Schemas = map[string]any{
+			"":       map[string]any{"type": "object"},
+			"flags/": json.RawMessage(flagSchema),
+		}
+	})
+
+	expectError(t, s.do(http.MethodPost, "/v1/kv", `{"content_type":"application/json","text":[1]}`), http.StatusUnprocessableEntity, "SCHEMA_VIOLATION")
+	s.create(`{"content_type":"application/json","text":{"any":1}}`)
+
+	expectError(t, s.do(http.MethodPost, "/v1/kv", `{"key":"flags/a","content_type":"application/json","text":{"any":1}}`), http.StatusUnprocessableEntity, "SCHEMA_VIOLATION")
+	s.create(`{"key":"flags/a","content_type":"application/json","text":{"enabled":false}}`)
+}
+
+// TestSchemaPolicyInvalid checks that an unusable policy schema is
+// rejected when the server starts, not on the first write.
+func TestSchemaPolicyInvalid(t *testing.T) {
+	store, err := kvtxt.OpenStorage(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	key, err := kvtxt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypt, err := kvtxt.NewCrypto(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := kvtxt.DefaultConfig()
+	cfg.Namespaces = []kvtxt.Namespace{{
+		Name:    "team",
+		APIKeys: []string{testAPIKey},
+		Schemas: map[string]any{"flags/": map[string]any{"type": "flag"}},
+	}}
+
+	_, err = kvtxt.NewServer(kvtxt.Options{Storage: store, Crypto: crypt, Config: cfg})
+	if err == nil || !strings.Contains(err.Error(), `prefix "flags/"`) {
+		t.Errorf("NewServer = %v, want a schema error", err)
+	}
+}
+
+func TestRequestSchema(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"schemas/port","content_type":"application/json","text":{"type":"integer","maximum":65535}}`)
+
+	s.create(`{"content_type":"application/json","schema":{"type":"string"},"text":"ok"}`)
+	s.create(`{"content_type":"application/json","schema_key":"schemas/port","text":8080}`)
+
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"content_type":"application/json","schema":{"type":"string"},"text":1}`, http.StatusUnprocessableEntity, "SCHEMA_VIOLATION"},
+		{`{"content_type":"application/json","schema_key":"schemas/port","text":70000}`, http.StatusUnprocessableEntity, "SCHEMA_VIOLATION"},
+		{`{"content_type":"application/json","schema_key":"schemas/missing","text":1}`, http.StatusBadRequest, "INVALID_SCHEMA"},
+		{`{"content_type":"application/json","schema":{"type":"float"},"text":1}`, http.StatusBadRequest, "INVALID_SCHEMA"},
+		{`{"content_type":"application/json","schema":{},"schema_key":"schemas/port","text":1}`, http.StatusBadRequest, "BAD_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		expectError(t, s.do(http.MethodPost, "/v1/kv", tt.body), tt.status, tt.code)
+	}
+}
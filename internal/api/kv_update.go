@@ -0,0 +1,456 @@
+// Update handlers change an existing entry atomically.
+//
+// - PUT /v1/kv/{key} replaces the value if If-Match holds
+// - POST /v1/kv/{key}:increment and :decrement add to an integer
+// - POST /v1/kv/{key}:append adds an element to a JSON array
+//
+// Each runs decrypt-modify-encrypt inside one storage transaction
+// while holding the key lock, and refreshes the cache before the
+// lock is released. If-Match accepts the ETag returned by GET, which
+// hashes the value, or the entry version as a quoted number: "3".
+//
+// New values must conform to the schema policy of the namespace.
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+type replaceRequest struct {
+	Text        json.RawMessage `json:"text"`
+	ContentType string          `json:"content_type"`
+	TTLSeconds  *int64          `json:"ttl_seconds"`
+}
+
+type counterRequest struct {
+	By *int64 `json:"by"`
+}
+
+type appendRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type updateResponse struct {
+	Key       string `json:"key"`
+	Version   int64  `json:"version"`
+	ExpiresAt *int64 `json:"expires_at,omitempty"`
+
+	// Value is the counter after increment or decrement
+	Value *int64 `json:"value,omitempty"`
+
+	// Length is the array length after append
+	Length *int `json:"length,omitempty"`
+}
+
+// modifyFunc returns the new value for the current one. It may also
+// change the content type and expiry of e.
+type modifyFunc func(current []byte, e *storage.Entry) ([]byte, *APIError)
+
+// errModifyAborted carries an APIError out of storage.Update.
+var errModifyAborted = errors.New("modify aborted")
+
+// ReplaceKV handles PUT /v1/kv/{key}. If-Match is required; use
+// "*" to replace whatever value the entry holds. Omitted content
+// type and TTL keep their current values, within the content type
+// policy.
+func ReplaceKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		hash := keyFromPath(r)
+		if hash == "" {
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Not found",
+			}
+		}
+
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		if r.Header.Get("If-Match") == "" {
+			discardBody(r)
+			return &APIError{
+				Status:  http.StatusPreconditionRequired,
+				Code:    ErrPreconditionNeeded,
+				Message: "If-Match is required, use * to replace any value",
+			}
+		}
+
+		var req replaceRequest
+		if apiErr := decodeBody(w, r, ns, &req); apiErr != nil {
+			return apiErr
+		}
+
+		if len(req.Text) == 0 {
+			return &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrTextRequired,
+				Message: "Text is required",
+			}
+		}
+
+		check := policyCheck(ns, hash)
+
+		entry, val, apiErr := updateEntry(r, store, c, locks, ns, hash, withCheck(check, func(_ []byte, e *storage.Entry) ([]byte, *APIError) {
+			contentType := e.ContentType
+			if req.ContentType != "" {
+				contentType = req.ContentType
+			}
+
+			contentType, value, limit, apiErr := checkContent(ns, contentType, req.Text)
+			if apiErr != nil {
+				return nil, apiErr
+			}
+			e.ContentType = contentType
+
+			now := time.Now()
+
+			switch {
+			case req.TTLSeconds != nil:
+				ttl, apiErr := entryTTL(ns, limit, req.TTLSeconds)
+				if apiErr != nil {
+					return nil, apiErr
+				}
+				e.ExpiresAt.Int64, e.ExpiresAt.Valid = now.Add(ttl).Unix(), true
+
+			// A kept expiry must still be within the limit of a new type
+			case limit.MaxTTL > 0 && e.ExpiresAt.Valid && e.ExpiresAt.Int64 > now.Add(limit.MaxTTL).Unix():
+				return nil, &APIError{
+					Status:  http.StatusBadRequest,
+					Code:    ErrTTLOutOfRange,
+					Message: "TTL exceeds maximum allowed",
+				}
+			}
+
+			return value, nil
+		}))
+		if apiErr != nil {
+			return apiErr
+		}
+
+		writeUpdate(w, entry, val, updateResponse{})
+		return nil
+	}
+}
+
+// OperateKV handles POST /v1/kv/{key}:{operation}. If-Match is
+// optional.
+func OperateKV(store *storage.Storage, c *cache.Cache, locks *keylock.Locker) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		path := keyFromPath(r)
+
+		i := strings.LastIndexByte(path, ':')
+		if i < 0 {
+			discardBody(r)
+			return &APIError{
+				Status:  http.StatusMethodNotAllowed,
+				Code:    ErrMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+		}
+
+		hash, op := path[:i], path[i+1:]
+		if hash == "" {
+			discardBody(r)
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Not found",
+			}
+		}
+
+		ns := GetNamespace(r.Context())
+		auditKey(r.Context(), hash)
+
+		var (
+			resp   updateResponse
+			fn     modifyFunc
+			apiErr *APIError
+		)
+
+		switch op {
+		case "increment":
+			fn, apiErr = incrementBy(w, r, ns, 1, &resp)
+		case "decrement":
+			fn, apiErr = incrementBy(w, r, ns, -1, &resp)
+		case "append":
+			fn, apiErr = appendValue(w, r, ns, &resp)
+		default:
+			discardBody(r)
+			return &APIError{
+				Status:  http.StatusNotFound,
+				Code:    ErrNotFound,
+				Message: "Unknown operation " + op,
+			}
+		}
+		if apiErr != nil {
+			return apiErr
+		}
+
+		check := policyCheck(ns, hash)
+
+		entry, val, apiErr := updateEntry(r, store, c, locks, ns, hash, withCheck(check, fn))
+		if apiErr != nil {
+			return apiErr
+		}
+
+		writeUpdate(w, entry, val, resp)
+		return nil
+	}
+}
+
+// incrementBy adds sign * by to an integer value; by defaults to 1
+// and the body may be empty.
+func incrementBy(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace, sign int64, resp *updateResponse) (modifyFunc, *APIError) {
+	var req counterRequest
+	if apiErr := decodeOptionalBody(w, r, ns, &req); apiErr != nil {
+		return nil, apiErr
+	}
+
+	by := int64(1)
+	if req.By != nil {
+		by = *req.By
+	}
+
+	if by == math.MinInt64 {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "by is out of range",
+		}
+	}
+	by *= sign
+
+	return func(current []byte, _ *storage.Entry) ([]byte, *APIError) {
+		n, err := strconv.ParseInt(string(bytes.TrimSpace(current)), 10, 64)
+		if err != nil {
+			return nil, &APIError{
+				Status:  http.StatusConflict,
+				Code:    ErrTypeMismatch,
+				Message: "Value is not an integer",
+			}
+		}
+
+		if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+			return nil, &APIError{
+				Status:  http.StatusConflict,
+				Code:    ErrTypeMismatch,
+				Message: "Result exceeds the 64-bit integer range",
+			}
+		}
+
+		n += by
+		resp.Value = &n
+
+		return []byte(strconv.FormatInt(n, 10)), nil
+	}, nil
+}
+
+// appendValue adds the request value as the last element of a JSON
+// array. The existing elements are kept byte for byte.
+func appendValue(w http.ResponseWriter, r *http.Request, ns *namespace.Namespace, resp *updateResponse) (modifyFunc, *APIError) {
+	var req appendRequest
+	if apiErr := decodeBody(w, r, ns, &req); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if len(req.Value) == 0 {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrBadRequest,
+			Message: "Value is required",
+		}
+	}
+
+	return func(current []byte, _ *storage.Entry) ([]byte, *APIError) {
+		var elems []json.RawMessage
+		trimmed := bytes.TrimSpace(current)
+
+		if len(trimmed) == 0 || trimmed[0] != '[' || json.Unmarshal(trimmed, &elems) != nil {
+			return nil, &APIError{
+				Status:  http.StatusConflict,
+				Code:    ErrTypeMismatch,
+				Message: "Value is not a JSON array",
+			}
+		}
+
+		// Drop the closing bracket, and any whitespace before it
+		next := bytes.TrimRight(trimmed[:len(trimmed)-1], " \t\r\n")
+		next = append([]byte(nil), next...)
+		if len(elems) > 0 {
+			next = append(next, ',')
+		}
+		next = append(next, req.Value...)
+		next = append(next, ']')
+
+		length := len(elems) + 1
+		resp.Length = &length
+
+		return next, nil
+	}, nil
+}
+
+// updateEntry applies modify to the decrypted value of a key under
+// its lock, checks If-Match and the payload limit, and caches the
+// result. It returns the stored entry and its new plaintext.
+func updateEntry(
+	r *http.Request,
+	store *storage.Storage,
+	c *cache.Cache,
+	locks *keylock.Locker,
+	ns *namespace.Namespace,
+	hash string,
+	modify modifyFunc,
+) (*storage.Entry, []byte, *APIError) {
+	key := cacheKey(ns.Name, hash)
+
+	unlock := locks.Lock(key)
+	defer unlock()
+
+	var (
+		next   []byte
+		apiErr *APIError
+	)
+
+	span := traceSpan(r, "storage.Update")
+	entry, err := store.Update(ns.Name, hash, time.Now().Unix(), ns.Quota, func(e *storage.Entry) error {
+		current, err := ns.Crypto.Decrypt(e.Payload)
+		if err != nil {
+			slog.Error("decryption failed", "error", err)
+			apiErr = &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrDecryptionFailed,
+				Message: "Decryption failed",
+			}
+			return errModifyAborted
+		}
+
+		if !ifMatch(r.Header.Get("If-Match"), current, e.Version) {
+			apiErr = &APIError{
+				Status:  http.StatusPreconditionFailed,
+				Code:    ErrPreconditionFailed,
+				Message: "Entry has changed, If-Match does not hold",
+			}
+			return errModifyAborted
+		}
+
+		next, apiErr = modify(current, e)
+		if apiErr != nil {
+			return errModifyAborted
+		}
+
+		maxSize := ns.MaxPayloadSize
+		if limit := ns.ContentTypes.Limit(contenttype.MediaType(e.ContentType)); limit.MaxSize > 0 {
+			maxSize = min(maxSize, limit.MaxSize)
+		}
+
+		if int64(len(next)) > maxSize {
+			apiErr = &APIError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    ErrPayloadTooLarge,
+				Message: "Value exceeds allowed size",
+			}
+			return errModifyAborted
+		}
+
+		e.Payload, err = ns.Crypto.Encrypt(next)
+		if err != nil {
+			slog.Error("encryption failed", "error", err)
+			apiErr = &APIError{
+				Status:  http.StatusInternalServerError,
+				Code:    ErrEncryptionFailed,
+				Message: "Encryption failed",
+			}
+			return errModifyAborted
+		}
+
+		return nil
+	})
+	span.End()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errModifyAborted):
+		return nil, nil, apiErr
+	case errors.Is(err, storage.ErrEntryNotFound):
+		return nil, nil, keyNotFound(ns, hash)
+	case errors.Is(err, storage.ErrEntryExpired):
+		return nil, nil, &APIError{
+			Status:  http.StatusGone,
+			Code:    ErrKeyExpired,
+			Message: "Key expired",
+		}
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return nil, nil, &APIError{
+			Status:  http.StatusForbidden,
+			Code:    ErrQuotaExceeded,
+			Message: "Namespace storage quota exceeded",
+		}
+	default:
+		slog.Error("update failed", "error", err)
+		return nil, nil, &APIError{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrStorage,
+			Message: "Storage error",
+		}
+	}
+
+	span = traceSpan(r, "cache.Set")
+	c.Set(key, string(next), entry.ContentType, entry.ExpiresAtPtr())
+	span.End()
+
+	return entry, next, nil
+}
+
+// ifMatch evaluates an If-Match header against the current value
+// and version. Weak tags never match.
+func ifMatch(header string, current []byte, version int64) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return true
+	}
+
+	etag := entityTag(current)
+	versionTag := `"` + strconv.FormatInt(version, 10) + `"`
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == etag || tag == versionTag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entityTag is the strong ETag of a value: a hash of its content.
+func entityTag(val []byte) string {
+	sum := sha256.Sum256(val)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func writeUpdate(w http.ResponseWriter, entry *storage.Entry, val []byte, resp updateResponse) {
+	resp.Key = entry.Hash
+	resp.Version = entry.Version
+	resp.ExpiresAt = entry.ExpiresAtPtr()
+
+	w.Header().Set("ETag", entityTag(val))
+	WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,233 @@
+package api_test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// counter creates a JSON number entry under key.
+func counter(s *testServer, key string, n int64) {
+	s.create(fmt.Sprintf(`{"key":%q,"text":%d,"content_type":"application/json"}`, key, n))
+}
+
+func get(t *testing.T, s *testServer, key string) string {
+	t.Helper()
+
+	rec := s.do(http.MethodGet, "/v1/kv/"+key, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d %s", key, rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func TestReplaceIfMatch(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"doc","text":{"a":1},"content_type":"application/json"}`)
+
+	etag := s.do(http.MethodGet, "/v1/kv/doc", nil).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET sent no ETag")
+	}
+
+	replace := func(ifMatch, text string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		headers := []string{}
+		if ifMatch != "" {
+			headers = append(headers, "If-Match", ifMatch)
+		}
+		return s.do(http.MethodPut, "/v1/kv/doc", `{"text":`+text+`}`, headers...)
+	}
+
+	expectError(t, replace("", `{"a":2}`), http.StatusPreconditionRequired, "PRECONDITION_REQUIRED")
+	expectError(t, replace(`"0123"`, `{"a":2}`), http.StatusPreconditionFailed, "PRECONDITION_FAILED")
+	expectError(t, replace("W/"+etag, `{"a":2}`), http.StatusPreconditionFailed, "PRECONDITION_FAILED")
+
+	// By ETag, which then changes with the value
+	rec := replace(etag, `{"a":2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT with the ETag = %d %s", rec.Code, rec.Body)
+	}
+	next := rec.Header().Get("ETag")
+	if next == etag || next != s.do(http.MethodGet, "/v1/kv/doc", nil).Header().Get("ETag") {
+		t.Errorf("ETag after PUT = %s, was %s", next, etag)
+	}
+	expectError(t, replace(etag, `{"a":3}`), http.StatusPreconditionFailed, "PRECONDITION_FAILED")
+
+	// By version, as a quoted number and within a list
+	var resp struct {
+		Version int64 `json:"version"`
+	}
+	decode(t, rec, &resp)
+
+	version := `"` + strconv.FormatInt(resp.Version, 10) + `"`
+	if rec := replace(`"999", `+version, `{"a":3}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT with version %s = %d %s", version, rec.Code, rec.Body)
+	}
+	expectError(t, replace(version, `{"a":4}`), http.StatusPreconditionFailed, "PRECONDITION_FAILED")
+
+	if rec := replace("*", `{"a":5}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT with * = %d %s", rec.Code, rec.Body)
+	}
+	if got := get(t, s, "doc"); got != `{"a":5}` {
+		t.Errorf("value = %s", got)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	s := newServer(t, nil)
+
+	tests := []struct {
+		start int64
+		op    string
+		body  string
+		want  int64
+		code  string
+	}{
+		{41, "increment", "", 42, ""},
+		{41, "decrement", "", 40, ""},
+		{41, "increment", `{"by":-50}`, -9, ""},
+		{41, "decrement", `{"by":-1}`, 42, ""},
+		{41, "increment", `{}`, 42, ""},
+		{math.MaxInt64 - 1, "increment", "", math.MaxInt64, ""},
+		{math.MaxInt64, "increment", "", 0, "TYPE_MISMATCH"},
+		{math.MinInt64, "decrement", "", 0, "TYPE_MISMATCH"},
+		{-1, "increment", `{"by":-9223372036854775808}`, 0, "BAD_REQUEST"},
+		{0, "increment", `{"by":`, 0, "INVALID_JSON"},
+	}
+
+	for i, tt := range tests {
+		key := fmt.Sprintf("n%d", i)
+		counter(s, key, tt.start)
+
+		var body any
+		if tt.body != "" {
+			body = tt.body
+		}
+		rec := s.do(http.MethodPost, "/v1/kv/"+key+":"+tt.op, body)
+
+		if tt.code != "" {
+			if rec.Code == http.StatusOK {
+				t.Errorf("%d %s %s succeeded: %s", tt.start, tt.op, tt.body, rec.Body)
+			} else if got := errorCode(t, rec); got != tt.code {
+				t.Errorf("%d %s %s: code = %s, want %s", tt.start, tt.op, tt.body, got, tt.code)
+			}
+			if got := get(t, s, key); got != strconv.FormatInt(tt.start, 10) {
+				t.Errorf("%d %s %s: value changed to %s", tt.start, tt.op, tt.body, got)
+			}
+			continue
+		}
+
+		var resp struct {
+			Value int64 `json:"value"`
+		}
+		decode(t, rec, &resp)
+		if rec.Code != http.StatusOK || resp.Value != tt.want {
+			t.Errorf("%d %s %s = %d %s, want %d", tt.start, tt.op, tt.body, rec.Code, rec.Body, tt.want)
+		}
+		if got := get(t, s, key); got != strconv.FormatInt(tt.want, 10) {
+			t.Errorf("%d %s %s: stored %s", tt.start, tt.op, tt.body, got)
+		}
+	}
+}
+
+// TestIncrementChunkedEmptyBody checks that an empty body without a
+// Content-Length counts as no body.
+func TestIncrementChunkedEmptyBody(t *testing.T) {
+	s := newServer(t, nil)
+	counter(s, "n", 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/kv/n:increment", strings.NewReader(""))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+
+	rec := s.serve(req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("chunked empty increment = %d %s", rec.Code, rec.Body)
+	}
+	if got := get(t, s, "n"); got != "2" {
+		t.Errorf("value = %s, want 2", got)
+	}
+}
+
+func TestIncrementNotInteger(t *testing.T) {
+	s := newServer(t, nil)
+	s.create(`{"key":"f","text":1.5,"content_type":"application/json"}`)
+	s.create(`{"key":"t","text":"7"}`)
+
+	for _, key := range []string{"f", "t"} {
+		expectError(t, s.do(http.MethodPost, "/v1/kv/"+key+":increment", nil), http.StatusConflict, "TYPE_MISMATCH")
+	}
+}
+
+func TestConcurrentIncrements(t *testing.T) {
+	s := newServer(t, nil)
+	counter(s, "n", 0)
+
+	const workers, each = 8, 25
+
+	var wg sync.WaitGroup
+	failed := make(chan int, workers*each)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				if rec := s.do(http.MethodPost, "/v1/kv/n:increment", nil); rec.Code != http.StatusOK {
+					failed <- rec.Code
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(failed)
+
+	for code := range failed {
+		t.Errorf("increment = %d", code)
+	}
+	if got := get(t, s, "n"); got != strconv.Itoa(workers*each) {
+		t.Errorf("count = %s, want %d", got, workers*each)
+	}
+}
+
+func TestAppendKeepsBytes(t *testing.T) {
+	s := newServer(t, nil)
+
+	tests := []struct {
+		start, value, want string
+		length             int
+	}{
+		{`[]`, `1`, `[1]`, 1},
+		{`[ ]`, `"x"`, `["x"]`, 1},
+		{`[1,  {"b":2, "a":1.50} ]`, `{"z": 0}`, `[1,  {"b":2, "a":1.50},{"z": 0}]`, 3},
+		{"[\n  1e2\n]", `null`, "[\n  1e2,null]", 2},
+	}
+
+	for i, tt := range tests {
+		key := fmt.Sprintf("a%d", i)
+		s.create(`{"key":"` + key + `","text":` + tt.start + `,"content_type":"application/json"}`)
+
+		rec := s.do(http.MethodPost, "/v1/kv/"+key+":append", `{"value":`+tt.value+`}`)
+		var resp struct {
+			Length int `json:"length"`
+		}
+		decode(t, rec, &resp)
+		if rec.Code != http.StatusOK || resp.Length != tt.length {
+			t.Errorf("append %s to %s = %d %s, want length %d", tt.value, tt.start, rec.Code, rec.Body, tt.length)
+		}
+
+		if got := get(t, s, key); got != tt.want {
+			t.Errorf("append %s to %s = %s, want %s", tt.value, tt.start, got, tt.want)
+		}
+	}
+
+	s.create(`{"key":"obj","text":{"a":1},"content_type":"application/json"}`)
+	expectError(t, s.do(http.MethodPost, "/v1/kv/obj:append", `{"value":1}`), http.StatusConflict, "TYPE_MISMATCH")
+	expectError(t, s.do(http.MethodPost, "/v1/kv/a0:append", `{}`), http.StatusBadRequest, "BAD_REQUEST")
+}
@@ -2,16 +2,22 @@
 // memory exhaustion and abuse.
 //
 // If the payload exceeds configured size, a 413 error is returned.
+// The limit is read per request so that it can change on reload.
 
 package api
 
 import "net/http"
 
-func MaxPayloadSize(limit int64) func(http.Handler) http.Handler {
+func MaxPayloadSize(limit func() int64) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := traceSpan(r, "middleware.MaxPayloadSize")
+			n := limit()
+			span.SetAttribute("limit_bytes", n)
+			span.End()
+
 			// Wrap request body with MaxBytesReader to enforce hard limit
-			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			r.Body = http.MaxBytesReader(w, r.Body, n)
 			next.ServeHTTP(w, r)
 		})
 	}
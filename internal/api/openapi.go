@@ -0,0 +1,21 @@
+// OpenAPI serves the embedded OpenAPI 3 document describing all
+// routes.
+
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func OpenAPI() HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *APIError {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(openAPISpec)
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+// Problem details (RFC 9457) are an alternative error format for
+// clients that prefer a standard shape over the native one.
+//
+// The format is chosen per request: an Accept header asking for
+// application/problem+json wins, otherwise the configured default
+// applies.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
+)
+
+const (
+	ErrorFormatJSON    = "json"
+	ErrorFormatProblem = "problem"
+
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:kvtxt:problem:"
+)
+
+type problemResponse struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Detail   string    `json:"detail"`
+	Instance string    `json:"instance,omitempty"`
+	Code     ErrorCode `json:"code"`
+
+	Violations []jsonschema.Violation `json:"violations,omitempty"`
+}
+
+// ErrorFormat stores the error format for the request in context.
+func ErrorFormat(defaultFormat string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			format := defaultFormat
+			if acceptsProblem(r.Header.Values("Accept")) {
+				format = ErrorFormatProblem
+			}
+
+			ctx := context.WithValue(r.Context(), constant.ErrorFormatKey, format)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func GetErrorFormat(ctx context.Context) string {
+	if v, ok := ctx.Value(constant.ErrorFormatKey).(string); ok {
+		return v
+	}
+	return ErrorFormatJSON
+}
+
+func acceptsProblem(accept []string) bool {
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			if mediaType == problemContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeProblem(w http.ResponseWriter, err *APIError, reqID string) {
+	title, ok := errorTitles[err.Code]
+	if !ok {
+		title = http.StatusText(err.Status)
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(err.Status)
+	_ = json.NewEncoder(w).Encode(problemResponse{
+		Type:     problemTypePrefix + strings.ToLower(strings.ReplaceAll(string(err.Code), "_", "-")),
+		Title:    title,
+		Status:   err.Status,
+		Detail:   err.Message,
+		Instance: reqID,
+		Code:     err.Code,
+
+		Violations: err.Violations,
+	})
+}
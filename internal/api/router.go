@@ -8,20 +8,69 @@ package api
 
 import (
 	"net/http"
+	"sort"
 )
 
+// Router is an http.ServeMux that records registered routes and
+// their methods, so that they can be checked against the OpenAPI
+// document.
+type Router struct {
+	*http.ServeMux
+	routes []Route
+}
+
+// Route is a registered pattern and the methods it serves.
+type Route struct {
+	Pattern string
+	Methods []string
+}
+
+func NewRouter() *Router {
+	return &Router{ServeMux: http.NewServeMux()}
+}
+
+// HandleMethods registers h for pattern, rejecting other methods.
+func (rt *Router) HandleMethods(pattern string, h HandlerFunc, methods ...string) {
+	rt.routes = append(rt.routes, Route{Pattern: pattern, Methods: methods})
+	rt.ServeMux.Handle(pattern, Adapter(AllowHttpMethods(methods...)(h)))
+}
+
+// HandleByMethod registers a handler per method for pattern.
+func (rt *Router) HandleByMethod(pattern string, handlers map[string]HandlerFunc) {
+	methods := make([]string, 0, len(handlers))
+	for m := range handlers {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+
+	rt.routes = append(rt.routes, Route{Pattern: pattern, Methods: methods})
+	rt.ServeMux.Handle(pattern, Adapter(ByMethod(handlers)))
+}
+
+// Routes returns all registered routes in registration order.
+func (rt *Router) Routes() []Route {
+	return rt.routes
+}
+
 // RegisterRoute registers all HTTP endpoints.
 // Each route should delegate to a thin handler that calls service/storage layer.
 // Avoid embedding business logic inside handlers.
 func RegisterRoute(
-	mux *http.ServeMux,
+	mux *Router,
 	path string,
 	method string,
 	h HandlerFunc,
 ) {
-	handler := Adapter(
-		AllowHttpMethods(method)(h),
-	)
+	mux.HandleMethods(path, h, method)
+}
 
-	mux.Handle(path, handler)
+// RegisterAdminRoute registers an operator endpoint guarded by the admin key.
+func RegisterAdminRoute(
+	mux *Router,
+	path string,
+	adminKey string,
+	h HandlerFunc,
+	methods ...string,
+) {
+	mux.HandleMethods(path, AdminAuth(adminKey)(h), methods...)
 }
@@ -0,0 +1,131 @@
+// Package audit records who created, read, updated or deleted each
+// entry.
+//
+// Every record is hash-chained to its predecessor: the hash covers
+// the record fields and the previous hash, so editing, inserting or
+// removing a row breaks the chain and is detected by Verify.
+// Payloads are never part of an audit record.
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionRead   Action = "read"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// OutcomeOK marks a successful operation. Failures are recorded
+// with the API error code instead.
+const OutcomeOK = "ok"
+
+// Event describes a single audited operation.
+type Event struct {
+	RequestID string
+	Identity  string
+	ClientIP  string
+	Namespace string
+	Key       string
+	Action    Action
+	Outcome   string
+}
+
+// Logger appends events to the audit log.
+// Appends are serialized to keep the hash chain linear.
+type Logger struct {
+	mu    sync.Mutex
+	store *storage.Storage
+	last  string
+}
+
+func New(store *storage.Storage) (*Logger, error) {
+	last, err := store.LastAuditHash()
+	if err != nil {
+		return nil, fmt.Errorf("read audit head: %w", err)
+	}
+
+	return &Logger{store: store, last: last}, nil
+}
+
+func (l *Logger) Record(ev *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := &storage.AuditRecord{
+		Time:      time.Now().Unix(),
+		RequestID: ev.RequestID,
+		Identity:  ev.Identity,
+		ClientIP:  ev.ClientIP,
+		Namespace: ev.Namespace,
+		Key:       ev.Key,
+		Action:    string(ev.Action),
+		Outcome:   ev.Outcome,
+		PrevHash:  l.last,
+	}
+	rec.Hash = hash(rec)
+
+	if err := l.store.AppendAudit(rec); err != nil {
+		return err
+	}
+
+	l.last = rec.Hash
+	return nil
+}
+
+// Verify walks the whole log and recomputes the hash chain.
+// It returns the number of records and the head hash, which
+// operators can store externally to also detect truncation.
+func Verify(store *storage.Storage) (int, string, error) {
+	var (
+		count int
+		prev  string
+	)
+
+	err := store.ScanAudit(func(rec *storage.AuditRecord) error {
+		if rec.PrevHash != prev {
+			return fmt.Errorf("record %d: chain broken, previous hash mismatch", rec.ID)
+		}
+
+		if rec.Hash != hash(rec) {
+			return fmt.Errorf("record %d: content does not match hash", rec.ID)
+		}
+
+		prev = rec.Hash
+		count++
+		return nil
+	})
+
+	return count, prev, err
+}
+
+// hash computes the chained digest of a record. The row ID is
+// excluded so that the chain only depends on content and order.
+func hash(rec *storage.AuditRecord) string {
+	fields, _ := json.Marshal([]any{
+		rec.PrevHash,
+		rec.Time,
+		rec.RequestID,
+		rec.Identity,
+		rec.ClientIP,
+		rec.Namespace,
+		rec.Key,
+		rec.Action,
+		rec.Outcome,
+	})
+
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,270 @@
+package audit
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// newLog records n events in a new database and returns its path
+// and the head hash.
+func newLog(t *testing.T, n int) (string, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	l, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= n; i++ {
+		err := l.Record(&Event{
+			RequestID: fmt.Sprintf("req-%d", i),
+			Identity:  "team",
+			ClientIP:  "203.0.113.7",
+			Namespace: "team",
+			Key:       fmt.Sprintf("key-%d", i),
+			Action:    ActionCreate,
+			Outcome:   OutcomeOK,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path, l.last
+}
+
+func verify(t *testing.T, path string) (int, string, error) {
+	t.Helper()
+
+	store, err := storage.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	return Verify(store)
+}
+
+// tamper runs statements against the database behind the storage
+// layer, with the triggers that keep the log append-only dropped.
+func tamper(t *testing.T, path string, stmts ...string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmts = append([]string{
+		`DROP TRIGGER audit_log_no_update`,
+		`DROP TRIGGER audit_log_no_delete`,
+	}, stmts...)
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestVerifyIntact(t *testing.T) {
+	path, head := newLog(t, 3)
+
+	count, got, err := verify(t, path)
+	if err != nil || count != 3 || got != head {
+		t.Fatalf("Verify = %d, %s, %v, want 3, %s", count, got, err, head)
+	}
+
+	// A new logger continues the chain
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record(&Event{Action: ActionRead, Outcome: "NOT_FOUND"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if count, _, err := verify(t, path); err != nil || count != 4 {
+		t.Fatalf("Verify after reopen = %d, %v", count, err)
+	}
+}
+
+func TestVerifyEmpty(t *testing.T) {
+	path, _ := newLog(t, 0)
+
+	if count, head, err := verify(t, path); err != nil || count != 0 || head != "" {
+		t.Fatalf("Verify = %d, %q, %v", count, head, err)
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	path, _ := newLog(t, 1)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`UPDATE audit_log SET key = 'other'`,
+		`DELETE FROM audit_log`,
+	} {
+		if _, err := db.Exec(stmt); err == nil || !strings.Contains(err.Error(), "append-only") {
+			t.Errorf("%s = %v", stmt, err)
+		}
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	// forged returns an INSERT, with the row ID left as %d, of a
+	// record hashed correctly on its own, as someone who knows the
+	// scheme would write it.
+	forged := func(prev string) string {
+		rec := &storage.AuditRecord{
+			Time: 1, RequestID: "forged", Identity: "team", ClientIP: "203.0.113.7",
+			Namespace: "team", Key: "forged", Action: "delete", Outcome: OutcomeOK,
+			PrevHash: prev,
+		}
+		return fmt.Sprintf(
+			`INSERT INTO audit_log (id, time, request_id, identity, client_ip, namespace, key, action, outcome, prev_hash, hash)
+			VALUES (%%d, 1, 'forged', 'team', '203.0.113.7', 'team', 'forged', 'delete', 'ok', '%s', '%s')`,
+			prev, hash(rec),
+		)
+	}
+
+	tests := []struct {
+		name  string
+		stmts func(path string) []string
+		want  string
+	}{
+		{
+			"edited field",
+			func(string) []string { return []string{`UPDATE audit_log SET key = 'other' WHERE id = 2`} },
+			"record 2: content does not match hash",
+		},
+		{
+			"edited time",
+			func(string) []string { return []string{`UPDATE audit_log SET time = time + 1 WHERE id = 3`} },
+			"record 3: content does not match hash",
+		},
+		{
+			"edited field with its hash recomputed",
+			func(path string) []string {
+				rec := record(t, path, 2)
+				rec.Key = "other"
+				return []string{fmt.Sprintf(`UPDATE audit_log SET key = 'other', hash = '%s' WHERE id = 2`, hash(rec))}
+			},
+			"record 3: chain broken",
+		},
+		{
+			"removed row",
+			func(string) []string { return []string{`DELETE FROM audit_log WHERE id = 2`} },
+			"record 3: chain broken",
+		},
+		{
+			"removed first row",
+			func(string) []string { return []string{`DELETE FROM audit_log WHERE id = 1`} },
+			"record 2: chain broken",
+		},
+		{
+			"inserted row",
+			func(path string) []string {
+				return []string{
+					`UPDATE audit_log SET id = id * 10`,
+					fmt.Sprintf(forged(record(t, path, 1).Hash), 15),
+				}
+			},
+			"record 20: chain broken",
+		},
+		{
+			"inserted first row",
+			func(string) []string { return []string{fmt.Sprintf(forged(""), 0)} },
+			"record 1: chain broken",
+		},
+		{
+			"appended row with a forged link",
+			func(string) []string { return []string{fmt.Sprintf(forged("0000"), 10)} },
+			"record 10: chain broken",
+		},
+		{
+			"swapped rows",
+			func(string) []string {
+				return []string{
+					`UPDATE audit_log SET id = 100 WHERE id = 2`,
+					`UPDATE audit_log SET id = 2 WHERE id = 3`,
+					`UPDATE audit_log SET id = 3 WHERE id = 100`,
+				}
+			},
+			"record 2: chain broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := newLog(t, 4)
+			tamper(t, path, tt.stmts(path)...)
+
+			_, _, err := verify(t, path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifyHeadExposesTruncation checks that removing the newest
+// rows, which leaves a valid chain, changes the head hash.
+func TestVerifyHeadExposesTruncation(t *testing.T) {
+	path, head := newLog(t, 3)
+	tamper(t, path, `DELETE FROM audit_log WHERE id = 3`)
+
+	count, got, err := verify(t, path)
+	if err != nil || count != 2 {
+		t.Fatalf("Verify = %d, %v", count, err)
+	}
+	if got == head {
+		t.Error("head unchanged after truncation")
+	}
+}
+
+func record(t *testing.T, path string, id int64) *storage.AuditRecord {
+	t.Helper()
+
+	store, err := storage.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var found *storage.AuditRecord
+	err = store.ScanAudit(func(rec *storage.AuditRecord) error {
+		if rec.ID == id {
+			found = rec
+		}
+		return nil
+	})
+	if err != nil || found == nil {
+		t.Fatalf("record %d: %v", id, err)
+	}
+	return found
+}
@@ -6,6 +6,7 @@ package cache
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,6 +22,19 @@ type Cache struct {
 	maxSize int
 	ll      *list.List
 	items   map[string]*list.Element
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Stats is a point-in-time snapshot of cache counters.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+	MaxSize   int
 }
 
 func New(maxSize int) *Cache {
@@ -41,6 +55,7 @@ func (c *Cache) Get(key string) (value string, contentType string, ok bool) {
 
 	el, ok := c.items[key]
 	if !ok {
+		c.misses.Add(1)
 		return "", "", false
 	}
 
@@ -48,10 +63,12 @@ func (c *Cache) Get(key string) (value string, contentType string, ok bool) {
 
 	if ent.expiresAt != nil && *ent.expiresAt <= time.Now().Unix() {
 		c.removeElement(el)
+		c.misses.Add(1)
 		return "", "", false
 	}
 
 	c.ll.MoveToFront(el)
+	c.hits.Add(1)
 	return ent.value, ent.contentType, true
 }
 
@@ -62,6 +79,7 @@ func (c *Cache) Set(key, value string, contentType string, expiresAt *int64) {
 	if el, ok := c.items[key]; ok {
 		c.ll.MoveToFront(el)
 		el.Value.(*entry).value = value
+		el.Value.(*entry).contentType = contentType
 		el.Value.(*entry).expiresAt = expiresAt
 		return
 	}
@@ -79,10 +97,64 @@ func (c *Cache) Set(key, value string, contentType string, expiresAt *int64) {
 	}
 }
 
+// Delete removes a single entry if present.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Resize changes the capacity, evicting the least recently used
+// entries when shrinking.
+func (c *Cache) Resize(maxSize int) {
+	if maxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	for c.ll.Len() > c.maxSize {
+		c.removeOldest()
+	}
+}
+
+// Flush removes all entries from the cache.
+func (c *Cache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.ll.Len()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+
+	return n
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	size := c.ll.Len()
+	maxSize := c.maxSize
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+		MaxSize:   maxSize,
+	}
+}
+
 func (c *Cache) removeOldest() {
 	el := c.ll.Back()
 	if el != nil {
 		c.removeElement(el)
+		c.evictions.Add(1)
 	}
 }
 
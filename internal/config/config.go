@@ -1,87 +1,458 @@
 // Package config loads and validates application configuration
-// from environment variables or config files.
+// from command-line flags, environment variables and config files.
+//
+// Sources are layered with the following precedence:
+// flags > environment > config file > defaults.
 
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
 )
 
 type Config struct {
-	AppPort          string
-	DatabaseFilePath string
-	EncryptionKey    string
-	MaxPayloadSize   int
+	AppPort          string      `yaml:"port"`
+	DatabaseFilePath string      `yaml:"db_path"`
+	EncryptionKey    string      `yaml:"encryption_key"`
+	MaxPayloadSize   int         `yaml:"max_payload_size_mb"`
+	MaxBatchSize     int         `yaml:"max_batch_size"`
+	TrustedProxies   PrefixList  `yaml:"trusted_proxies"`
+	NamespacesFile   string      `yaml:"namespaces_file"`
+	Namespaces       []Namespace `yaml:"namespaces"`
+	AdminKey         string      `yaml:"admin_key"`
+	AuditEnabled     bool        `yaml:"audit_enabled"`
+	OTLPEndpoint     string      `yaml:"otlp_endpoint"`
+	ServiceName      string      `yaml:"service_name"`
+	LogLevel         string      `yaml:"log_level"`
+	ErrorFormat      string      `yaml:"error_format"`
+
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	CacheSize       int           `yaml:"cache_size"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+
+	MinTTL     time.Duration `yaml:"min_ttl"`
+	DefaultTTL time.Duration `yaml:"default_ttl"`
+	MaxTTL     time.Duration `yaml:"max_ttl"`
+
+	KeyStrategy  string `yaml:"key_strategy"`
+	KeyLength    int    `yaml:"key_length"`
+	KeyWords     int    `yaml:"key_words"`
+	KeyCheckChar bool   `yaml:"key_check_char"`
+
+	ContentTypes ContentTypePolicy `yaml:"content_types"`
+}
+
+// ContentTypePolicy restricts the content types of entries. Entries
+// are matched by patterns such as application/json, text/* or
+// application/*+json.
+type ContentTypePolicy struct {
+	Allow  []string                    `yaml:"allow"`
+	Deny   []string                    `yaml:"deny"`
+	Limits map[string]ContentTypeLimit `yaml:"limits"`
 }
 
-func Load() (*Config, error) {
-	cfg := &Config{
-		AppPort:          os.Getenv("KVTXT_PORT"),
-		DatabaseFilePath: os.Getenv("KVTXT_DB_PATH"),
-		EncryptionKey:    os.Getenv("KVTXT_ENCRYPTION_KEY"),
-		MaxPayloadSize:   getEnvInt("KVTXT_MAX_PAYLOAD_SIZE", constant.DefaultMaxPayloadSizeMB),
+// ContentTypeLimit bounds entries of matching content types.
+// Zero values leave the namespace limits in place.
+type ContentTypeLimit struct {
+	MaxSizeBytes int64         `yaml:"max_size_bytes"`
+	MaxTTL       time.Duration `yaml:"max_ttl"`
+}
+
+// Policy builds the content type policy.
+func (p ContentTypePolicy) Policy() (*contenttype.Policy, error) {
+	limits := make(map[string]contenttype.Limit, len(p.Limits))
+	for pattern, l := range p.Limits {
+		limits[pattern] = contenttype.Limit{MaxSize: l.MaxSizeBytes, MaxTTL: l.MaxTTL}
 	}
 
-	if cfg.AppPort == "" {
-		cfg.AppPort = constant.DefaultPort
+	return contenttype.NewPolicy(p.Allow, p.Deny, limits)
+}
+
+// Namespace describes a tenant sharing the deployment.
+// Zero-valued limits fall back to the global defaults.
+type Namespace struct {
+	Name             string   `json:"name" yaml:"name"`
+	APIKeys          []string `json:"api_keys" yaml:"api_keys"`
+	MaxTTLSeconds    int64    `json:"max_ttl_seconds" yaml:"max_ttl_seconds"`
+	MaxPayloadSizeMB int      `json:"max_payload_size_mb" yaml:"max_payload_size_mb"`
+	QuotaBytes       int64    `json:"quota_bytes" yaml:"quota_bytes"`
+	QuotaEntries     int64    `json:"quota_entries" yaml:"quota_entries"`
+
+	// Schemas maps key prefixes to the JSON Schema that values under
+	// the prefix must conform to. They live in the configuration so
+	// that clients of the namespace cannot replace them.
+	Schemas map[string]any `json:"schemas" yaml:"schemas"`
+}
+
+// SchemaPolicy compiles the schemas of the namespace by prefix.
+func (ns Namespace) SchemaPolicy() (map[string]*jsonschema.Schema, error) {
+	if len(ns.Schemas) == 0 {
+		return nil, nil
 	}
 
-	if !strings.HasPrefix(cfg.AppPort, ":") {
-		return nil, fmt.Errorf("invalid KVTXT_PORT format: %s", cfg.AppPort)
+	schemas := make(map[string]*jsonschema.Schema, len(ns.Schemas))
+
+	for _, prefix := range slices.Sorted(maps.Keys(ns.Schemas)) {
+		data, err := json.Marshal(ns.Schemas[prefix])
+		if err != nil {
+			return nil, fmt.Errorf("prefix %q: schema is not a JSON document", prefix)
+		}
+
+		schemas[prefix], err = jsonschema.Compile(data)
+		if err != nil {
+			return nil, fmt.Errorf("prefix %q: %w", prefix, err)
+		}
+	}
+
+	return schemas, nil
+}
+
+// Defaults returns the configuration used when no source sets a value.
+func Defaults() *Config {
+	return &Config{
+		AppPort:         constant.DefaultPort,
+		MaxPayloadSize:  constant.DefaultMaxPayloadSizeMB,
+		MaxBatchSize:    constant.DefaultMaxBatchSize,
+		ServiceName:     constant.DefaultServiceName,
+		LogLevel:        "info",
+		ErrorFormat:     "json",
+		ReadTimeout:     constant.ReadTimeout,
+		WriteTimeout:    constant.WriteTimeout,
+		IdleTimeout:     constant.IdleTimeout,
+		ShutdownTimeout: constant.ShutdownTimeout,
+		CacheSize:       constant.DefaultCacheSize,
+		CleanupInterval: constant.CleanupInterval,
+		MinTTL:          constant.MinTTL,
+		DefaultTTL:      constant.DefaultTTL,
+		MaxTTL:          constant.MaxTTL,
+		KeyStrategy:     constant.DefaultKeyStrategy,
+		KeyLength:       constant.DefaultKeyLength,
+		KeyWords:        constant.DefaultKeyWords,
+	}
+}
+
+// Load builds the effective configuration from args, the environment
+// and the optional config file given by --config or KVTXT_CONFIG.
+// All invalid settings are reported together.
+func Load(args []string) (*Config, error) {
+	return LoadCommand("kvtxt", args, nil)
+}
+
+// LoadCommand is Load for a command that has flags of its own.
+// declare, if not nil, adds them to the flag set before args are
+// parsed.
+func LoadCommand(name string, args []string, declare func(fs *flag.FlagSet)) (*Config, error) {
+	cfg, errs, err := load(name, args, declare)
+	if err != nil {
+		return nil, err
 	}
 
-	port, err := strconv.Atoi(strings.TrimPrefix(cfg.AppPort, ":"))
-	if err != nil || port < 1 || port > 65535 {
-		return nil, fmt.Errorf("invalid KVTXT_PORT value: %s", cfg.AppPort)
+	errs = append(errs, cfg.validate()...)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return cfg, nil
+}
+
+// LoadDatabase is LoadCommand for commands that only work on the
+// database file, such as migrate. Only db_path is required, and the
+// file need not exist yet.
+func LoadDatabase(name string, args []string, declare func(fs *flag.FlagSet)) (*Config, error) {
+	cfg, errs, err := load(name, args, declare)
+	if err != nil {
+		return nil, err
 	}
 
 	if cfg.DatabaseFilePath == "" {
-		return nil, errors.New("KVTXT_DB_PATH is required")
+		errs = append(errs, errors.New("db_path: is required"))
 	}
 
-	if _, err := os.Stat(cfg.DatabaseFilePath); err != nil {
-		return nil, fmt.Errorf("invalid KVTXT_DB_PATH: %w", err)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
-	if cfg.EncryptionKey == "" {
-		return nil, errors.New("KVTXT_ENCRYPTION_KEY is required")
+	return cfg, nil
+}
+
+// load reads every source over the defaults and returns the
+// settings that failed to parse. The error is only set when args
+// cannot be parsed.
+func load(name string, args []string, declare func(fs *flag.FlagSet)) (*Config, []error, error) {
+	cfg := Defaults()
+
+	fs, configPath := newFlagSet(name, cfg)
+	if declare != nil {
+		declare(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
 	}
 
-	if len(cfg.EncryptionKey) < constant.MinEncryptionKeyLength {
-		return nil, fmt.Errorf(
-			"KVTXT_ENCRYPTION_KEY must be at least %d characters",
-			constant.MinEncryptionKeyLength,
-		)
+	if *configPath == "" {
+		*configPath = os.Getenv("KVTXT_CONFIG")
+	}
+
+	var errs []error
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, cfg); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %w", *configPath, err))
+		}
+	}
+
+	errs = append(errs, applyEnv(cfg)...)
+	errs = append(errs, applyFlags(fs, cfg)...)
+
+	if cfg.NamespacesFile != "" {
+		if len(cfg.Namespaces) > 0 {
+			errs = append(errs, errors.New("namespaces: set either inline namespaces or namespaces_file, not both"))
+		} else {
+			namespaces, err := loadNamespaces(cfg.NamespacesFile)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("namespaces_file: %w", err))
+			}
+			cfg.Namespaces = namespaces
+		}
+	}
+
+	return cfg, errs, nil
+}
+
+// Level returns the parsed log level. It must only be called on a
+// validated configuration.
+func (cfg *Config) Level() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	return level
+}
+
+// validate checks every field and returns all violations.
+func (cfg *Config) validate() []error {
+	var errs []error
+
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !strings.HasPrefix(cfg.AppPort, ":") {
+		fail("port: invalid format %q, expected :<port>", cfg.AppPort)
+	} else if port, err := strconv.Atoi(strings.TrimPrefix(cfg.AppPort, ":")); err != nil || port < 1 || port > 65535 {
+		fail("port: invalid value %q", cfg.AppPort)
+	}
+
+	if cfg.DatabaseFilePath == "" {
+		fail("db_path: is required")
+	} else if _, err := os.Stat(cfg.DatabaseFilePath); err != nil {
+		fail("db_path: %v", err)
+	}
+
+	if cfg.EncryptionKey == "" {
+		fail("encryption_key: is required")
+	} else if len(cfg.EncryptionKey) < constant.MinEncryptionKeyLength {
+		fail("encryption_key: must be at least %d characters", constant.MinEncryptionKeyLength)
 	}
 
 	if cfg.MaxPayloadSize < constant.MinMaxPayloadSizeMB ||
 		cfg.MaxPayloadSize > constant.MaxMaxPayloadSizeMB {
-		return nil, fmt.Errorf(
-			"KVTXT_MAX_PAYLOAD_SIZE must be between %d and %d MB",
+		fail(
+			"max_payload_size_mb: must be between %d and %d",
 			constant.MinMaxPayloadSizeMB,
 			constant.MaxMaxPayloadSizeMB,
 		)
 	}
 
-	return cfg, nil
+	if cfg.MaxBatchSize < 1 || cfg.MaxBatchSize > constant.MaxMaxBatchSize {
+		fail("max_batch_size: must be between 1 and %d", constant.MaxMaxBatchSize)
+	}
+
+	if cfg.AdminKey != "" && len(cfg.AdminKey) < constant.MinAPIKeyLength {
+		fail("admin_key: must be at least %d characters", constant.MinAPIKeyLength)
+	}
+
+	if cfg.ServiceName == "" {
+		fail("service_name: must not be empty")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		fail("log_level: invalid value %q, expected debug, info, warn or error", cfg.LogLevel)
+	}
+
+	if cfg.ErrorFormat != "json" && cfg.ErrorFormat != "problem" {
+		fail("error_format: invalid value %q, expected json or problem", cfg.ErrorFormat)
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		u, err := url.Parse(cfg.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("otlp_endpoint: invalid URL %q", cfg.OTLPEndpoint)
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read_timeout", cfg.ReadTimeout},
+		{"write_timeout", cfg.WriteTimeout},
+		{"idle_timeout", cfg.IdleTimeout},
+		{"shutdown_timeout", cfg.ShutdownTimeout},
+		{"cleanup_interval", cfg.CleanupInterval},
+		{"min_ttl", cfg.MinTTL},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			fail("%s: must be positive", d.name)
+		}
+	}
+
+	if cfg.MinTTL%time.Second != 0 || cfg.DefaultTTL%time.Second != 0 || cfg.MaxTTL%time.Second != 0 {
+		fail("ttl: min_ttl, default_ttl and max_ttl must be whole seconds")
+	}
+
+	if cfg.DefaultTTL < cfg.MinTTL || cfg.DefaultTTL > cfg.MaxTTL {
+		fail("default_ttl: must be between min_ttl (%s) and max_ttl (%s)", cfg.MinTTL, cfg.MaxTTL)
+	}
+
+	if cfg.CacheSize < 1 {
+		fail("cache_size: must be at least 1")
+	}
+
+	if cfg.KeyLength < constant.MinKeyLength || cfg.KeyLength > constant.MaxKeyLength {
+		fail("key_length: must be between %d and %d", constant.MinKeyLength, constant.MaxKeyLength)
+	}
+
+	switch cfg.KeyStrategy {
+	case "base62", "uuidv7", "diceware":
+	default:
+		fail("key_strategy: invalid value %q, expected base62, uuidv7 or diceware", cfg.KeyStrategy)
+	}
+
+	if cfg.KeyWords < constant.MinKeyWords || cfg.KeyWords > constant.MaxKeyWords {
+		fail("key_words: must be between %d and %d", constant.MinKeyWords, constant.MaxKeyWords)
+	}
+
+	if _, err := cfg.ContentTypes.Policy(); err != nil {
+		fail("content_types: %v", err)
+	}
+
+	for pattern, l := range cfg.ContentTypes.Limits {
+		if l.MaxSizeBytes < 0 {
+			fail("content_types.limits.%s.max_size_bytes: must not be negative", pattern)
+		}
+
+		if l.MaxTTL%time.Second != 0 || (l.MaxTTL != 0 && (l.MaxTTL < cfg.MinTTL || l.MaxTTL > cfg.MaxTTL)) {
+			fail("content_types.limits.%s.max_ttl: must be whole seconds between min_ttl (%s) and max_ttl (%s)", pattern, cfg.MinTTL, cfg.MaxTTL)
+		}
+	}
+
+	errs = append(errs, cfg.validateNamespaces()...)
+
+	return errs
 }
 
-func getEnvInt(key string, defaultVal int) int {
-	valStr := os.Getenv(key)
-	if valStr == "" {
-		return defaultVal
+// validateNamespaces checks tenant definitions.
+// Every API key must be unique across all namespaces.
+func (cfg *Config) validateNamespaces() []error {
+	var errs []error
+
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	names := make(map[string]struct{}, len(cfg.Namespaces))
+	keys := make(map[string]struct{})
+
+	for _, ns := range cfg.Namespaces {
+		if !constant.NamespacePattern.MatchString(ns.Name) {
+			fail("namespaces: invalid name %q", ns.Name)
+			continue
+		}
+
+		if _, ok := names[ns.Name]; ok {
+			fail("namespaces: duplicate namespace %s", ns.Name)
+		}
+		names[ns.Name] = struct{}{}
+
+		if len(ns.APIKeys) == 0 {
+			fail("namespaces.%s.api_keys: at least one key is required", ns.Name)
+		}
+
+		for _, key := range ns.APIKeys {
+			if len(key) < constant.MinAPIKeyLength {
+				fail(
+					"namespaces.%s.api_keys: keys must be at least %d characters",
+					ns.Name,
+					constant.MinAPIKeyLength,
+				)
+				continue
+			}
+
+			if _, ok := keys[key]; ok {
+				fail("namespaces.%s.api_keys: key already in use", ns.Name)
+			}
+			keys[key] = struct{}{}
+		}
+
+		if ns.MaxTTLSeconds < 0 || time.Duration(ns.MaxTTLSeconds)*time.Second > cfg.MaxTTL {
+			fail("namespaces.%s.max_ttl_seconds: must be between 0 and max_ttl", ns.Name)
+		}
+
+		if ns.MaxPayloadSizeMB != 0 &&
+			(ns.MaxPayloadSizeMB < constant.MinMaxPayloadSizeMB ||
+				ns.MaxPayloadSizeMB > constant.MaxMaxPayloadSizeMB) {
+			fail(
+				"namespaces.%s.max_payload_size_mb: must be between %d and %d",
+				ns.Name,
+				constant.MinMaxPayloadSizeMB,
+				constant.MaxMaxPayloadSizeMB,
+			)
+		}
+
+		if ns.QuotaBytes < 0 || ns.QuotaEntries < 0 {
+			fail("namespaces.%s: quotas must not be negative", ns.Name)
+		}
+
+		if _, err := ns.SchemaPolicy(); err != nil {
+			fail("namespaces.%s.schemas: %v", ns.Name, err)
+		}
 	}
 
-	val, err := strconv.Atoi(valStr)
+	return errs
+}
+
+// loadNamespaces reads tenant definitions from a JSON file.
+func loadNamespaces(path string) ([]Namespace, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return defaultVal
+		return nil, err
+	}
+
+	var namespaces []Namespace
+	if err := json.Unmarshal(data, &namespaces); err != nil {
+		return nil, err
 	}
 
-	return val
+	return namespaces, nil
 }
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setup sets the required settings in the environment and returns
+// the path of a config file holding content.
+func setup(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	db := filepath.Join(dir, "kv.db")
+	if err := os.WriteFile(db, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KVTXT_DB_PATH", db)
+	t.Setenv("KVTXT_ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+
+	path := filepath.Join(dir, "kvtxt.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := setup(t, `
+cache_size: 10
+key_length: 30
+log_level: warn
+read_timeout: 45
+`)
+	t.Setenv("KVTXT_CACHE_SIZE", "20")
+	t.Setenv("KVTXT_LOG_LEVEL", "debug")
+
+	cfg, err := Load([]string{"--config", path, "--log-level", "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over env", cfg.LogLevel, "error"},
+		{"env over file", cfg.CacheSize, 20},
+		{"file over default", cfg.KeyLength, 30},
+		{"file duration", cfg.ReadTimeout, 45 * time.Second},
+		{"default", cfg.WriteTimeout, Defaults().WriteTimeout},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestLoadFileDurations checks that durations in the file read as in
+// the environment and flags, with plain integers as seconds.
+func TestLoadFileDurations(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"0x10", 16 * time.Second},
+		{`"30"`, 0},
+		{"90s", 90 * time.Second},
+		{"1h30m", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		path := setup(t, "idle_timeout: "+tt.value+"\n")
+
+		cfg, err := Load([]string{"--config", path})
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("idle_timeout: %s accepted", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("idle_timeout: %s: %v", tt.value, err)
+			continue
+		}
+		if cfg.IdleTimeout != tt.want {
+			t.Errorf("idle_timeout: %s = %s, want %s", tt.value, cfg.IdleTimeout, tt.want)
+		}
+	}
+
+	// Nested durations follow the same rule
+	path := setup(t, `
+content_types:
+  limits:
+    text/*:
+      max_ttl: 3600
+`)
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.ContentTypes.Limits["text/*"].MaxTTL; got != time.Hour {
+		t.Errorf("content_types.limits.text/*.max_ttl = %s, want 1h", got)
+	}
+}
+
+// TestLoadReportsEveryError checks that file, environment and
+// validation errors are reported together.
+func TestLoadReportsEveryError(t *testing.T) {
+	path := setup(t, `
+cache_sise: 10
+namespaces:
+  - name: team
+    api_keys: [team-api-key-0123456789]
+    max_ttl: 60
+key_words: many
+`)
+	t.Setenv("KVTXT_KEY_LENGTH", "2")
+
+	_, err := Load([]string{"--config", path, "--cache-size", "lots"})
+	if err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+
+	for _, want := range []string{
+		"line 2: field cache_sise not found in type config.Config",
+		"line 6: field max_ttl not found in type config.Namespace",
+		"line 7: cannot unmarshal !!str `many`",
+		"key_length: must be between",
+		`--cache-size: invalid integer "lots"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+// TestLoadNamespaceSchemas checks that policy schemas are written
+// inline in YAML and compiled when the configuration is loaded.
+func TestLoadNamespaceSchemas(t *testing.T) {
+	path := setup(t, `
+namespaces:
+  - name: team
+    api_keys: [team-api-key-0123456789]
+    schemas:
+      "flags/":
+        title: Feature flag
+        type: object
+        required: [enabled]
+        properties:
+          rollout: {type: integer, maximum: 100}
+`)
+
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := cfg.Namespaces[0].SchemaPolicy()
+	if err != nil || schemas["flags/"] == nil {
+		t.Fatalf("SchemaPolicy = %v, %v", schemas, err)
+	}
+
+	violations, err := schemas["flags/"].Validate([]byte(`{"rollout":150}`))
+	if err != nil || len(violations) != 2 {
+		t.Errorf("Validate = %v, %v, want two violations", violations, err)
+	}
+
+	path = setup(t, `
+namespaces:
+  - name: team
+    api_keys: [team-api-key-0123456789]
+    schemas:
+      "flags/": {type: flag}
+`)
+
+	_, err = Load([]string{"--config", path})
+	if err == nil || !strings.Contains(err.Error(), `namespaces.team.schemas: prefix "flags/"`) {
+		t.Errorf("Load = %v, want a schema error", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	setup(t, "")
+	t.Setenv("KVTXT_PORT", "8080")
+
+	_, err := Load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	if err == nil {
+		t.Fatal("missing config file accepted")
+	}
+
+	for _, want := range []string{"missing.yaml", "port: invalid format"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.EncryptionKey = "secret-encryption-key"
+	cfg.AdminKey = "secret-admin-key"
+	cfg.Namespaces = []Namespace{{Name: "team", APIKeys: []string{"secret-api-key"}}}
+
+	out := cfg.Redacted()
+
+	if out.EncryptionKey != "REDACTED" || out.AdminKey != "REDACTED" || out.Namespaces[0].APIKeys[0] != "REDACTED" {
+		t.Errorf("Redacted = %+v", out)
+	}
+	if cfg.Namespaces[0].APIKeys[0] != "secret-api-key" {
+		t.Error("Redacted changed the original")
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	t.Setenv("KVTXT_ENCRYPTION_KEY", "")
+	t.Setenv("KVTXT_DB_PATH", "")
+
+	path := filepath.Join(t.TempDir(), "new.db")
+
+	cfg, err := LoadDatabase("migrate", []string{"--db-path", path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatabaseFilePath != path {
+		t.Errorf("db_path = %q, want %q", cfg.DatabaseFilePath, path)
+	}
+
+	if _, err := LoadDatabase("migrate", nil, nil); err == nil || !strings.Contains(err.Error(), "db_path: is required") {
+		t.Errorf("LoadDatabase without db_path = %v", err)
+	}
+
+	// The full configuration still needs the database to exist
+	if _, err := Load([]string{"--db-path", path}); err == nil || !strings.Contains(err.Error(), "db_path:") {
+		t.Errorf("Load with a missing database = %v", err)
+	}
+}
@@ -0,0 +1,452 @@
+// Sources binds configuration fields to environment variables,
+// command-line flags and the YAML config file.
+//
+// Every setting is declared once in the settings table so that
+// env names, flag names and file keys cannot drift apart.
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  func(cfg *Config) flag.Value
+}
+
+// settings lists every scalar configuration field.
+// Secrets are excluded from flags to keep them out of process lists.
+var settings = []setting{
+	{"port", "KVTXT_PORT", "HTTP bind address, e.g. :8080", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.AppPort) }},
+	{"db_path", "KVTXT_DB_PATH", "SQLite database file", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.DatabaseFilePath) }},
+	{"encryption_key", "KVTXT_ENCRYPTION_KEY", "base64 encoded 32-byte master key", true,
+		func(c *Config) flag.Value { return (*stringValue)(&c.EncryptionKey) }},
+	{"max_payload_size_mb", "KVTXT_MAX_PAYLOAD_SIZE", "maximum request body size in MB", false,
+		func(c *Config) flag.Value { return (*intValue)(&c.MaxPayloadSize) }},
+	{"max_batch_size", "KVTXT_MAX_BATCH_SIZE", "maximum number of items in a batch request", false,
+		func(c *Config) flag.Value { return (*intValue)(&c.MaxBatchSize) }},
+	{"trusted_proxies", "KVTXT_TRUSTED_PROXIES", "comma-separated trusted proxy CIDRs", false,
+		func(c *Config) flag.Value { return &c.TrustedProxies }},
+	{"namespaces_file", "KVTXT_NAMESPACES_FILE", "JSON file defining namespaces", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.NamespacesFile) }},
+	{"admin_key", "KVTXT_ADMIN_KEY", "bearer key for /admin routes", true,
+		func(c *Config) flag.Value { return (*stringValue)(&c.AdminKey) }},
+	{"audit_enabled", "KVTXT_AUDIT_ENABLED", "record create/read/delete events in the audit log", false,
+		func(c *Config) flag.Value { return (*boolValue)(&c.AuditEnabled) }},
+	{"otlp_endpoint", "KVTXT_OTLP_ENDPOINT", "OTLP/HTTP traces URL", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.OTLPEndpoint) }},
+	{"service_name", "KVTXT_SERVICE_NAME", "service.name reported with spans", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.ServiceName) }},
+	{"log_level", "KVTXT_LOG_LEVEL", "log level: debug, info, warn or error", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.LogLevel) }},
+	{"error_format", "KVTXT_ERROR_FORMAT", "default error body: json or problem (RFC 9457)", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.ErrorFormat) }},
+	{"read_timeout", "KVTXT_READ_TIMEOUT", "HTTP read timeout", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.ReadTimeout) }},
+	{"write_timeout", "KVTXT_WRITE_TIMEOUT", "HTTP write timeout", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.WriteTimeout) }},
+	{"idle_timeout", "KVTXT_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.IdleTimeout) }},
+	{"shutdown_timeout", "KVTXT_SHUTDOWN_TIMEOUT", "graceful shutdown timeout", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.ShutdownTimeout) }},
+	{"cache_size", "KVTXT_CACHE_SIZE", "maximum number of cached entries", false,
+		func(c *Config) flag.Value { return (*intValue)(&c.CacheSize) }},
+	{"cleanup_interval", "KVTXT_CLEANUP_INTERVAL", "interval between expired entry cleanups", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.CleanupInterval) }},
+	{"min_ttl", "KVTXT_MIN_TTL", "minimum entry TTL", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.MinTTL) }},
+	{"default_ttl", "KVTXT_DEFAULT_TTL", "TTL applied when a request sets none", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.DefaultTTL) }},
+	{"max_ttl", "KVTXT_MAX_TTL", "maximum entry TTL", false,
+		func(c *Config) flag.Value { return (*durationValue)(&c.MaxTTL) }},
+	{"key_strategy", "KVTXT_KEY_STRATEGY", "key generator: base62, uuidv7 or diceware", false,
+		func(c *Config) flag.Value { return (*stringValue)(&c.KeyStrategy) }},
+	{"key_length", "KVTXT_KEY_LENGTH", "length of generated base62 keys", false,
+		func(c *Config) flag.Value { return (*intValue)(&c.KeyLength) }},
+	{"key_words", "KVTXT_KEY_WORDS", "number of words in diceware keys", false,
+		func(c *Config) flag.Value { return (*intValue)(&c.KeyWords) }},
+	{"key_check_char", "KVTXT_KEY_CHECK_CHAR", "append a check character to generated keys", false,
+		func(c *Config) flag.Value { return (*boolValue)(&c.KeyCheckChar) }},
+}
+
+// newFlagSet declares a flag per non-secret setting. Flag values are
+// only recorded during parsing and applied last by applyFlags, so
+// that they take precedence over the file and the environment.
+func newFlagSet(name string, cfg *Config) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML config file (env KVTXT_CONFIG)")
+
+	defaults := Defaults()
+
+	for _, s := range settings {
+		if s.secret {
+			continue
+		}
+
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		def := s.value(defaults)
+
+		if b, ok := def.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			fs.Var(&rawBool{}, flagName(s.key), usage)
+			continue
+		}
+
+		fs.Var(&rawValue{def: def.String()}, flagName(s.key), usage)
+	}
+
+	return fs, configPath
+}
+
+func applyEnv(cfg *Config) []error {
+	var errs []error
+
+	for _, s := range settings {
+		raw, ok := os.LookupEnv(s.env)
+		if !ok || raw == "" {
+			continue
+		}
+
+		if err := s.value(cfg).Set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+
+	return errs
+}
+
+func applyFlags(fs *flag.FlagSet, cfg *Config) []error {
+	var errs []error
+
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.key) != f.Name {
+				continue
+			}
+
+			if err := s.value(cfg).Set(f.Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+			}
+		}
+	})
+
+	return errs
+}
+
+// loadFile decodes a YAML config file over cfg.
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := yaml.NewDecoder(f).Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// UnmarshalYAML decodes a config file. Durations accept plain
+// integers as seconds, as in the environment and flags, and unknown
+// keys are rejected to catch typos.
+func (cfg *Config) UnmarshalYAML(node *yaml.Node) error {
+	errs := prepareFields(node, reflect.TypeOf(Config{}))
+
+	if err := node.Decode((*plainConfig)(cfg)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// plainConfig has no UnmarshalYAML method, so that decoding into it
+// does not recurse.
+type plainConfig Config
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// prepareNode walks node as a value of type t. It rewrites integer
+// durations to seconds and reports keys that match no field, which
+// node.Decode would silently ignore.
+func prepareNode(node *yaml.Node, t reflect.Type) []error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	// Types decoding themselves check their own input
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var errs []error
+
+	switch {
+	case t == durationType:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			break
+		}
+		if n, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			node.Value = (time.Duration(n) * time.Second).String()
+			node.Tag = "!!str"
+		}
+
+	case t.Kind() == reflect.Pointer:
+		errs = prepareNode(node, t.Elem())
+
+	case t.Kind() == reflect.Struct:
+		errs = prepareFields(node, t)
+
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			errs = append(errs, prepareNode(item, t.Elem())...)
+		}
+
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			errs = append(errs, prepareNode(node.Content[i], t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// prepareFields walks the mapping node as the fields of struct
+// type t.
+func prepareFields(node *yaml.Node, t reflect.Type) []error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var errs []error
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		field, ok := yamlField(t, key.Value)
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: field %s not found in type %s", key.Line, key.Value, t))
+			continue
+		}
+		errs = append(errs, prepareNode(value, field.Type)...)
+	}
+
+	return errs
+}
+
+// yamlField returns the field of struct type t decoded from key.
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if f.IsExported() && name == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Redacted returns a copy of cfg that is safe to print or log.
+func (cfg *Config) Redacted() *Config {
+	const mask = "REDACTED"
+
+	out := *cfg
+
+	if out.EncryptionKey != "" {
+		out.EncryptionKey = mask
+	}
+
+	if out.AdminKey != "" {
+		out.AdminKey = mask
+	}
+
+	out.Namespaces = make([]Namespace, len(cfg.Namespaces))
+	for i, ns := range cfg.Namespaces {
+		ns.APIKeys = make([]string, len(cfg.Namespaces[i].APIKeys))
+		for j := range ns.APIKeys {
+			ns.APIKeys[j] = mask
+		}
+		out.Namespaces[i] = ns
+	}
+
+	return &out
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+// durationValue accepts Go durations ("90s", "720h") or plain
+// integers, which are interpreted as seconds.
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	s = strings.TrimSpace(s)
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*v = durationValue(time.Duration(n) * time.Second)
+		return nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// rawValue and rawBool record a flag's raw text for applyFlags.
+type rawValue struct {
+	def string
+	raw *string
+}
+
+func (v *rawValue) Set(s string) error { v.raw = &s; return nil }
+
+func (v *rawValue) String() string {
+	if v.raw != nil {
+		return *v.raw
+	}
+	return v.def
+}
+
+type rawBool struct {
+	raw string
+}
+
+func (v *rawBool) Set(s string) error { v.raw = s; return nil }
+func (v *rawBool) String() string     { return v.raw }
+func (v *rawBool) IsBoolFlag() bool   { return true }
+
+// PrefixList is a list of CIDRs. A bare IP address is treated as a
+// single-host prefix.
+type PrefixList []netip.Prefix
+
+// Set parses a comma-separated list, replacing the current value.
+func (p *PrefixList) Set(val string) error {
+	var prefixes PrefixList
+
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		prefix, err := parsePrefix(item)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	*p = prefixes
+	return nil
+}
+
+func (p *PrefixList) String() string {
+	if p == nil {
+		return ""
+	}
+
+	items := make([]string, len(*p))
+	for i, prefix := range *p {
+		items[i] = prefix.String()
+	}
+	return strings.Join(items, ",")
+}
+
+func (p *PrefixList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return p.Set(node.Value)
+	}
+
+	var items []string
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	return p.Set(strings.Join(items, ","))
+}
+
+func (p PrefixList) MarshalYAML() (any, error) {
+	items := make([]string, len(p))
+	for i, prefix := range p {
+		items[i] = prefix.String()
+	}
+	return items, nil
+}
+
+func parsePrefix(item string) (netip.Prefix, error) {
+	if !strings.Contains(item, "/") {
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(item)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
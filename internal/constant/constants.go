@@ -1,6 +1,9 @@
 package constant
 
-import "time"
+import (
+	"regexp"
+	"time"
+)
 
 // Server configuration
 const (
@@ -19,6 +22,12 @@ const (
 	MB                      = int64(1 << 20)
 )
 
+// Batch configuration
+const (
+	DefaultMaxBatchSize = 100
+	MaxMaxBatchSize     = 1000
+)
+
 // Cache configuration
 const (
 	DefaultCacheSize = 1000
@@ -27,19 +36,52 @@ const (
 
 // Time-to-live configuration
 const (
-	MinTTL     = 1
-	DefaultTTL = 5184000
+	MinTTL     = 1 * time.Second
+	DefaultTTL = 5184000 * time.Second
 	MaxTTL     = 31536000 * time.Second
 )
 
+// Key generation configuration
+const (
+	DefaultKeyLength   = 16
+	MinKeyLength       = 8
+	MaxKeyLength       = 64
+	MaxCustomKeyLength = 128
+	DefaultKeyStrategy = "base62"
+	DefaultKeyWords    = 6
+	MinKeyWords        = 4
+	MaxKeyWords        = 16
+)
+
+// CustomKeyPattern matches client-chosen keys: one or more
+// slash-separated segments, each starting with a letter or digit.
+var CustomKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)*$`)
+
 // Security configuration
 const (
 	MinEncryptionKeyLength = 16
+	MinAPIKeyLength        = 16
 	Base62Characters       = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 )
 
+// Namespace configuration
+const (
+	DefaultNamespace = "default"
+)
+
+var NamespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
 // Application metadata
 const (
-	RequestIdKey = "request_id"
-	AppVersion   = "1.0.0"
+	RequestIdKey   = "request_id"
+	ClientIPKey    = "client_ip"
+	NamespaceKey   = "namespace"
+	AuditEventKey  = "audit_event"
+	ErrorFormatKey = "error_format"
+	AppVersion     = "1.0.0"
+)
+
+// Tracing configuration
+const (
+	DefaultServiceName = "kvtxt"
 )
@@ -0,0 +1,207 @@
+// Package contenttype normalizes media types, matches them against
+// the configured policy and checks that values of well-known types
+// are well-formed:
+// - JSON: application/json and +json types
+// - YAML: application/yaml, text/yaml, their x- forms and +yaml types
+// - XML: application/xml, text/xml and +xml types
+// - text: any other text/* type must be UTF-8
+
+package contenttype
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalid is returned for content types that cannot be parsed.
+	ErrInvalid = errors.New("invalid content type")
+
+	// ErrMalformed is returned for values that are not well-formed
+	// for their content type.
+	ErrMalformed = errors.New("malformed")
+)
+
+// Kind is the family of formats a media type belongs to.
+type Kind int
+
+const (
+	Other Kind = iota
+	Text
+	JSON
+	YAML
+	XML
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Text:
+		return "text"
+	case JSON:
+		return "JSON"
+	case YAML:
+		return "YAML"
+	case XML:
+		return "XML"
+	}
+	return "other"
+}
+
+// Normalize parses a Content-Type value. It returns the value in
+// canonical form, with lower-case type and parameter names, and the
+// bare media type.
+func Normalize(contentType string) (string, string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	if !strings.Contains(mediaType, "/") {
+		return "", "", fmt.Errorf("%w: %q has no subtype", ErrInvalid, mediaType)
+	}
+
+	normalized := mime.FormatMediaType(mediaType, params)
+	if normalized == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalid, contentType)
+	}
+
+	return normalized, mediaType, nil
+}
+
+// MediaType returns the bare media type of a Content-Type value, or
+// "" if it cannot be parsed.
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// KindOf classifies a bare media type.
+func KindOf(mediaType string) Kind {
+	switch mediaType {
+	case "application/json":
+		return JSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return YAML
+	case "application/xml", "text/xml":
+		return XML
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSON
+	case strings.HasSuffix(mediaType, "+yaml"):
+		return YAML
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XML
+	case strings.HasPrefix(mediaType, "text/"):
+		return Text
+	}
+
+	return Other
+}
+
+// Validate checks that data is well-formed for a bare media type.
+// Values of other kinds are not checked.
+func Validate(mediaType string, data []byte) error {
+	kind := KindOf(mediaType)
+
+	switch kind {
+	case Other:
+		return nil
+
+	case JSON:
+		if !json.Valid(data) {
+			return fmt.Errorf("%w JSON", ErrMalformed)
+		}
+		return nil
+	}
+
+	if !utf8.Valid(data) {
+		return fmt.Errorf("%w %s: invalid UTF-8", ErrMalformed, kind)
+	}
+
+	var err error
+	switch kind {
+	case YAML:
+		err = validateYAML(data)
+	case XML:
+		err = validateXML(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%w %s: %s", ErrMalformed, kind, err)
+	}
+
+	return nil
+}
+
+// validateYAML parses every document of a YAML stream.
+func validateYAML(data []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// validateXML checks that data is a single, properly nested root
+// element, optionally preceded by a declaration, comments and
+// processing instructions.
+func validateXML(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	depth, roots := 0, 0
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+				if roots > 1 {
+					return errors.New("more than one root element")
+				}
+			}
+			depth++
+
+		case xml.EndElement:
+			depth--
+
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(tok)) > 0 {
+				return errors.New("text outside the root element")
+			}
+		}
+	}
+
+	if roots == 0 {
+		return errors.New("no root element")
+	}
+
+	return nil
+}
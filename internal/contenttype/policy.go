@@ -0,0 +1,192 @@
+// A Policy restricts the content types entries may have and sets
+// limits per type. Types are matched by patterns:
+// - "application/json" matches that type only
+// - "application/*+json" matches any application type with the suffix
+// - "text/*" matches any text type
+// - "*/*" matches every type
+//
+// Where several limit patterns match, the most specific one applies.
+
+package contenttype
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limit bounds entries of a content type. Zero means no bound
+// beyond the namespace limits.
+type Limit struct {
+	MaxSize int64
+	MaxTTL  time.Duration
+}
+
+// Policy is an allowlist, a denylist and per-type limits. The zero
+// value, like a nil Policy, allows every type without limits.
+type Policy struct {
+	allow  []pattern
+	deny   []pattern
+	limits []limitRule
+}
+
+type limitRule struct {
+	pattern pattern
+	limit   Limit
+}
+
+// NewPolicy builds a policy. An empty allowlist allows every type
+// that is not denied; a denied type is refused even if allowed.
+func NewPolicy(allow, deny []string, limits map[string]Limit) (*Policy, error) {
+	p := &Policy{}
+
+	for _, list := range []struct {
+		src []string
+		dst *[]pattern
+	}{
+		{allow, &p.allow},
+		{deny, &p.deny},
+	} {
+		for _, s := range list.src {
+			pat, err := parsePattern(s)
+			if err != nil {
+				return nil, err
+			}
+			*list.dst = append(*list.dst, pat)
+		}
+	}
+
+	for s, limit := range limits {
+		pat, err := parsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		p.limits = append(p.limits, limitRule{pattern: pat, limit: limit})
+	}
+
+	sort.Slice(p.limits, func(i, j int) bool {
+		a, b := p.limits[i].pattern, p.limits[j].pattern
+		if a.specificity() != b.specificity() {
+			return a.specificity() > b.specificity()
+		}
+		return a.String() < b.String()
+	})
+
+	return p, nil
+}
+
+// Allowed reports whether a bare media type may be stored.
+func (p *Policy) Allowed(mediaType string) bool {
+	if p == nil {
+		return true
+	}
+
+	for _, pat := range p.deny {
+		if pat.match(mediaType) {
+			return false
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return true
+	}
+
+	for _, pat := range p.allow {
+		if pat.match(mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Limit returns the limit of the most specific pattern matching a
+// bare media type.
+func (p *Policy) Limit(mediaType string) Limit {
+	if p == nil {
+		return Limit{}
+	}
+
+	for _, rule := range p.limits {
+		if rule.pattern.match(mediaType) {
+			return rule.limit
+		}
+	}
+
+	return Limit{}
+}
+
+// pattern is a parsed media type pattern. subtype is "*" for any
+// subtype; suffix is set for "*+suffix".
+type pattern struct {
+	typ     string
+	subtype string
+	suffix  string
+}
+
+// parsePattern parses a media type pattern such as "text/*".
+func parsePattern(s string) (pattern, error) {
+	typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "/")
+	if !ok || typ == "" || subtype == "" {
+		return pattern{}, fmt.Errorf("%w: pattern %q must be type/subtype", ErrInvalid, s)
+	}
+
+	if typ == "*" && subtype != "*" {
+		return pattern{}, fmt.Errorf("%w: pattern %q: a wildcard type needs a wildcard subtype", ErrInvalid, s)
+	}
+
+	pat := pattern{typ: typ, subtype: subtype}
+
+	if suffix, ok := strings.CutPrefix(subtype, "*+"); ok {
+		if suffix == "" {
+			return pattern{}, fmt.Errorf("%w: pattern %q has an empty suffix", ErrInvalid, s)
+		}
+		pat.subtype, pat.suffix = "*", suffix
+	}
+
+	for _, part := range []string{pat.typ, pat.subtype, pat.suffix} {
+		if strings.ContainsAny(part, " \t;,/") || (part != "*" && strings.Contains(part, "*")) {
+			return pattern{}, fmt.Errorf("%w: pattern %q", ErrInvalid, s)
+		}
+	}
+
+	return pat, nil
+}
+
+func (p pattern) match(mediaType string) bool {
+	typ, subtype, ok := strings.Cut(mediaType, "/")
+	if !ok {
+		return false
+	}
+
+	switch {
+	case p.typ != "*" && p.typ != typ:
+		return false
+	case p.suffix != "":
+		return strings.HasSuffix(subtype, "+"+p.suffix)
+	case p.subtype != "*":
+		return p.subtype == subtype
+	}
+
+	return true
+}
+
+func (p pattern) specificity() int {
+	switch {
+	case p.typ == "*":
+		return 0
+	case p.suffix != "":
+		return 2
+	case p.subtype == "*":
+		return 1
+	}
+	return 3
+}
+
+func (p pattern) String() string {
+	if p.suffix != "" {
+		return p.typ + "/*+" + p.suffix
+	}
+	return p.typ + "/" + p.subtype
+}
@@ -8,14 +8,38 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"io"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/metrics"
 )
 
+// namespaceInfo is the HKDF context prefix for namespace data keys.
+const namespaceInfo = "kvtxt namespace data key:"
+
 type Crypto struct {
 	aead cipher.AEAD
+	key  []byte
+	ad   []byte
+
+	// duration is shared with the namespace ciphers derived from
+	// this one.
+	duration *metrics.HistogramVec
+}
+
+// GenerateKey returns a new random master key, base64 encoded
+// in the form accepted by New.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
 }
 
 func New(keyB64 string) (*Crypto, error) {
@@ -28,6 +52,10 @@ func New(keyB64 string) (*Crypto, error) {
 		return nil, errors.New("encryption key must be 32 bytes (AES-256)")
 	}
 
+	return newCrypto(key, nil, metrics.NewCryptoDuration())
+}
+
+func newCrypto(key, ad []byte, duration *metrics.HistogramVec) (*Crypto, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -38,17 +66,32 @@ func New(keyB64 string) (*Crypto, error) {
 		return nil, err
 	}
 
-	return &Crypto{aead: aead}, nil
+	return &Crypto{aead: aead, key: key, ad: ad, duration: duration}, nil
+}
+
+// ForNamespace returns a Crypto bound to the given namespace.
+// The data key is derived from the master key with HKDF-SHA256 and the
+// namespace name is authenticated as additional data, so ciphertext of
+// one namespace cannot be opened in the context of another.
+func (c *Crypto) ForNamespace(name string) (*Crypto, error) {
+	key, err := hkdf.Key(sha256.New, c.key, nil, namespaceInfo+name, len(c.key))
+	if err != nil {
+		return nil, err
+	}
+
+	return newCrypto(key, []byte(name), c.duration)
 }
 
 // Encrypt secures plaintext value before persistence.
 func (c *Crypto) Encrypt(plaintext []byte) ([]byte, error) {
+	defer c.observe("encrypt", time.Now())
+
 	nonce := make([]byte, c.aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
 
-	ciphertext := c.aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext := c.aead.Seal(nil, nonce, plaintext, c.ad)
 
 	// nonce || ciphertext
 	return append(nonce, ciphertext...), nil
@@ -56,6 +99,8 @@ func (c *Crypto) Encrypt(plaintext []byte) ([]byte, error) {
 
 // Decrypt restores original value before returning to client.
 func (c *Crypto) Decrypt(data []byte) ([]byte, error) {
+	defer c.observe("decrypt", time.Now())
+
 	nonceSize := c.aead.NonceSize()
 	if len(data) < nonceSize {
 		return nil, errors.New("ciphertext too short")
@@ -64,5 +109,21 @@ func (c *Crypto) Decrypt(data []byte) ([]byte, error) {
 	nonce := data[:nonceSize]
 	ciphertext := data[nonceSize:]
 
-	return c.aead.Open(nil, nonce, ciphertext, nil)
+	return c.aead.Open(nil, nonce, ciphertext, c.ad)
+}
+
+// PlaintextSize returns the plaintext length of a value produced by
+// Encrypt, without decrypting it.
+func (c *Crypto) PlaintextSize(ciphertextSize int) int {
+	return max(ciphertextSize-c.aead.NonceSize()-c.aead.Overhead(), 0)
+}
+
+// Metrics returns the encryption latency histogram of this cipher
+// and the namespace ciphers derived from it.
+func (c *Crypto) Metrics() *metrics.HistogramVec {
+	return c.duration
+}
+
+func (c *Crypto) observe(op string, start time.Time) {
+	c.duration.Observe(time.Since(start).Seconds(), op)
 }
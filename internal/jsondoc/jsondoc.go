@@ -0,0 +1,98 @@
+// Package jsondoc selects fragments of JSON documents without
+// decoding them fully. Fragments are returned as the raw bytes of
+// the source document, so numbers and formatting are preserved.
+//
+// Two syntaxes are supported: RFC 6901 JSON Pointer, which selects a
+// single value, and a subset of RFC 9535 JSONPath, which selects a
+// list of values.
+
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPath is returned for a malformed pointer or path.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrNotFound is returned when a pointer does not resolve.
+	ErrNotFound = errors.New("no value at path")
+)
+
+type member struct {
+	name  string
+	value json.RawMessage
+}
+
+// members returns the members of a JSON object in document order,
+// or false if raw is not an object.
+func members(raw json.RawMessage) ([]member, bool, error) {
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil, false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, false, err
+	}
+
+	var list []member
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false, err
+		}
+
+		name, ok := tok.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("unexpected object key %v", tok)
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false, err
+		}
+
+		list = append(list, member{name: name, value: value})
+	}
+
+	return list, true, nil
+}
+
+// elements returns the elements of a JSON array, or false if raw is
+// not an array.
+func elements(raw json.RawMessage) ([]json.RawMessage, bool, error) {
+	if len(raw) == 0 || raw[0] != '[' {
+		return nil, false, nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, false, err
+	}
+	return list, true, nil
+}
+
+// lookup returns the value of an object member. For duplicate
+// names the last one wins, as with encoding/json.
+func lookup(raw json.RawMessage, name string) (json.RawMessage, bool, error) {
+	list, ok, err := members(raw)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	var (
+		value json.RawMessage
+		found bool
+	)
+	for _, m := range list {
+		if m.name == name {
+			value, found = m.value, true
+		}
+	}
+	return value, found, nil
+}
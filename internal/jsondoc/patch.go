@@ -0,0 +1,314 @@
+// Patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch
+// documents.
+
+package jsondoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPatch is returned for a malformed patch document.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrPatchFailed is returned when a well-formed JSON Patch cannot
+	// be applied, e.g. because a path does not exist or a test fails.
+	ErrPatchFailed = errors.New("patch failed")
+)
+
+// MergePatch applies a JSON Merge Patch to doc: members of patch
+// objects replace those of doc, and null removes them.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	return encode(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(*object)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(*object)
+	if !ok {
+		t = newObject()
+	}
+
+	for _, k := range p.keys {
+		v := p.values[k]
+		if v == nil {
+			t.remove(k)
+			continue
+		}
+
+		current, _ := t.get(k)
+		t.set(k, mergePatch(current, v))
+	}
+
+	return t
+}
+
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies a JSON Patch, a list of operations, to doc.
+// Operations apply in order and all or none take effect.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: expected an array of operations", ErrInvalidPatch)
+	}
+
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return encode(target)
+}
+
+func (op operation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidPatch)
+	}
+
+	path, err := ParsePointer(*op.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: value at %s differs", ErrPatchFailed, *op.Path)
+		}
+		return doc, nil
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: from is required", ErrInvalidPatch)
+		}
+
+		from, err := ParsePointer(*op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+		}
+
+		if op.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, clone(value))
+		}
+
+		if len(path) > len(from) && isPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrPatchFailed)
+		}
+
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+func (op operation) value() (any, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidPatch)
+	}
+	return decode(op.Value)
+}
+
+func isPrefix(prefix, p Pointer) bool {
+	for i := range prefix {
+		if prefix[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func get(doc any, p Pointer) (any, error) {
+	cur := doc
+	for _, tok := range p {
+		switch node := cur.(type) {
+		case *object:
+			v, ok := node.get(tok)
+			if !ok {
+				return nil, notFound(p)
+			}
+			cur = v
+
+		case []any:
+			i, err := arrayIndex(tok, len(node)-1)
+			if err != nil {
+				return nil, notFound(p)
+			}
+			cur = node[i]
+
+		default:
+			return nil, notFound(p)
+		}
+	}
+	return cur, nil
+}
+
+// update walks to the parent of the last token of p, lets fn change
+// it, and returns the document with the changed parent in place.
+func update(doc any, p Pointer, fn func(parent any, tok string) (any, error)) (any, error) {
+	if len(p) == 1 {
+		return fn(doc, p[0])
+	}
+
+	child, err := get(doc, p[:1])
+	if err != nil {
+		return nil, notFound(p)
+	}
+
+	child, err = update(child, p[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case *object:
+		node.set(p[0], child)
+	case []any:
+		i, _ := arrayIndex(p[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func add(doc any, p Pointer, value any) (any, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+
+	return update(doc, p, func(parent any, tok string) (any, error) {
+		switch node := parent.(type) {
+		case *object:
+			node.set(tok, value)
+			return node, nil
+
+		case []any:
+			if tok == "-" {
+				return append(node, value), nil
+			}
+
+			i, err := arrayIndex(tok, len(node))
+			if err != nil {
+				return nil, notFound(p)
+			}
+
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+
+		return nil, notFound(p)
+	})
+}
+
+func replace(doc any, p Pointer, value any) (any, error) {
+	if _, err := get(doc, p); err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return value, nil
+	}
+
+	return update(doc, p, func(parent any, tok string) (any, error) {
+		switch node := parent.(type) {
+		case *object:
+			node.set(tok, value)
+			return node, nil
+
+		case []any:
+			i, _ := arrayIndex(tok, len(node)-1)
+			node[i] = value
+			return node, nil
+		}
+
+		return nil, notFound(p)
+	})
+}
+
+// remove deletes the value at p and returns it.
+func remove(doc any, p Pointer) (any, any, error) {
+	removed, err := get(doc, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(p) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrPatchFailed)
+	}
+
+	doc, err = update(doc, p, func(parent any, tok string) (any, error) {
+		switch node := parent.(type) {
+		case *object:
+			node.remove(tok)
+			return node, nil
+
+		case []any:
+			i, _ := arrayIndex(tok, len(node)-1)
+			return append(node[:i], node[i+1:]...), nil
+		}
+
+		return nil, notFound(p)
+	})
+
+	return doc, removed, err
+}
+
+func notFound(p Pointer) error {
+	return fmt.Errorf("%w: no value at %s", ErrPatchFailed, p)
+}
@@ -0,0 +1,173 @@
+package jsondoc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		// RFC 7396, Appendix A
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+
+		// Nulls remove members at any depth, and only there
+		{`{"a":{"b":{"c":1,"d":2}},"e":3}`, `{"a":{"b":{"c":null}}}`, `{"a":{"b":{"d":2}},"e":3}`},
+		{`{"a":{"b":1}}`, `{"a":{"b":null,"c":{"d":null,"e":1}}}`, `{"a":{"c":{"e":1}}}`},
+		{`{"a":1}`, `{"a":{"b":null}}`, `{"a":{}}`},
+		{`{"a":1}`, `{"b":[null,{"c":null}]}`, `{"a":1,"b":[null,{"c":null}]}`},
+		{`{"a":1}`, `{"missing":null}`, `{"a":1}`},
+
+		// Member order and numbers are kept as written
+		{`{"z":1.50,"a":1e2,"m":{"y":1,"x":2}}`, `{"a":3,"m":{"x":0}}`, `{"z":1.50,"a":3,"m":{"y":1,"x":0}}`},
+		{`{"html":"<b>"}`, `{"amp":"&"}`, `{"html":"<b>","amp":"&"}`},
+	}
+
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("MergePatch(%s, %s) = %s, %v; want %s", tt.doc, tt.patch, got, err, tt.want)
+		}
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{"a":1}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("invalid patch: error = %v, want ErrInvalidPatch", err)
+	}
+	if _, err := MergePatch([]byte(`{"a":`), []byte(`{}`)); err == nil || errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("invalid document: error = %v", err)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		// RFC 6902, Appendix A
+		{"A.1", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{"A.2", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"A.3", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"A.4", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"A.5", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"A.6", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"A.7", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"A.8", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"A.10", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"A.11", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{"A.14", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"A.16", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+
+		// add appends with "-" and inserts at an index up to the length
+		{"add -", `[1,2]`, `[{"op":"add","path":"/-","value":3}]`, `[1,2,3]`},
+		{"add - empty", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1}]`, `{"a":[1]}`},
+		{"add 0", `[1,2]`, `[{"op":"add","path":"/0","value":0}]`, `[0,1,2]`},
+		{"add at length", `[1,2]`, `[{"op":"add","path":"/2","value":3}]`, `[1,2,3]`},
+		{"add replaces member", `{"a":1,"b":2}`, `[{"op":"add","path":"/a","value":3}]`, `{"a":3,"b":2}`},
+		{"add root", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"add - member", `{"a":1}`, `[{"op":"add","path":"/-","value":2}]`, `{"a":1,"-":2}`},
+
+		// move and copy
+		{"move to itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":{"b":1}}`},
+		{"move to sibling prefix", `{"a":1}`, `[{"op":"move","from":"/a","path":"/ab"}]`, `{"ab":1}`},
+		{"move up", `{"a":{"b":{"c":1}}}`, `[{"op":"move","from":"/a/b","path":"/b"}]`, `{"a":{},"b":{"c":1}}`},
+		{"copy is deep", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"copy into array", `{"a":[1,2]}`, `[{"op":"copy","from":"/a/1","path":"/a/0"}]`, `{"a":[2,1,2]}`},
+
+		// Operations see the effect of earlier ones
+		{"sequence", `{}`, `[{"op":"add","path":"/a","value":[]},{"op":"add","path":"/a/-","value":1},{"op":"test","path":"/a/0","value":1},{"op":"replace","path":"/a/0","value":2}]`, `{"a":[2]}`},
+
+		// Numbers, order and HTML characters are kept as written
+		{"keeps bytes", `{"z":1.50,"h":"<&>","a":1e2}`, `[{"op":"remove","path":"/h"}]`, `{"z":1.50,"a":1e2}`},
+		{"empty patch", `{"a":1.0}`, `[]`, `{"a":1.0}`},
+	}
+
+	for _, tt := range tests {
+		got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: JSONPatch = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             error
+	}{
+		// RFC 6902, Appendix A
+		{"A.9", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrPatchFailed},
+		{"A.12", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrPatchFailed},
+		// Of duplicate members the last wins, making this a remove
+		{"A.13", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`, ErrPatchFailed},
+		{"A.15", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, ErrPatchFailed},
+
+		{"add past the end", `[1,2]`, `[{"op":"add","path":"/3","value":3}]`, ErrPatchFailed},
+		{"add leading zero", `[1,2]`, `[{"op":"add","path":"/01","value":3}]`, ErrPatchFailed},
+		{"add negative", `[1,2]`, `[{"op":"add","path":"/-1","value":3}]`, ErrPatchFailed},
+		{"add into scalar", `{"a":1}`, `[{"op":"add","path":"/a/b","value":3}]`, ErrPatchFailed},
+		{"remove missing", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ErrPatchFailed},
+		{"remove -", `[1]`, `[{"op":"remove","path":"/-"}]`, ErrPatchFailed},
+		{"remove root", `{"a":1}`, `[{"op":"remove","path":""}]`, ErrPatchFailed},
+		{"replace missing", `{"a":1}`, `[{"op":"replace","path":"/b","value":1}]`, ErrPatchFailed},
+		{"replace past the end", `[1]`, `[{"op":"replace","path":"/1","value":1}]`, ErrPatchFailed},
+		{"move into descendant", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ErrPatchFailed},
+		{"move into child", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ErrPatchFailed},
+		{"move missing", `{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`, ErrPatchFailed},
+		{"copy missing", `{"a":1}`, `[{"op":"copy","from":"/b","path":"/c"}]`, ErrPatchFailed},
+		{"test missing", `{"a":1}`, `[{"op":"test","path":"/b","value":1}]`, ErrPatchFailed},
+
+		{"not an array", `{}`, `{"op":"add","path":"/a","value":1}`, ErrInvalidPatch},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, ErrInvalidPatch},
+		{"missing op", `{}`, `[{"path":"/a","value":1}]`, ErrInvalidPatch},
+		{"missing path", `{}`, `[{"op":"add","value":1}]`, ErrInvalidPatch},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ErrInvalidPatch},
+		{"null is a value", `{}`, `[{"op":"test","path":"/a","value":null}]`, ErrPatchFailed},
+		{"missing from", `{"a":1}`, `[{"op":"move","path":"/b"}]`, ErrInvalidPatch},
+		{"bad pointer", `{}`, `[{"op":"add","path":"a","value":1}]`, ErrInvalidPatch},
+		{"bad escape", `{}`, `[{"op":"add","path":"/~2","value":1}]`, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: JSONPatch = %s, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// TestJSONPatchAllOrNothing checks that a failing operation leaves
+// the document as it was, whatever the operations before it did.
+func TestJSONPatchAllOrNothing(t *testing.T) {
+	doc := []byte(`{"a":[1,2],"b":{"c":1}}`)
+	orig := string(doc)
+
+	for _, patch := range []string{
+		`[{"op":"add","path":"/x","value":1},{"op":"remove","path":"/missing"}]`,
+		`[{"op":"remove","path":"/a/0"},{"op":"add","path":"/a/-","value":3},{"op":"test","path":"/a/0","value":1}]`,
+		`[{"op":"replace","path":"/b/c","value":2},{"op":"move","from":"/b","path":"/b/d"}]`,
+		`[{"op":"copy","from":"/b","path":"/d"},{"op":"merge"}]`,
+	} {
+		if got, err := JSONPatch(doc, []byte(patch)); err == nil {
+			t.Errorf("JSONPatch(%s) = %s, want an error", patch, got)
+		}
+		if string(doc) != orig {
+			t.Fatalf("JSONPatch(%s) changed its input to %s", patch, doc)
+		}
+	}
+}
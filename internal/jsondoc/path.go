@@ -0,0 +1,218 @@
+// Path implements a subset of RFC 9535 JSONPath: the root "$"
+// followed by child selectors.
+//
+//	$.db.password      member names
+//	$['db']["host"]    quoted member names
+//	$.servers[0]       array indexes, negative ones from the end
+//	$.servers[*].host  wildcards over members or elements
+//
+// Filters, slices, unions and recursive descent are not supported.
+
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectIndex
+	selectWildcard
+)
+
+type selector struct {
+	kind  selectorKind
+	name  string
+	index int
+}
+
+// Path is a parsed JSONPath expression.
+type Path []selector
+
+// ParsePath parses a JSONPath expression such as "$.servers[0].host".
+func ParsePath(s string) (Path, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: path must start with '$'", ErrInvalidPath)
+	}
+
+	p := Path{}
+	rest := s[1:]
+
+	for rest != "" {
+		var (
+			sel selector
+			err error
+		)
+
+		switch rest[0] {
+		case '.':
+			sel, rest, err = parseDotted(rest[1:])
+		case '[':
+			sel, rest, err = parseBracketed(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s at offset %d", ErrInvalidPath, err, len(s)-len(rest))
+		}
+
+		p = append(p, sel)
+	}
+
+	return p, nil
+}
+
+// parseDotted parses what follows '.': a member name or '*'.
+func parseDotted(s string) (selector, string, error) {
+	if strings.HasPrefix(s, "*") {
+		return selector{kind: selectWildcard}, s[1:], nil
+	}
+
+	if strings.HasPrefix(s, ".") {
+		return selector{}, s, fmt.Errorf("recursive descent is not supported")
+	}
+
+	n := 0
+	for n < len(s) && isNameChar(s[n], n == 0) {
+		n++
+	}
+	if n == 0 {
+		return selector{}, s, fmt.Errorf("member name expected")
+	}
+
+	return selector{kind: selectName, name: s[:n]}, s[n:], nil
+}
+
+// parseBracketed parses what follows '[' up to and including ']'.
+func parseBracketed(s string) (selector, string, error) {
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		return selector{}, s, fmt.Errorf("unterminated '['")
+	}
+
+	var sel selector
+
+	switch c := s[0]; {
+	case c == '*':
+		sel.kind = selectWildcard
+		s = s[1:]
+
+	case c == '\'' || c == '"':
+		var b strings.Builder
+		i := 1
+		for ; i < len(s) && s[i] != c; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return selector{}, s, fmt.Errorf("unterminated string")
+		}
+
+		sel.kind, sel.name = selectName, b.String()
+		s = s[i+1:]
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		n := 1
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+
+		index, err := strconv.Atoi(s[:n])
+		if err != nil {
+			return selector{}, s, fmt.Errorf("invalid index %q", s[:n])
+		}
+
+		sel.kind, sel.index = selectIndex, index
+		s = s[n:]
+
+	default:
+		return selector{}, s, fmt.Errorf("unsupported selector")
+	}
+
+	s = strings.TrimLeft(s, " ")
+	if !strings.HasPrefix(s, "]") {
+		return selector{}, s, fmt.Errorf("']' expected")
+	}
+
+	return sel, s[1:], nil
+}
+
+// isNameChar reports whether c may appear in a dotted member name.
+// Bytes of non-ASCII characters are accepted as they are.
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= 0x80:
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// Select returns every value p matches in doc, in document order.
+// No match is an empty list, not an error.
+func (p Path) Select(doc []byte) ([]json.RawMessage, error) {
+	nodes := []json.RawMessage{bytes.TrimSpace(doc)}
+
+	for _, sel := range p {
+		next := []json.RawMessage{}
+
+		for _, node := range nodes {
+			matched, err := sel.apply(node)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, matched...)
+		}
+
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+func (sel selector) apply(node json.RawMessage) ([]json.RawMessage, error) {
+	switch sel.kind {
+	case selectName:
+		value, ok, err := lookup(node, sel.name)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []json.RawMessage{value}, nil
+
+	case selectIndex:
+		list, ok, err := elements(node)
+		if err != nil || !ok {
+			return nil, err
+		}
+
+		i := sel.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil, nil
+		}
+		return []json.RawMessage{list[i]}, nil
+	}
+
+	if list, ok, err := elements(node); ok || err != nil {
+		return list, err
+	}
+
+	list, _, err := members(node)
+	values := make([]json.RawMessage, len(list))
+	for i, m := range list {
+		values[i] = m.value
+	}
+	return values, err
+}
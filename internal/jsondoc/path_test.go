@@ -0,0 +1,114 @@
+package jsondoc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const pathDoc = `{
+	"db": {"host": "h", "port": 5432},
+	"servers": [{"host": "a"}, {"host": "b"}, {"host": "c"}],
+	"a.b": 1,
+	"it's": 2,
+	"q\"": 3,
+	"x y": 4,
+	"ünï": 5
+}`
+
+func TestPathSelect(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"$.db.host", []string{`"h"`}},
+		{`$['db']["port"]`, []string{"5432"}},
+		{"$.db.*", []string{`"h"`, "5432"}},
+		{"$.db[*]", []string{`"h"`, "5432"}},
+
+		// Indexes, negative ones counting from the end
+		{"$.servers[0].host", []string{`"a"`}},
+		{"$.servers[2].host", []string{`"c"`}},
+		{"$.servers[-1].host", []string{`"c"`}},
+		{"$.servers[-3].host", []string{`"a"`}},
+		{"$.servers[ -2 ].host", []string{`"b"`}},
+		{"$.servers[3]", []string{}},
+		{"$.servers[-4]", []string{}},
+		{"$.servers[*].host", []string{`"a"`, `"b"`, `"c"`}},
+		{"$.servers.*.host", []string{`"a"`, `"b"`, `"c"`}},
+
+		// Quoted names hold characters dotted names cannot
+		{"$['a.b']", []string{"1"}},
+		{`$['it\'s']`, []string{"2"}},
+		{`$["it's"]`, []string{"2"}},
+		{`$["q\""]`, []string{"3"}},
+		{`$['x y']`, []string{"4"}},
+		{"$.ünï", []string{"5"}},
+
+		// No match is an empty list
+		{"$.missing", []string{}},
+		{"$.db[0]", []string{}},
+		{"$.servers.host", []string{}},
+		{"$.db.host.*", []string{}},
+	}
+
+	for _, tt := range tests {
+		p, err := ParsePath(tt.path)
+		if err != nil {
+			t.Errorf("ParsePath(%q): %v", tt.path, err)
+			continue
+		}
+
+		nodes, err := p.Select([]byte(pathDoc))
+		if err != nil {
+			t.Errorf("Select(%q): %v", tt.path, err)
+			continue
+		}
+
+		got := make([]string, len(nodes))
+		for i, n := range nodes {
+			got[i] = string(n)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") || len(got) != len(tt.want) {
+			t.Errorf("Select(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPathSelectRoot(t *testing.T) {
+	p, err := ParsePath("$")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := p.Select([]byte("  [1, 2]\n"))
+	if err != nil || len(nodes) != 1 || string(nodes[0]) != "[1, 2]" {
+		t.Errorf("Select($) = %q, %v", nodes, err)
+	}
+}
+
+func TestParsePathInvalid(t *testing.T) {
+	for _, path := range []string{
+		"",
+		"db.host",
+		"$$",
+		"$.",
+		"$..db",
+		"$.1a",
+		"$.a-b",
+		"$[",
+		"$[1",
+		"$['a'",
+		"$['a]",
+		"$[-]",
+		"$[a]",
+		"$[1:2]",
+		"$[0,1]",
+		"$[?(@.a)]",
+		"$ .a",
+	} {
+		if _, err := ParsePath(path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("ParsePath(%q) error = %v, want ErrInvalidPath", path, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Pointer implements RFC 6901 JSON Pointer.
+
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pointer is a parsed JSON Pointer: the reference tokens, unescaped.
+// The empty pointer refers to the whole document.
+type Pointer []string
+
+// ParsePointer parses s, such as "/db/password" or "/servers/0".
+// Within a token, "~1" stands for '/' and "~0" for '~'.
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer must be empty or start with '/'", ErrInvalidPath)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: '~' must be followed by '0' or '1'", ErrInvalidPath)
+			}
+		}
+
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+
+	return Pointer(tokens), nil
+}
+
+// Get returns the value p refers to in doc.
+func (p Pointer) Get(doc []byte) (json.RawMessage, error) {
+	cur := json.RawMessage(bytes.TrimSpace(doc))
+
+	for _, tok := range p {
+		next, ok, err := child(cur, tok)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotFound
+		}
+		cur = next
+	}
+
+	return cur, nil
+}
+
+func child(raw json.RawMessage, tok string) (json.RawMessage, bool, error) {
+	if value, ok, err := lookup(raw, tok); ok || err != nil {
+		return value, ok, err
+	}
+
+	list, ok, err := elements(raw)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	i, err := arrayIndex(tok, len(list)-1)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	return list[i], true, nil
+}
+
+// arrayIndex parses an array index token no greater than last.
+// Indexes are digits without leading zeros; "-", which refers past
+// the end, is left to the caller.
+func arrayIndex(tok string, last int) (int, error) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') || strings.Trim(tok, "0123456789") != "" {
+		return 0, ErrNotFound
+	}
+
+	i, err := strconv.Atoi(tok)
+	if err != nil || i > last {
+		return 0, ErrNotFound
+	}
+	return i, nil
+}
+
+// String formats p as a JSON Pointer.
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, tok := range p {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(tok, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
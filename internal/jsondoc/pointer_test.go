@@ -0,0 +1,130 @@
+package jsondoc
+
+import (
+	"errors"
+	"testing"
+)
+
+// rfc6901Doc is the example document of RFC 6901, section 5.
+const rfc6901Doc = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func TestPointerGet(t *testing.T) {
+	tests := []struct {
+		doc, pointer, want string
+	}{
+		// RFC 6901, section 5
+		{rfc6901Doc, "", rfc6901Doc},
+		{rfc6901Doc, "/foo", `["bar", "baz"]`},
+		{rfc6901Doc, "/foo/0", `"bar"`},
+		{rfc6901Doc, "/", "0"},
+		{rfc6901Doc, "/a~1b", "1"},
+		{rfc6901Doc, "/c%d", "2"},
+		{rfc6901Doc, "/e^f", "3"},
+		{rfc6901Doc, "/g|h", "4"},
+		{rfc6901Doc, `/i\j`, "5"},
+		{rfc6901Doc, `/k"l`, "6"},
+		{rfc6901Doc, "/ ", "7"},
+		{rfc6901Doc, "/m~0n", "8"},
+
+		// "~01" is "~1", not "/"
+		{`{"~1": "tilde", "/": "slash"}`, "/~01", `"tilde"`},
+		{`{"~1": "tilde", "/": "slash"}`, "/~1", `"slash"`},
+
+		// Digits name members of objects, leading zeros included
+		{`{"0": "a", "01": "b", "-": "c"}`, "/01", `"b"`},
+		{`{"0": "a", "01": "b", "-": "c"}`, "/-", `"c"`},
+
+		{`{"a": {"b": [1, {"c": 1.50}]}}`, "/a/b/1/c", "1.50"},
+		{`{"a": 1, "a": 2}`, "/a", "2"},
+	}
+
+	for _, tt := range tests {
+		p, err := ParsePointer(tt.pointer)
+		if err != nil {
+			t.Errorf("ParsePointer(%q): %v", tt.pointer, err)
+			continue
+		}
+
+		got, err := p.Get([]byte(tt.doc))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Get(%q) = %s, %v; want %s", tt.pointer, got, err, tt.want)
+		}
+	}
+}
+
+func TestPointerNotFound(t *testing.T) {
+	for _, pointer := range []string{
+		"/missing",
+		"/foo/2",
+		"/foo/-",
+		"/foo/00",
+		"/foo/01",
+		"/foo/-1",
+		"/foo/+1",
+		"/foo/ 1",
+		"/foo/1e0",
+		"/foo/99999999999999999999",
+		"/foo/0/x",
+		"/a~1b/0",
+	} {
+		p, err := ParsePointer(pointer)
+		if err != nil {
+			t.Errorf("ParsePointer(%q): %v", pointer, err)
+			continue
+		}
+
+		if got, err := p.Get([]byte(rfc6901Doc)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %s, %v; want ErrNotFound", pointer, got, err)
+		}
+	}
+}
+
+func TestParsePointerInvalid(t *testing.T) {
+	for _, pointer := range []string{"foo", "#/foo", "/~", "/a~", "/~2", "/a~b", "/ok/~"} {
+		if _, err := ParsePointer(pointer); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("ParsePointer(%q) error = %v, want ErrInvalidPath", pointer, err)
+		}
+	}
+}
+
+func TestPointerString(t *testing.T) {
+	tests := []struct {
+		p    Pointer
+		want string
+	}{
+		{Pointer{}, ""},
+		{Pointer{""}, "/"},
+		{Pointer{"a/b", "m~n"}, "/a~1b/m~0n"},
+		{Pointer{"~1"}, "/~01"},
+		{Pointer{"servers", "0"}, "/servers/0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("%q.String() = %q, want %q", []string(tt.p), got, tt.want)
+		}
+
+		back, err := ParsePointer(tt.want)
+		if err != nil || len(back) != len(tt.p) {
+			t.Errorf("ParsePointer(%q) = %q, %v", tt.want, []string(back), err)
+			continue
+		}
+		for i := range back {
+			if back[i] != tt.p[i] {
+				t.Errorf("ParsePointer(%q) = %q, want %q", tt.want, []string(back), []string(tt.p))
+				break
+			}
+		}
+	}
+}
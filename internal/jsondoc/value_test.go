@@ -0,0 +1,112 @@
+package jsondoc
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		// Numbers compare by value, not as written
+		{`1`, `1`, true},
+		{`1`, `1.0`, true},
+		{`1`, `1e0`, true},
+		{`100`, `1E2`, true},
+		{`1`, `10e-1`, true},
+		{`0`, `-0`, true},
+		{`0.1`, `0.10`, true},
+		{`1`, `2`, false},
+		{`0.1`, `0.2`, false},
+		{`12345678901234567890`, `12345678901234567891`, false},
+		{`1`, `"1"`, false},
+		{`0`, `false`, false},
+		{`0`, `null`, false},
+
+		// Objects compare regardless of member order
+		{`{"a":1,"b":[2]}`, `{"b":[2.0],"a":1}`, true},
+		{`{"a":1}`, `{"a":1,"b":2}`, false},
+		{`{"a":null}`, `{}`, false},
+		{`{"a":null}`, `{"b":null}`, false},
+		{`{}`, `[]`, false},
+
+		// Arrays compare in order
+		{`[1,[2,{"x":3}]]`, `[1,[2,{"x":3}]]`, true},
+		{`[1,2]`, `[2,1]`, false},
+		{`[1]`, `[1,1]`, false},
+
+		{`"a"`, `"a"`, true},
+		{`"a"`, `"A"`, false},
+		{`true`, `true`, true},
+		{`true`, `false`, false},
+		{`null`, `null`, true},
+	}
+
+	for _, tt := range tests {
+		a, err := decode([]byte(tt.a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := decode([]byte(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := equal(a, b); got != tt.want {
+			t.Errorf("equal(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := equal(b, a); got != tt.want {
+			t.Errorf("equal(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeEncode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"b":1,"a":2}`, `{"b":1,"a":2}`},
+		{` { "n" : 1.50 , "e" : 1E+2 } `, `{"n":1.50,"e":1E+2}`},
+		{`{"a":1,"a":2}`, `{"a":2}`},
+		{`["<&>", "é", "\n"]`, `["<&>","é","\n"]`},
+		{`[]`, `[]`},
+		{`{}`, `{}`},
+		{`null`, `null`},
+		{`-0`, `-0`},
+	}
+
+	for _, tt := range tests {
+		v, err := decode([]byte(tt.in))
+		if err != nil {
+			t.Errorf("decode(%s): %v", tt.in, err)
+			continue
+		}
+
+		got, err := encode(v)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("encode(decode(%s)) = %s, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{``, `{`, `{"a":1}{}`, `[1,]`, `1 2`} {
+		if _, err := decode([]byte(in)); err == nil {
+			t.Errorf("decode(%q) succeeded", in)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	v, err := decode([]byte(`{"a":[1,{"b":2}],"c":{"d":3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := clone(v)
+	c.(*object).values["a"].([]any)[1].(*object).set("b", "changed")
+	c.(*object).values["c"].(*object).remove("d")
+	c.(*object).set("e", 4)
+
+	got, _ := encode(v)
+	if string(got) != `{"a":[1,{"b":2}],"c":{"d":3}}` {
+		t.Errorf("changing a clone changed the original to %s", got)
+	}
+}
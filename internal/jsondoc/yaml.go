@@ -0,0 +1,72 @@
+// YAML renders JSON documents as YAML, keeping member order and
+// numbers as written.
+
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML converts a JSON document to a YAML document.
+func ToYAML(doc []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(yamlNode(v)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func yamlNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case *object:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range v.keys {
+			n.Content = append(n.Content, scalar("!!str", k), yamlNode(v.values[k]))
+		}
+		return n
+
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range v {
+			n.Content = append(n.Content, yamlNode(e))
+		}
+		return n
+
+	case string:
+		return scalar("!!str", v)
+
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			return scalar("!!float", string(v))
+		}
+		return scalar("!!int", string(v))
+
+	case bool:
+		if v {
+			return scalar("!!bool", "true")
+		}
+		return scalar("!!bool", "false")
+	}
+
+	return scalar("!!null", "null")
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
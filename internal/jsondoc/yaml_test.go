@@ -0,0 +1,105 @@
+package jsondoc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestToYAML(t *testing.T) {
+	tests := []struct {
+		doc, want string
+	}{
+		{`{"b":1,"a":{"d":[1,2],"c":null}}`, "b: 1\na:\n  d:\n    - 1\n    - 2\n  c: null\n"},
+		{`{"n":1.50,"e":1e3,"i":-0,"big":12345678901234567890}`, "n: 1.50\ne: 1e3\ni: -0\nbig: 12345678901234567890\n"},
+		{`[]`, "[]\n"},
+		{`{}`, "{}\n"},
+		{`"text"`, "text\n"},
+		{`true`, "true\n"},
+		{`null`, "null\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := ToYAML([]byte(tt.doc))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("ToYAML(%s) = %q, %v; want %q", tt.doc, got, err, tt.want)
+		}
+	}
+
+	if _, err := ToYAML([]byte(`{"a":`)); err == nil {
+		t.Error("ToYAML accepted invalid JSON")
+	}
+}
+
+// TestToYAMLRoundTrip checks that the YAML reads back as the JSON
+// document it came from, with strings that look like other scalars
+// still tagged !!str.
+func TestToYAMLRoundTrip(t *testing.T) {
+	strs := []string{
+		"true", "false", "yes", "no", "on", "off", "y", "n", "True", "FALSE",
+		"1", "-1", "0x1F", "0o17", "1_000", "1.5", "1e3", ".5", ".inf", "-.Inf", ".nan",
+		"null", "Null", "~", "",
+		"2001-12-14", "12:30:00",
+		"- item", "key: value", "# comment", "&anchor", "*alias", "!tag", "|", ">", "%", "@", "`",
+		"{}", "[]", "'", `"`, " leading", "trailing ", "multi\nline\n", "tab\there", "é", "<&>",
+	}
+
+	for _, s := range strs {
+		quoted, _ := json.Marshal(s)
+		doc := []byte(`{"s":` + string(quoted) + `,"list":[` + string(quoted) + `]}`)
+
+		out, err := ToYAML(doc)
+		if err != nil {
+			t.Errorf("ToYAML(%s): %v", doc, err)
+			continue
+		}
+
+		var n yaml.Node
+		if err := yaml.Unmarshal(out, &n); err != nil {
+			t.Errorf("%q: YAML %q does not parse: %v", s, out, err)
+			continue
+		}
+
+		root := n.Content[0]
+		value, item := root.Content[1], root.Content[3].Content[0]
+		for _, node := range []*yaml.Node{value, item} {
+			if node.ShortTag() != "!!str" || node.Value != s {
+				t.Errorf("%q: read back as %s %q from %q", s, node.ShortTag(), node.Value, out)
+			}
+		}
+	}
+
+	docs := []string{
+		`{"a":1,"b":[true,false,null],"c":{"d":"x","e":[{"f":1.5}]},"g":-3}`,
+		`[1,"1",true,"true",null,"null",1.5,"1.5"]`,
+		`{"":"empty key","1":"numeric key","true":"bool key","null":null}`,
+		`{"nested":[[[]],[{}],{"x":[]}]}`,
+	}
+
+	for _, doc := range docs {
+		out, err := ToYAML([]byte(doc))
+		if err != nil {
+			t.Errorf("ToYAML(%s): %v", doc, err)
+			continue
+		}
+
+		var fromYAML any
+		if err := yaml.Unmarshal(out, &fromYAML); err != nil {
+			t.Errorf("YAML %q does not parse: %v", out, err)
+			continue
+		}
+
+		got, err := json.Marshal(fromYAML)
+		if err != nil {
+			t.Errorf("%s: %v", doc, err)
+			continue
+		}
+
+		a, _ := decode(got)
+		b, _ := decode([]byte(doc))
+		if !equal(a, b) {
+			t.Errorf("ToYAML(%s) = %q, reads back as %s", doc, out, got)
+		}
+	}
+}
@@ -0,0 +1,691 @@
+// Package jsonschema validates JSON documents against a subset of
+// JSON Schema (draft 2020-12), enough for typical configuration and
+// feature-flag documents.
+//
+// Supported keywords:
+// - any: type, enum, const, allOf, anyOf, oneOf, not, $ref (local "#/...")
+// - objects: properties, required, additionalProperties, minProperties, maxProperties
+// - arrays: items, minItems, maxItems, uniqueItems
+// - strings: minLength, maxLength, pattern (RE2 syntax)
+// - numbers: minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//
+// Other keywords, such as format or title, are ignored, as the
+// specification requires of unknown keywords.
+
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSchema is returned by Compile for unusable schemas.
+var ErrInvalidSchema = errors.New("invalid schema")
+
+const (
+	// maxViolations bounds the violations reported for one document.
+	maxViolations = 100
+
+	// maxDepth bounds nested checks, so that references which loop
+	// without descending into the document cannot recurse forever.
+	maxDepth = 256
+
+	// maxSteps bounds the checks made for one document. Within
+	// maxDepth, references through anyOf, oneOf or allOf can still
+	// branch exponentially.
+	maxSteps = 1_000_000
+)
+
+// Violation is a failed constraint at a location in the document.
+type Violation struct {
+	// Path is a JSON Pointer to the offending value, "" for the root.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Schema is a compiled schema, safe for concurrent use.
+type Schema struct {
+	root *node
+}
+
+type node struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	types    []string
+	enum     []any
+	constant *any
+
+	properties    map[string]*node
+	required      []string
+	additional    *node
+	minProperties *int
+	maxProperties *int
+
+	items       *node
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *big.Rat
+	maximum          *big.Rat
+	exclusiveMinimum *big.Rat
+	exclusiveMaximum *big.Rat
+	multipleOf       *big.Rat
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+	ref   *node
+}
+
+// Compile parses a schema document.
+func Compile(data []byte) (*Schema, error) {
+	doc, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSchema, err)
+	}
+
+	c := &compiler{root: doc, refs: make(map[string]*node)}
+
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSchema, err)
+	}
+
+	return &Schema{root: root}, nil
+}
+
+// Validate checks a JSON document and returns its violations,
+// sorted by path. A document that is not valid JSON is an error.
+func (s *Schema) Validate(data []byte) ([]Violation, error) {
+	doc, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{budget: &budget{steps: maxSteps}}
+	v.check(s.root, doc, "")
+
+	// Violations found before running out are incomplete
+	if v.budget.exhausted {
+		return []Violation{{Path: "", Message: "schema is too complex to validate this document"}}, nil
+	}
+
+	sort.SliceStable(v.violations, func(i, j int) bool {
+		return v.violations[i].Path < v.violations[j].Path
+	})
+
+	return v.violations, nil
+}
+
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+type compiler struct {
+	root any
+	refs map[string]*node
+}
+
+func (c *compiler) compile(v any, at string) (*node, error) {
+	if b, ok := v.(bool); ok {
+		return &node{always: &b}, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema at %q must be an object or boolean", at)
+	}
+
+	n := &node{}
+	var err error
+
+	if t, ok := m["type"]; ok {
+		if n.types, err = stringList(t); err != nil {
+			return nil, fmt.Errorf("%s/type: %w", at, err)
+		}
+		for _, name := range n.types {
+			switch name {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return nil, fmt.Errorf("%s/type: unknown type %q", at, name)
+			}
+		}
+	}
+
+	if e, ok := m["enum"]; ok {
+		list, ok := e.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s/enum: must be an array", at)
+		}
+		n.enum = list
+	}
+
+	if cv, ok := m["const"]; ok {
+		n.constant = &cv
+	}
+
+	if p, ok := m["properties"]; ok {
+		props, ok := p.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s/properties: must be an object", at)
+		}
+		n.properties = make(map[string]*node, len(props))
+		for name, sub := range props {
+			if n.properties[name], err = c.compile(sub, at+"/properties/"+escape(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r, ok := m["required"]; ok {
+		if n.required, err = stringList(r); err != nil {
+			return nil, fmt.Errorf("%s/required: %w", at, err)
+		}
+	}
+
+	subschemas := []struct {
+		keyword string
+		dst     **node
+	}{
+		{"additionalProperties", &n.additional},
+		{"items", &n.items},
+		{"not", &n.not},
+	}
+	for _, s := range subschemas {
+		if sub, ok := m[s.keyword]; ok {
+			if *s.dst, err = c.compile(sub, at+"/"+s.keyword); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lists := []struct {
+		keyword string
+		dst     *[]*node
+	}{
+		{"allOf", &n.allOf},
+		{"anyOf", &n.anyOf},
+		{"oneOf", &n.oneOf},
+	}
+	for _, l := range lists {
+		sub, ok := m[l.keyword]
+		if !ok {
+			continue
+		}
+		items, ok := sub.([]any)
+		if !ok || len(items) == 0 {
+			return nil, fmt.Errorf("%s/%s: must be a non-empty array", at, l.keyword)
+		}
+		for i, item := range items {
+			compiled, err := c.compile(item, fmt.Sprintf("%s/%s/%d", at, l.keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*l.dst = append(*l.dst, compiled)
+		}
+	}
+
+	counts := []struct {
+		keyword string
+		dst     **int
+	}{
+		{"minProperties", &n.minProperties},
+		{"maxProperties", &n.maxProperties},
+		{"minItems", &n.minItems},
+		{"maxItems", &n.maxItems},
+		{"minLength", &n.minLength},
+		{"maxLength", &n.maxLength},
+	}
+	for _, cnt := range counts {
+		if v, ok := m[cnt.keyword]; ok {
+			if *cnt.dst, err = count(v); err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", at, cnt.keyword, err)
+			}
+		}
+	}
+
+	bounds := []struct {
+		keyword string
+		dst     **big.Rat
+	}{
+		{"minimum", &n.minimum},
+		{"maximum", &n.maximum},
+		{"exclusiveMinimum", &n.exclusiveMinimum},
+		{"exclusiveMaximum", &n.exclusiveMaximum},
+		{"multipleOf", &n.multipleOf},
+	}
+	for _, b := range bounds {
+		if v, ok := m[b.keyword]; ok {
+			r, ok := number(v)
+			if !ok {
+				return nil, fmt.Errorf("%s/%s: must be a number", at, b.keyword)
+			}
+			*b.dst = r
+		}
+	}
+	if n.multipleOf != nil && n.multipleOf.Sign() <= 0 {
+		return nil, fmt.Errorf("%s/multipleOf: must be greater than 0", at)
+	}
+
+	if u, ok := m["uniqueItems"]; ok {
+		if n.uniqueItems, ok = u.(bool); !ok {
+			return nil, fmt.Errorf("%s/uniqueItems: must be a boolean", at)
+		}
+	}
+
+	if p, ok := m["pattern"]; ok {
+		s, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s/pattern: must be a string", at)
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("%s/pattern: %w", at, err)
+		}
+	}
+
+	if r, ok := m["$ref"]; ok {
+		ref, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s/$ref: must be a string", at)
+		}
+		if n.ref, err = c.resolve(ref); err != nil {
+			return nil, fmt.Errorf("%s/$ref: %w", at, err)
+		}
+	}
+
+	return n, nil
+}
+
+// resolve compiles the schema a local reference points to. The
+// node is registered before compiling, so recursive schemas work.
+func (c *compiler) resolve(ref string) (*node, error) {
+	if n, ok := c.refs[ref]; ok {
+		return n, nil
+	}
+
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("only local references such as \"#/$defs/name\" are supported")
+	}
+
+	target := c.root
+	if pointer != "" {
+		for _, tok := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+
+			m, ok := target.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%q does not resolve", ref)
+			}
+			if target, ok = m[tok]; !ok {
+				return nil, fmt.Errorf("%q does not resolve", ref)
+			}
+		}
+	}
+
+	n := &node{}
+	c.refs[ref] = n
+
+	compiled, err := c.compile(target, pointer)
+	if err != nil {
+		return nil, err
+	}
+	*n = *compiled
+
+	return n, nil
+}
+
+type validator struct {
+	violations []Violation
+	depth      int
+	budget     *budget
+}
+
+// budget counts the checks left for a document, shared by the
+// validator and its probes.
+type budget struct {
+	steps     int
+	exhausted bool
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	if len(v.violations) < maxViolations {
+		v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// matches reports whether value satisfies n, without recording
+// violations.
+func (v *validator) matches(n *node, value any) bool {
+	probe := &validator{depth: v.depth, budget: v.budget}
+	probe.check(n, value, "")
+	return len(probe.violations) == 0
+}
+
+func (v *validator) check(n *node, value any, path string) {
+	if v.budget.steps == 0 {
+		v.budget.exhausted = true
+		return
+	}
+	v.budget.steps--
+
+	if v.depth >= maxDepth {
+		v.fail(path, "schema nesting is too deep")
+		return
+	}
+	v.depth++
+	defer func() { v.depth-- }()
+
+	if n.always != nil {
+		if !*n.always {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	}
+
+	if n.ref != nil {
+		v.check(n.ref, value, path)
+	}
+
+	if len(n.types) > 0 && !hasType(n.types, value) {
+		v.fail(path, "expected %s, got %s", strings.Join(n.types, " or "), typeOf(value))
+		return
+	}
+
+	if n.enum != nil {
+		found := false
+		for _, e := range n.enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of the enumerated values")
+		}
+	}
+
+	if n.constant != nil && !equal(*n.constant, value) {
+		v.fail(path, "must equal the constant value")
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.checkObject(n, value, path)
+	case []any:
+		v.checkArray(n, value, path)
+	case string:
+		v.checkString(n, value, path)
+	case json.Number:
+		v.checkNumber(n, value, path)
+	}
+
+	for _, sub := range n.allOf {
+		v.check(sub, value, path)
+	}
+
+	if n.anyOf != nil {
+		matched := false
+		for _, sub := range n.anyOf {
+			if v.matches(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "must match at least one schema in anyOf")
+		}
+	}
+
+	if n.oneOf != nil {
+		matched := 0
+		for _, sub := range n.oneOf {
+			if v.matches(sub, value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(path, "must match exactly one schema in oneOf, matched %d", matched)
+		}
+	}
+
+	if n.not != nil && v.matches(n.not, value) {
+		v.fail(path, "must not match the schema in not")
+	}
+}
+
+func (v *validator) checkObject(n *node, obj map[string]any, path string) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path, "missing required property %q", name)
+		}
+	}
+
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		v.fail(path, "must have at least %d properties", *n.minProperties)
+	}
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		v.fail(path, "must have at most %d properties", *n.maxProperties)
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		at := path + "/" + escape(name)
+
+		if sub, ok := n.properties[name]; ok {
+			v.check(sub, obj[name], at)
+			continue
+		}
+
+		if n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				v.fail(at, "property is not allowed")
+				continue
+			}
+			v.check(n.additional, obj[name], at)
+		}
+	}
+}
+
+func (v *validator) checkArray(n *node, list []any, path string) {
+	if n.minItems != nil && len(list) < *n.minItems {
+		v.fail(path, "must have at least %d items", *n.minItems)
+	}
+	if n.maxItems != nil && len(list) > *n.maxItems {
+		v.fail(path, "must have at most %d items", *n.maxItems)
+	}
+
+	if n.uniqueItems {
+	outer:
+		for i := range list {
+			for j := 0; j < i; j++ {
+				if equal(list[i], list[j]) {
+					v.fail(path, "items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+
+	if n.items != nil {
+		for i, item := range list {
+			v.check(n.items, item, fmt.Sprintf("%s/%d", path, i))
+		}
+	}
+}
+
+func (v *validator) checkString(n *node, s, path string) {
+	length := utf8.RuneCountInString(s)
+
+	if n.minLength != nil && length < *n.minLength {
+		v.fail(path, "must be at least %d characters", *n.minLength)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		v.fail(path, "must be at most %d characters", *n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		v.fail(path, "must match pattern %q", n.pattern.String())
+	}
+}
+
+func (v *validator) checkNumber(n *node, num json.Number, path string) {
+	x, ok := number(num)
+	if !ok {
+		return
+	}
+
+	if n.minimum != nil && x.Cmp(n.minimum) < 0 {
+		v.fail(path, "must be at least %s", n.minimum.RatString())
+	}
+	if n.maximum != nil && x.Cmp(n.maximum) > 0 {
+		v.fail(path, "must be at most %s", n.maximum.RatString())
+	}
+	if n.exclusiveMinimum != nil && x.Cmp(n.exclusiveMinimum) <= 0 {
+		v.fail(path, "must be greater than %s", n.exclusiveMinimum.RatString())
+	}
+	if n.exclusiveMaximum != nil && x.Cmp(n.exclusiveMaximum) >= 0 {
+		v.fail(path, "must be less than %s", n.exclusiveMaximum.RatString())
+	}
+	if n.multipleOf != nil && !new(big.Rat).Quo(x, n.multipleOf).IsInt() {
+		v.fail(path, "must be a multiple of %s", n.multipleOf.RatString())
+	}
+}
+
+func hasType(types []string, value any) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if r, ok := number(value); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+// equal compares JSON values, numbers by value.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	case json.Number:
+		x, okA := number(a)
+		y, okB := number(b)
+		return okA && okB && x.Cmp(y) == 0
+	}
+
+	return a == b
+}
+
+func number(v any) (*big.Rat, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(n))
+}
+
+func count(v any) (*int, error) {
+	r, ok := number(v)
+	if !ok || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	n := int(r.Num().Int64())
+	return &n, nil
+}
+
+func stringList(v any) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return []string{s}, nil
+	}
+
+	items, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("must be a string or an array of strings")
+	}
+
+	list := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be a string or an array of strings")
+		}
+		list[i] = s
+	}
+	return list, nil
+}
+
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,286 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
+)
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		keyword, schema, doc string
+
+		// want lists the paths of the expected violations, in order
+		want []string
+	}{
+		{"true", `true`, `{"a":1}`, nil},
+		{"false", `false`, `1`, []string{""}},
+		{"empty", `{}`, `[null]`, nil},
+
+		{"type", `{"type":"string"}`, `"a"`, nil},
+		{"type", `{"type":"string"}`, `1`, []string{""}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"type list", `{"type":["string","null"]}`, `false`, []string{""}},
+		{"type integer", `{"type":"integer"}`, `1.0`, nil},
+		{"type integer", `{"type":"integer"}`, `1e2`, nil},
+		{"type integer", `{"type":"integer"}`, `1.5`, []string{""}},
+		{"type number", `{"type":"number"}`, `3`, nil},
+		{"type object", `{"type":"object"}`, `[]`, []string{""}},
+		{"type array", `{"type":"array"}`, `{}`, []string{""}},
+		{"type boolean", `{"type":"boolean"}`, `0`, []string{""}},
+
+		{"enum", `{"enum":["a",1,null,{"b":[2]}]}`, `{"b":[2.0]}`, nil},
+		{"enum", `{"enum":["a",1,null]}`, `1.0`, nil},
+		{"enum", `{"enum":["a",1,null]}`, `"1"`, []string{""}},
+		{"const", `{"const":{"a":1}}`, `{"a":1}`, nil},
+		{"const", `{"const":{"a":1}}`, `{"a":1,"b":2}`, []string{""}},
+		{"const null", `{"const":null}`, `false`, []string{""}},
+
+		{"properties", `{"properties":{"a":{"type":"string"},"b":{"properties":{"c":{"type":"integer"}}}}}`, `{"a":1,"b":{"c":"x"},"d":1}`, []string{"/a", "/b/c"}},
+		{"properties escaped", `{"properties":{"a/b":{"type":"string"},"m~n":{"type":"string"}}}`, `{"a/b":1,"m~n":2}`, []string{"/a~1b", "/m~0n"}},
+		{"required", `{"required":["a","b"]}`, `{"a":null}`, []string{""}},
+		{"required", `{"required":["a"]}`, `[]`, nil},
+		{"additionalProperties false", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2,"c":3}`, []string{"/b", "/c"}},
+		{"additionalProperties schema", `{"properties":{"a":{}},"additionalProperties":{"type":"integer"}}`, `{"a":"x","b":2,"c":"y"}`, []string{"/c"}},
+		{"minProperties", `{"minProperties":2}`, `{"a":1}`, []string{""}},
+		{"maxProperties", `{"maxProperties":1}`, `{"a":1,"b":2}`, []string{""}},
+
+		{"items", `{"items":{"type":"integer"}}`, `[1,"a",2,null]`, []string{"/1", "/3"}},
+		{"items nested", `{"items":{"items":{"minimum":0}}}`, `[[0],[1,-1]]`, []string{"/1/1"}},
+		{"minItems", `{"minItems":1}`, `[]`, []string{""}},
+		{"maxItems", `{"maxItems":1}`, `[1,2]`, []string{""}},
+		{"uniqueItems", `{"uniqueItems":true}`, `[1,{"a":1},{"a":1.0}]`, []string{""}},
+		{"uniqueItems", `{"uniqueItems":true}`, `[1,"1",[1]]`, nil},
+		{"uniqueItems false", `{"uniqueItems":false}`, `[1,1]`, nil},
+
+		{"minLength", `{"minLength":2}`, `"é"`, []string{""}},
+		{"minLength runes", `{"minLength":2}`, `"éé"`, nil},
+		{"maxLength runes", `{"maxLength":2}`, `"日本"`, nil},
+		{"maxLength", `{"maxLength":2}`, `"abc"`, []string{""}},
+		{"pattern", `{"pattern":"^[a-z]+$"}`, `"abc"`, nil},
+		{"pattern", `{"pattern":"^[a-z]+$"}`, `"ab1"`, []string{""}},
+		{"pattern unanchored", `{"pattern":"b"}`, `"abc"`, nil},
+
+		{"minimum", `{"minimum":1.5}`, `1.5`, nil},
+		{"minimum", `{"minimum":1.5}`, `1.4999999999999999999`, []string{""}},
+		{"maximum", `{"maximum":10}`, `1e1`, nil},
+		{"maximum", `{"maximum":10}`, `10.000000000000000001`, []string{""}},
+		{"exclusiveMinimum", `{"exclusiveMinimum":0}`, `0`, []string{""}},
+		{"exclusiveMinimum", `{"exclusiveMinimum":0}`, `1e-30`, nil},
+		{"exclusiveMaximum", `{"exclusiveMaximum":1}`, `1.0`, []string{""}},
+		{"multipleOf", `{"multipleOf":0.1}`, `0.3`, nil},
+		{"multipleOf", `{"multipleOf":0.1}`, `0.35`, []string{""}},
+		{"multipleOf", `{"multipleOf":3}`, `-9`, nil},
+		{"number keywords ignore strings", `{"minimum":5,"maxLength":0}`, `1`, []string{""}},
+		{"string keywords ignore numbers", `{"maxLength":0}`, `12`, nil},
+
+		{"allOf", `{"allOf":[{"minimum":1},{"multipleOf":2}]}`, `3`, []string{""}},
+		{"allOf", `{"allOf":[{"minimum":1},{"multipleOf":2}]}`, `-1`, []string{"", ""}},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"minimum":1}]}`, `2`, nil},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"minimum":1}]}`, `0`, []string{""}},
+		{"oneOf", `{"oneOf":[{"minimum":1},{"maximum":5}]}`, `9`, nil},
+		{"oneOf", `{"oneOf":[{"minimum":1},{"maximum":5}]}`, `3`, []string{""}},
+		{"oneOf none", `{"oneOf":[{"minimum":10},{"maximum":-10}]}`, `0`, []string{""}},
+		{"not", `{"not":{"type":"null"}}`, `null`, []string{""}},
+		{"not", `{"not":{"type":"null"}}`, `0`, nil},
+		{"nested combinator", `{"properties":{"a":{"anyOf":[{"type":"string"},{"type":"null"}]}}}`, `{"a":1}`, []string{"/a"}},
+
+		{"$ref", `{"$defs":{"port":{"type":"integer","maximum":65535}},"properties":{"p":{"$ref":"#/$defs/port"}}}`, `{"p":70000}`, []string{"/p"}},
+		{"$ref escaped", `{"$defs":{"a/b":{"type":"string"}},"$ref":"#/$defs/a~1b"}`, `1`, []string{""}},
+		{"$ref root", `{"properties":{"child":{"$ref":"#"}},"required":["id"]}`, `{"id":1,"child":{"id":2,"child":{}}}`, []string{"/child/child"}},
+
+		{"ignored keywords", `{"title":"t","format":"email","x-custom":1}`, `"not an email"`, nil},
+	}
+
+	for _, tt := range tests {
+		s, err := jsonschema.Compile([]byte(tt.schema))
+		if err != nil {
+			t.Errorf("%s: Compile(%s): %v", tt.keyword, tt.schema, err)
+			continue
+		}
+
+		violations, err := s.Validate([]byte(tt.doc))
+		if err != nil {
+			t.Errorf("%s: Validate(%s): %v", tt.keyword, tt.doc, err)
+			continue
+		}
+
+		got := make([]string, len(violations))
+		for i, v := range violations {
+			got[i] = v.Path
+			if v.Message == "" {
+				t.Errorf("%s: violation at %q has no message", tt.keyword, v.Path)
+			}
+		}
+
+		if len(got) != len(tt.want) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: %s against %s: violations at %q, want %q (%v)", tt.keyword, tt.doc, tt.schema, got, tt.want, violations)
+		}
+	}
+}
+
+func TestViolationMessages(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"port": {"type": "integer", "maximum": 65535},
+			"tags": {"items": {"enum": ["a", "b"]}}
+		},
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err := s.Validate([]byte(`{"port": 70000, "tags": ["a", "c"], "extra": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []jsonschema.Violation{
+		{Path: "", Message: `missing required property "name"`},
+		{Path: "/extra", Message: "property is not allowed"},
+		{Path: "/port", Message: "must be at most 65535"},
+		{Path: "/tags/1", Message: "must be one of the enumerated values"},
+	}
+
+	if len(violations) != len(want) {
+		t.Fatalf("violations = %v, want %v", violations, want)
+	}
+	for i := range want {
+		if violations[i] != want[i] {
+			t.Errorf("violation %d = %+v, want %+v", i, violations[i], want[i])
+		}
+	}
+}
+
+func TestViolationsBounded(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{"items": {"type": "string"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := "[" + strings.Repeat("1,", 500) + "1]"
+	violations, err := s.Validate([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 100 {
+		t.Errorf("%d violations reported, want 100", len(violations))
+	}
+}
+
+func TestRecursiveRefWithoutDescent(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err := s.Validate([]byte(`1`))
+	if err != nil || len(violations) == 0 {
+		t.Errorf("Validate = %v, %v; want a nesting violation", violations, err)
+	}
+}
+
+// TestRecursiveRefBranching checks that references which branch
+// without descending into the document are cut off, rather than
+// taking exponential time within the depth bound.
+func TestRecursiveRefBranching(t *testing.T) {
+	for _, keyword := range []string{"oneOf", "anyOf", "allOf"} {
+		schema := `{"$defs":{"d":{"` + keyword + `":[{"$ref":"#/$defs/d"},{"$ref":"#/$defs/d"}]}},"$ref":"#/$defs/d"}`
+
+		s, err := jsonschema.Compile([]byte(schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan []jsonschema.Violation, 1)
+		go func() {
+			violations, _ := s.Validate([]byte(`{"a":[1,2,3]}`))
+			done <- violations
+		}()
+
+		select {
+		case violations := <-done:
+			if len(violations) != 1 || violations[0].Path != "" {
+				t.Errorf("%s: violations = %+v, want one at the root", keyword, violations)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: Validate did not return", keyword)
+		}
+	}
+}
+
+// TestLargeDocumentWithinBudget checks that the step budget leaves
+// room for large documents.
+func TestLargeDocumentWithinBudget(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{"items":{"type":"object","properties":{"id":{"type":"integer","minimum":0}},"required":["id"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := "[" + strings.Repeat(`{"id":1},`, 50000) + `{"id":1}]`
+	violations, err := s.Validate([]byte(doc))
+	if err != nil || len(violations) != 0 {
+		t.Errorf("Validate = %v, %v", violations, err)
+	}
+}
+
+func TestValidateInvalidDocument(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, doc := range []string{``, `{`, `1 2`, `text`} {
+		if _, err := s.Validate([]byte(doc)); err == nil {
+			t.Errorf("Validate(%q) succeeded", doc)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, schema := range []string{
+		``,
+		`{`,
+		`1`,
+		`"string"`,
+		`null`,
+		`{} {}`,
+		`{"type":"float"}`,
+		`{"type":1}`,
+		`{"type":["string",1]}`,
+		`{"enum":"a"}`,
+		`{"properties":[]}`,
+		`{"properties":{"a":1}}`,
+		`{"required":[1]}`,
+		`{"additionalProperties":"no"}`,
+		`{"items":[]}`,
+		`{"not":null}`,
+		`{"allOf":[]}`,
+		`{"anyOf":{}}`,
+		`{"oneOf":[1]}`,
+		`{"minItems":-1}`,
+		`{"maxLength":1.5}`,
+		`{"minProperties":"1"}`,
+		`{"minimum":"0"}`,
+		`{"multipleOf":0}`,
+		`{"multipleOf":-1}`,
+		`{"uniqueItems":"yes"}`,
+		`{"pattern":"("}`,
+		`{"pattern":"(?=a)"}`,
+		`{"pattern":1}`,
+		`{"$ref":1}`,
+		`{"$ref":"https://example.com/schema"}`,
+		`{"$ref":"#/$defs/missing"}`,
+		`{"$defs":{"a":1},"$ref":"#/$defs/a"}`,
+	} {
+		if _, err := jsonschema.Compile([]byte(schema)); !errors.Is(err, jsonschema.ErrInvalidSchema) {
+			t.Errorf("Compile(%s) error = %v, want ErrInvalidSchema", schema, err)
+		}
+	}
+}
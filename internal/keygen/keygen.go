@@ -0,0 +1,236 @@
+// Package keygen generates entry keys.
+// A strategy decides the shape of keys; an optional check character
+// lets the API tell a mistyped key apart from an unknown one.
+
+package keygen
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+)
+
+// Strategy names accepted by New.
+const (
+	Base62   = "base62"
+	UUIDv7   = "uuidv7"
+	Diceware = "diceware"
+)
+
+//go:embed words.txt
+var wordList string
+
+var words = strings.Fields(wordList)
+
+// Generator produces new keys.
+type Generator interface {
+	Generate() (string, error)
+
+	// Mistyped reports whether key has the shape of a generated key
+	// but fails its check character. Without check characters it is
+	// always false.
+	Mistyped(key string) bool
+}
+
+// Options selects and tunes a strategy.
+type Options struct {
+	Strategy string
+
+	// Length is the number of base62 characters.
+	Length int
+
+	// Words is the number of diceware words.
+	Words int
+
+	// CheckChar appends a check character to every key.
+	CheckChar bool
+}
+
+type strategy interface {
+	generate() (string, error)
+
+	// matches reports whether key has the shape of a generated key,
+	// without regard to its content.
+	matches(key string) bool
+}
+
+func New(opts Options) (Generator, error) {
+	var (
+		s   strategy
+		sep string
+	)
+
+	switch opts.Strategy {
+	case Base62:
+		if opts.Length < constant.MinKeyLength || opts.Length > constant.MaxKeyLength {
+			return nil, fmt.Errorf("key length must be between %d and %d", constant.MinKeyLength, constant.MaxKeyLength)
+		}
+		s = base62{length: opts.Length}
+
+	case UUIDv7:
+		s = uuidV7{}
+		sep = "-"
+
+	case Diceware:
+		if opts.Words < constant.MinKeyWords || opts.Words > constant.MaxKeyWords {
+			return nil, fmt.Errorf("key words must be between %d and %d", constant.MinKeyWords, constant.MaxKeyWords)
+		}
+		s = diceware{words: opts.Words}
+		sep = "-"
+
+	default:
+		return nil, fmt.Errorf("unknown key strategy %q, expected %s, %s or %s", opts.Strategy, Base62, UUIDv7, Diceware)
+	}
+
+	if opts.CheckChar {
+		return checked{strategy: s, sep: sep}, nil
+	}
+	return plain{strategy: s}, nil
+}
+
+type plain struct {
+	strategy
+}
+
+func (p plain) Generate() (string, error) { return p.generate() }
+
+func (p plain) Mistyped(string) bool { return false }
+
+// checked appends a Luhn mod 62 check character, after sep.
+type checked struct {
+	strategy
+	sep string
+}
+
+func (c checked) Generate() (string, error) {
+	key, err := c.generate()
+	if err != nil {
+		return "", err
+	}
+	return key + c.sep + string(checkChar(key)), nil
+}
+
+func (c checked) Mistyped(key string) bool {
+	if len(key) < len(c.sep)+1 {
+		return false
+	}
+
+	body, check := key[:len(key)-1-len(c.sep)], key[len(key)-1]
+	if !strings.HasSuffix(key[:len(key)-1], c.sep) || !c.matches(body) {
+		return false
+	}
+
+	return checkChar(body) != check
+}
+
+// checkChar computes a Luhn mod N check character over the base62
+// characters of key; other characters, such as '-', are skipped.
+// It catches every single-character error and most transpositions.
+func checkChar(key string) byte {
+	const n = len(constant.Base62Characters)
+
+	factor, sum := 2, 0
+	for i := len(key) - 1; i >= 0; i-- {
+		code := strings.IndexByte(constant.Base62Characters, key[i])
+		if code < 0 {
+			continue
+		}
+
+		addend := factor * code
+		sum += addend/n + addend%n
+
+		factor = 3 - factor
+	}
+
+	return constant.Base62Characters[(n-sum%n)%n]
+}
+
+type base62 struct {
+	length int
+}
+
+func (b base62) generate() (string, error) {
+	result := make([]byte, b.length)
+	maxValue := big.NewInt(int64(len(constant.Base62Characters)))
+
+	for i := range result {
+		n, err := rand.Int(rand.Reader, maxValue)
+		if err != nil {
+			return "", err
+		}
+		result[i] = constant.Base62Characters[n.Int64()]
+	}
+
+	return string(result), nil
+}
+
+func (b base62) matches(key string) bool {
+	if len(key) != b.length {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(constant.Base62Characters, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// uuidV7 keys sort by creation time.
+type uuidV7 struct{}
+
+func (uuidV7) generate() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func (uuidV7) matches(key string) bool {
+	if len(key) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(key)
+	return err == nil
+}
+
+// diceware keys are lowercase words joined by '-', easy to read out.
+type diceware struct {
+	words int
+}
+
+func (d diceware) generate() (string, error) {
+	picked := make([]string, d.words)
+	maxValue := big.NewInt(int64(len(words)))
+
+	for i := range picked {
+		n, err := rand.Int(rand.Reader, maxValue)
+		if err != nil {
+			return "", err
+		}
+		picked[i] = words[n.Int64()]
+	}
+
+	return strings.Join(picked, "-"), nil
+}
+
+// matches accepts any lowercase words, so that a misspelt word is
+// still recognised as a typo.
+func (d diceware) matches(key string) bool {
+	parts := strings.Split(key, "-")
+	if len(parts) != d.words {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return false
+		}
+	}
+	return true
+}
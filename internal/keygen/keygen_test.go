@@ -0,0 +1,251 @@
+package keygen
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+)
+
+var shapes = []struct {
+	opts    Options
+	pattern *regexp.Regexp
+}{
+	{Options{Strategy: Base62, Length: 16}, regexp.MustCompile(`^[0-9A-Za-z]{16}$`)},
+	{Options{Strategy: Base62, Length: constant.MinKeyLength}, regexp.MustCompile(`^[0-9A-Za-z]+$`)},
+	{Options{Strategy: UUIDv7}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	{Options{Strategy: Diceware, Words: 4}, regexp.MustCompile(`^[a-z]+(-[a-z]+){3}$`)},
+	{Options{Strategy: Base62, Length: 16, CheckChar: true}, regexp.MustCompile(`^[0-9A-Za-z]{17}$`)},
+	{Options{Strategy: UUIDv7, CheckChar: true}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}-[0-9A-Za-z]$`)},
+	{Options{Strategy: Diceware, Words: 4, CheckChar: true}, regexp.MustCompile(`^[a-z]+(-[a-z]+){3}-[0-9A-Za-z]$`)},
+}
+
+func TestGenerateShapes(t *testing.T) {
+	for _, tt := range shapes {
+		g, err := New(tt.opts)
+		if err != nil {
+			t.Fatalf("New(%+v): %v", tt.opts, err)
+		}
+
+		seen := make(map[string]bool)
+		for i := 0; i < 50; i++ {
+			key, err := g.Generate()
+			if err != nil {
+				t.Fatalf("%+v: Generate: %v", tt.opts, err)
+			}
+			if !tt.pattern.MatchString(key) {
+				t.Errorf("%+v: key %q does not match %s", tt.opts, key, tt.pattern)
+			}
+			if g.Mistyped(key) {
+				t.Errorf("%+v: valid key %q reported as mistyped", tt.opts, key)
+			}
+			if len(key) > constant.MaxCustomKeyLength || !constant.CustomKeyPattern.MatchString(key) {
+				t.Errorf("%+v: key %q is not a valid chosen key", tt.opts, key)
+			}
+			seen[key] = true
+		}
+
+		if len(seen) < 50 {
+			t.Errorf("%+v: %d distinct keys out of 50", tt.opts, len(seen))
+		}
+	}
+}
+
+func TestUUIDv7KeysSortByTime(t *testing.T) {
+	g, err := New(Options{Strategy: UUIDv7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := ""
+	for i := 0; i < 100; i++ {
+		key, _ := g.Generate()
+		if key < prev {
+			t.Fatalf("key %q sorts before earlier key %q", key, prev)
+		}
+		prev = key
+	}
+}
+
+// TestMistypedSubstitutions checks that replacing any character of
+// a checked key by another character that keeps the key's shape is
+// reported as a typo, and that shape-breaking replacements are not.
+func TestMistypedSubstitutions(t *testing.T) {
+	const lower = "abcdefghijklmnopqrstuvwxyz"
+
+	tests := []struct {
+		opts Options
+
+		// keepsShape reports whether c may replace the character at
+		// position i of key without breaking its shape. Any character
+		// may replace the check character.
+		keepsShape func(key string, i int, c byte) bool
+	}{
+		{
+			opts: Options{Strategy: Base62, Length: 12, CheckChar: true},
+			keepsShape: func(key string, i int, c byte) bool {
+				return i == len(key)-1 || strings.IndexByte(constant.Base62Characters, c) >= 0
+			},
+		},
+		{
+			opts: Options{Strategy: UUIDv7, CheckChar: true},
+			keepsShape: func(key string, i int, c byte) bool {
+				if i == len(key)-1 {
+					return true
+				}
+				return key[i] != '-' && strings.IndexByte("0123456789abcdefABCDEF", c) >= 0
+			},
+		},
+		{
+			opts: Options{Strategy: Diceware, Words: 4, CheckChar: true},
+			keepsShape: func(key string, i int, c byte) bool {
+				if i == len(key)-1 {
+					return true
+				}
+				return key[i] != '-' && strings.IndexByte(lower, c) >= 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		g, err := New(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for n := 0; n < 20; n++ {
+			key, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < len(key); i++ {
+				for _, c := range []byte(constant.Base62Characters + "-_") {
+					if c == key[i] {
+						continue
+					}
+
+					typo := key[:i] + string(c) + key[i+1:]
+					want := tt.keepsShape(key, i, c)
+
+					if got := g.Mistyped(typo); got != want {
+						t.Errorf("%s: Mistyped(%q) = %v, want %v (from %q)", tt.opts.Strategy, typo, got, want, key)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMistypedOtherShapes(t *testing.T) {
+	g, err := New(Options{Strategy: Base62, Length: 12, CheckChar: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := g.Generate()
+
+	for _, k := range []string{"", "a", key[:len(key)-1], key + "a", "team-a/config", strings.Repeat("-", len(key))} {
+		if g.Mistyped(k) {
+			t.Errorf("Mistyped(%q) = true for a key of another shape", k)
+		}
+	}
+}
+
+func TestPlainNeverMistyped(t *testing.T) {
+	for _, opts := range []Options{
+		{Strategy: Base62, Length: 12},
+		{Strategy: UUIDv7},
+		{Strategy: Diceware, Words: 4},
+	} {
+		g, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, _ := g.Generate()
+		if g.Mistyped(key) || g.Mistyped(key[:len(key)-1]+"0") {
+			t.Errorf("%s: Mistyped is true without check characters", opts.Strategy)
+		}
+	}
+}
+
+func TestCheckChar(t *testing.T) {
+	tests := []struct {
+		key  string
+		want byte
+	}{
+		// Codes are positions in Base62Characters, so 'a' is 0 and
+		// '0' is 52. The rightmost character is doubled: 2*52 = 104
+		// adds 1 + 42 = 43, and 62 - 43 = 19 is 't'.
+		{"", 'a'},
+		{"a", 'a'},
+		{"0", 't'},
+		{"1", 'r'},
+		{"ba", '9'},
+
+		// Characters outside the alphabet, such as '-', are skipped
+		{"abc", checkChar("a-b-c")},
+		{"ZZ", checkChar("Z-Z")},
+	}
+
+	for _, tt := range tests {
+		if got := checkChar(tt.key); got != tt.want {
+			t.Errorf("checkChar(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	// Adjacent transpositions of distinct characters are mostly caught
+	g := checked{strategy: base62{length: 12}}
+	caught, total := 0, 0
+
+	for n := 0; n < 200; n++ {
+		key, _ := g.Generate()
+		for i := 0; i+2 < len(key); i++ {
+			if key[i] == key[i+1] {
+				continue
+			}
+			swapped := key[:i] + string(key[i+1]) + string(key[i]) + key[i+2:]
+			total++
+			if g.Mistyped(swapped) {
+				caught++
+			}
+		}
+	}
+
+	if caught*100 < total*95 {
+		t.Errorf("caught %d of %d transpositions", caught, total)
+	}
+}
+
+func TestWordList(t *testing.T) {
+	if len(words) < 1024 {
+		t.Errorf("word list has %d words", len(words))
+	}
+
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		if w == "" || strings.Trim(w, "abcdefghijklmnopqrstuvwxyz") != "" {
+			t.Errorf("word %q is not lowercase letters", w)
+		}
+		if seen[w] {
+			t.Errorf("duplicate word %q", w)
+		}
+		seen[w] = true
+	}
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Strategy: "sha1"},
+		{Strategy: Base62, Length: constant.MinKeyLength - 1},
+		{Strategy: Base62, Length: constant.MaxKeyLength + 1},
+		{Strategy: Diceware, Words: constant.MinKeyWords - 1},
+		{Strategy: Diceware, Words: constant.MaxKeyWords + 1},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded", opts)
+		}
+	}
+}
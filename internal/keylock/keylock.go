@@ -0,0 +1,68 @@
+// Package keylock serializes work on a single key.
+// Locks are created on demand and dropped once no goroutine holds
+// or waits for them, so idle keys cost nothing.
+
+package keylock
+
+import (
+	"slices"
+	"sync"
+)
+
+type lock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+type Locker struct {
+	mu    sync.Mutex
+	locks map[string]*lock
+}
+
+func New() *Locker {
+	return &Locker{locks: make(map[string]*lock)}
+}
+
+// Lock blocks until key is free and returns the function that
+// releases it.
+func (l *Locker) Lock(key string) (unlock func()) {
+	l.mu.Lock()
+	k, ok := l.locks[key]
+	if !ok {
+		k = &lock{}
+		l.locks[key] = k
+	}
+	k.refs++
+	l.mu.Unlock()
+
+	k.mu.Lock()
+
+	return func() {
+		k.mu.Unlock()
+
+		l.mu.Lock()
+		k.refs--
+		if k.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// LockAll locks every distinct key and returns the function that
+// releases them. Keys are locked in sorted order, so that callers
+// locking overlapping sets cannot deadlock.
+func (l *Locker) LockAll(keys []string) (unlock func()) {
+	sorted := slices.Compact(slices.Sorted(slices.Values(keys)))
+
+	unlocks := make([]func(), len(sorted))
+	for i, key := range sorted {
+		unlocks[i] = l.Lock(key)
+	}
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package keylock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// entries returns the number of keys with a lock entry.
+func entries(l *Locker) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
+
+func TestLockRefCounting(t *testing.T) {
+	l := New()
+
+	unlock := l.Lock("a")
+
+	acquired := make(chan func())
+	go func() { acquired <- l.Lock("a") }()
+
+	// Wait for the second caller to register
+	for {
+		l.mu.Lock()
+		refs := l.locks["a"].refs
+		l.mu.Unlock()
+		if refs == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock returned while the key was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	unlock2 := <-acquired
+
+	// The entry survives while the second caller holds it
+	if n := entries(l); n != 1 {
+		t.Errorf("%d entries while held, want 1", n)
+	}
+
+	unlock2()
+	if n := entries(l); n != 0 {
+		t.Errorf("%d entries after all unlocks, want 0", n)
+	}
+}
+
+func TestLockIndependentKeys(t *testing.T) {
+	l := New()
+
+	unlock := l.Lock("a")
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.Lock("b")()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Lock on another key blocked")
+	}
+}
+
+func TestLockAll(t *testing.T) {
+	l := New()
+
+	unlock := l.LockAll([]string{"b", "a", "b", "c"})
+	if n := entries(l); n != 3 {
+		t.Errorf("%d entries held, want 3", n)
+	}
+	unlock()
+
+	if n := entries(l); n != 0 {
+		t.Errorf("%d entries after unlock, want 0", n)
+	}
+
+	l.LockAll(nil)()
+
+	// Overlapping sets given in opposite orders do not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(3)
+		go func() { defer wg.Done(); l.LockAll([]string{"x", "y", "z"})() }()
+		go func() { defer wg.Done(); l.LockAll([]string{"z", "y", "x"})() }()
+		go func() { defer wg.Done(); l.Lock("y")() }()
+	}
+	wg.Wait()
+
+	if n := entries(l); n != 0 {
+		t.Errorf("%d entries left, want 0", n)
+	}
+}
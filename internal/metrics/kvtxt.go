@@ -0,0 +1,61 @@
+package metrics
+
+// HTTP holds the request metrics of a server.
+type HTTP struct {
+	Requests *CounterVec
+	Duration *HistogramVec
+}
+
+// NewHTTP registers the request metrics of a server with r.
+func NewHTTP(r *Registry) *HTTP {
+	return &HTTP{
+		Requests: r.NewCounterVec(
+			"kvtxt_http_requests_total",
+			"Total HTTP requests by route, method, status and error code.",
+			"route", "method", "status", "code",
+		),
+		Duration: r.NewHistogramVec(
+			"kvtxt_http_request_duration_seconds",
+			"HTTP request latency by route, method, status and error code.",
+			DefaultBuckets,
+			"route", "method", "status", "code",
+		),
+	}
+}
+
+// NewCryptoDuration creates the encryption latency histogram. It is
+// owned by a cipher and registered by every server using it.
+func NewCryptoDuration() *HistogramVec {
+	return NewHistogramVec(
+		"kvtxt_crypto_duration_seconds",
+		"Encryption and decryption latency.",
+		DefaultBuckets,
+		"op",
+	)
+}
+
+// Cleanup holds the metrics of a cleanup worker.
+type Cleanup struct {
+	Runs    *CounterVec
+	Deleted *CounterVec
+}
+
+// NewCleanup registers the metrics of a cleanup worker with r.
+func NewCleanup(r *Registry) *Cleanup {
+	c := &Cleanup{
+		Runs: r.NewCounterVec(
+			"kvtxt_cleanup_runs_total",
+			"Cleanup worker runs by result.",
+			"result",
+		),
+		Deleted: r.NewCounterVec(
+			"kvtxt_cleanup_deleted_total",
+			"Expired entries deleted by the cleanup worker.",
+		),
+	}
+
+	// Expose unlabeled counters before their first increment
+	c.Deleted.Add(0)
+
+	return c
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("requests_total", "Requests by route.", "route")
+	requests.Inc(`/a"b`)
+	requests.Add(2, "/c")
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1})
+	latency.Observe(0.5)
+
+	r.NewGaugeFunc("open", "Open things.", func() float64 { return 3 })
+
+	var b strings.Builder
+	r.Write(&b)
+
+	want := `# HELP requests_total Requests by route.
+# TYPE requests_total counter
+requests_total{route="/a\"b"} 1
+requests_total{route="/c"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 0
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="+Inf"} 1
+latency_seconds_sum 0.5
+latency_seconds_count 1
+# HELP open Open things.
+# TYPE open gauge
+open 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestGaugeGroupFunc checks that gauges sharing a source read it once
+// per scrape.
+func TestGaugeGroupFunc(t *testing.T) {
+	r := NewRegistry()
+
+	calls := 0
+	r.NewGaugeGroupFunc([]GaugeDesc{
+		{Name: "db_bytes", Help: "Database size."},
+		{Name: "wal_bytes", Help: "WAL size."},
+	}, func() []float64 {
+		calls++
+		return []float64{100, 20}
+	})
+
+	var b strings.Builder
+	r.Write(&b)
+
+	if calls != 1 {
+		t.Errorf("source read %d times per scrape, want 1", calls)
+	}
+	for _, line := range []string{"db_bytes 100\n", "wal_bytes 20\n", "# TYPE wal_bytes gauge\n"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("output lacks %q:\n%s", line, b.String())
+		}
+	}
+}
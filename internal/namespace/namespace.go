@@ -0,0 +1,211 @@
+// Package namespace maps API credentials to tenants.
+// Each namespace carries its own data key and limits, so tenants
+// sharing one deployment stay isolated from each other.
+
+package namespace
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/jsonschema"
+	"github.com/hritikkanojiya/kvtxt/internal/keygen"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// Namespace holds the effective settings of a tenant.
+// Limits are already resolved against the global defaults.
+type Namespace struct {
+	Name           string
+	Crypto         *crypto.Crypto
+	MinTTL         time.Duration
+	DefaultTTL     time.Duration
+	MaxTTL         time.Duration
+	MaxPayloadSize int64
+	Keys           keygen.Generator
+	Quota          storage.Quota
+	ContentTypes   *contenttype.Policy
+
+	// CustomKeys allows clients to choose keys. Only namespaces
+	// reached with an API key allow it, so that keys cannot be
+	// claimed anonymously.
+	CustomKeys bool
+
+	// Schemas maps key prefixes to the schemas values under them
+	// must conform to.
+	Schemas map[string]*jsonschema.Schema
+}
+
+// Registry resolves API keys to namespaces.
+// With no namespaces configured, authentication is disabled and
+// every request is served from the default namespace.
+//
+// The definitions can be replaced at runtime with Reload; readers
+// always see either the old or the new set, never a mix.
+type Registry struct {
+	crypt *crypto.Crypto
+	state atomic.Pointer[registryState]
+}
+
+type registryState struct {
+	byKey       map[[sha256.Size]byte]*Namespace
+	byName      map[string]*Namespace
+	names       []string
+	fallback    *Namespace
+	maxPayload  int64
+	authEnabled bool
+}
+
+func NewRegistry(cfg *config.Config, crypt *crypto.Crypto) (*Registry, error) {
+	reg := &Registry{crypt: crypt}
+
+	if err := reg.Reload(cfg); err != nil {
+		return nil, err
+	}
+
+	return reg, nil
+}
+
+// Reload rebuilds the namespaces from cfg and swaps them in atomically.
+// On error the current definitions are kept.
+func (r *Registry) Reload(cfg *config.Config) error {
+	globalPayload := int64(cfg.MaxPayloadSize) * constant.MB
+
+	keys, err := keygen.New(keygen.Options{
+		Strategy:  cfg.KeyStrategy,
+		Length:    cfg.KeyLength,
+		Words:     cfg.KeyWords,
+		CheckChar: cfg.KeyCheckChar,
+	})
+	if err != nil {
+		return err
+	}
+
+	contentTypes, err := cfg.ContentTypes.Policy()
+	if err != nil {
+		return err
+	}
+
+	state := &registryState{
+		byKey:  make(map[[sha256.Size]byte]*Namespace),
+		byName: make(map[string]*Namespace),
+		fallback: &Namespace{
+			Name:           constant.DefaultNamespace,
+			Crypto:         r.crypt,
+			MinTTL:         cfg.MinTTL,
+			DefaultTTL:     cfg.DefaultTTL,
+			MaxTTL:         cfg.MaxTTL,
+			MaxPayloadSize: globalPayload,
+			Keys:           keys,
+			ContentTypes:   contentTypes,
+		},
+		maxPayload:  globalPayload,
+		authEnabled: len(cfg.Namespaces) > 0,
+	}
+
+	for _, def := range cfg.Namespaces {
+		schemas, err := def.SchemaPolicy()
+		if err != nil {
+			return fmt.Errorf("schemas of namespace %s: %w", def.Name, err)
+		}
+
+		ns := &Namespace{
+			Name:           def.Name,
+			Crypto:         r.crypt,
+			MinTTL:         cfg.MinTTL,
+			DefaultTTL:     cfg.DefaultTTL,
+			MaxTTL:         cfg.MaxTTL,
+			MaxPayloadSize: globalPayload,
+			Keys:           keys,
+			ContentTypes:   contentTypes,
+			CustomKeys:     true,
+			Schemas:        schemas,
+			Quota: storage.Quota{
+				MaxBytes:   def.QuotaBytes,
+				MaxEntries: def.QuotaEntries,
+			},
+		}
+
+		// The default namespace keeps the master key so that
+		// entries written before namespaces existed stay readable.
+		if def.Name != constant.DefaultNamespace {
+			nsCrypt, err := r.crypt.ForNamespace(def.Name)
+			if err != nil {
+				return fmt.Errorf("derive key for namespace %s: %w", def.Name, err)
+			}
+			ns.Crypto = nsCrypt
+		}
+
+		if def.MaxTTLSeconds > 0 {
+			ns.MaxTTL = time.Duration(def.MaxTTLSeconds) * time.Second
+			ns.DefaultTTL = min(ns.DefaultTTL, ns.MaxTTL)
+		}
+
+		if def.MaxPayloadSizeMB > 0 {
+			ns.MaxPayloadSize = int64(def.MaxPayloadSizeMB) * constant.MB
+		}
+
+		state.maxPayload = max(state.maxPayload, ns.MaxPayloadSize)
+		state.byName[ns.Name] = ns
+		state.names = append(state.names, ns.Name)
+
+		for _, key := range def.APIKeys {
+			state.byKey[sha256.Sum256([]byte(key))] = ns
+		}
+	}
+
+	r.state.Store(state)
+	return nil
+}
+
+// AuthEnabled reports whether callers must present an API key.
+func (r *Registry) AuthEnabled() bool {
+	return r.state.Load().authEnabled
+}
+
+// Authenticate returns the namespace bound to the given API key.
+// Keys are looked up by digest to avoid comparing secrets directly.
+func (r *Registry) Authenticate(apiKey string) (*Namespace, bool) {
+	s := r.state.Load()
+
+	if !s.authEnabled {
+		return s.fallback, true
+	}
+
+	ns, ok := s.byKey[sha256.Sum256([]byte(apiKey))]
+	return ns, ok
+}
+
+// Lookup returns a namespace by name.
+func (r *Registry) Lookup(name string) (*Namespace, bool) {
+	s := r.state.Load()
+
+	if !s.authEnabled {
+		return s.fallback, name == s.fallback.Name
+	}
+
+	ns, ok := s.byName[name]
+	return ns, ok
+}
+
+// Names lists all configured namespaces in definition order.
+func (r *Registry) Names() []string {
+	s := r.state.Load()
+
+	if !s.authEnabled {
+		return []string{s.fallback.Name}
+	}
+	return s.names
+}
+
+// MaxPayloadSize returns the largest payload size any namespace accepts.
+// The global body limit must be at least this large.
+func (r *Registry) MaxPayloadSize() int64 {
+	return r.state.Load().maxPayload
+}
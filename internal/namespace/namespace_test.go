@@ -0,0 +1,149 @@
+package namespace_test
+
+import (
+	"testing"
+
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/constant"
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+)
+
+func newCrypto(t *testing.T) *crypto.Crypto {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypt, err := crypto.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypt
+}
+
+func TestRegistryWithoutNamespaces(t *testing.T) {
+	reg, err := namespace.NewRegistry(config.Defaults(), newCrypto(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reg.AuthEnabled() {
+		t.Error("authentication enabled without namespaces")
+	}
+
+	for _, key := range []string{"", "anything"} {
+		ns, ok := reg.Authenticate(key)
+		if !ok || ns.Name != constant.DefaultNamespace {
+			t.Errorf("Authenticate(%q) = %v, %v", key, ns, ok)
+		}
+	}
+}
+
+func TestRegistryAuthenticate(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Namespaces = []config.Namespace{
+		{Name: "team-a", APIKeys: []string{"team-a-key-0123456789", "team-a-key-9876543210"}},
+		{Name: "team-b", APIKeys: []string{"team-b-key-0123456789"}, MaxPayloadSizeMB: 8},
+	}
+
+	reg, err := namespace.NewRegistry(cfg, newCrypto(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reg.AuthEnabled() {
+		t.Fatal("authentication disabled with namespaces")
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"team-a-key-0123456789", "team-a"},
+		{"team-a-key-9876543210", "team-a"},
+		{"team-b-key-0123456789", "team-b"},
+		{"team-b-key-012345678", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		ns, ok := reg.Authenticate(tt.key)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Authenticate(%q) = %s, want no namespace", tt.key, ns.Name)
+			}
+			continue
+		}
+		if !ok || ns.Name != tt.want {
+			t.Errorf("Authenticate(%q) = %v, %v, want %s", tt.key, ns, ok, tt.want)
+		}
+	}
+
+	a, _ := reg.Authenticate("team-a-key-0123456789")
+	b, _ := reg.Authenticate("team-b-key-0123456789")
+
+	if a.MaxPayloadSize != int64(cfg.MaxPayloadSize)*constant.MB {
+		t.Errorf("team-a payload limit = %d", a.MaxPayloadSize)
+	}
+	if b.MaxPayloadSize != 8*constant.MB {
+		t.Errorf("team-b payload limit = %d", b.MaxPayloadSize)
+	}
+	if got := reg.MaxPayloadSize(); got != max(a.MaxPayloadSize, b.MaxPayloadSize) {
+		t.Errorf("MaxPayloadSize = %d", got)
+	}
+}
+
+// TestNamespaceKeys checks that each namespace encrypts with its own
+// key, except default, which keeps the master key.
+func TestNamespaceKeys(t *testing.T) {
+	master := newCrypto(t)
+
+	cfg := config.Defaults()
+	cfg.Namespaces = []config.Namespace{
+		{Name: "team-a", APIKeys: []string{"team-a-key-0123456789"}},
+		{Name: "team-b", APIKeys: []string{"team-b-key-0123456789"}},
+		{Name: constant.DefaultNamespace, APIKeys: []string{"default-key-0123456789"}},
+	}
+
+	reg, err := namespace.NewRegistry(cfg, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := reg.Authenticate("team-a-key-0123456789")
+	b, _ := reg.Authenticate("team-b-key-0123456789")
+	def, _ := reg.Authenticate("default-key-0123456789")
+
+	sealed, err := a.Crypto.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plain, err := a.Crypto.Decrypt(sealed); err != nil || string(plain) != "secret" {
+		t.Errorf("team-a Decrypt = %q, %v", plain, err)
+	}
+	for name, other := range map[string]*crypto.Crypto{"team-b": b.Crypto, "master": master} {
+		if _, err := other.Decrypt(sealed); err == nil {
+			t.Errorf("%s decrypted a team-a value", name)
+		}
+	}
+
+	// The same name derives the same key, so values survive restarts
+	again, err := master.ForNamespace("team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := again.Decrypt(sealed); err != nil || string(plain) != "secret" {
+		t.Errorf("rederived team-a Decrypt = %q, %v", plain, err)
+	}
+
+	legacy, err := master.Encrypt([]byte("before namespaces"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := def.Crypto.Decrypt(legacy); err != nil || string(plain) != "before namespaces" {
+		t.Errorf("default Decrypt = %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,108 @@
+// Audit persists audit records in an append-only table.
+// Triggers reject any UPDATE or DELETE so that rows can only be
+// altered by bypassing SQLite, which the hash chain then exposes.
+
+package storage
+
+import (
+	"database/sql"
+)
+
+type AuditRecord struct {
+	ID        int64
+	Time      int64
+	RequestID string
+	Identity  string
+	ClientIP  string
+	Namespace string
+	Key       string
+	Action    string
+	Outcome   string
+	PrevHash  string
+	Hash      string
+}
+
+func (s *Storage) AppendAudit(rec *AuditRecord) error {
+	const q = `
+	INSERT INTO audit_log (
+		time, request_id, identity, client_ip, namespace,
+		key, action, outcome, prev_hash, hash
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := s.db.Exec(
+		q,
+		rec.Time,
+		rec.RequestID,
+		rec.Identity,
+		rec.ClientIP,
+		rec.Namespace,
+		rec.Key,
+		rec.Action,
+		rec.Outcome,
+		rec.PrevHash,
+		rec.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	rec.ID, err = result.LastInsertId()
+	return err
+}
+
+// LastAuditHash returns the hash of the newest record,
+// or an empty string when the log is empty.
+func (s *Storage) LastAuditHash() (string, error) {
+	var hash string
+
+	err := s.db.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return hash, err
+}
+
+// ScanAudit calls fn for every record in insertion order.
+func (s *Storage) ScanAudit(fn func(*AuditRecord) error) error {
+	const q = `
+	SELECT id, time, request_id, identity, client_ip, namespace,
+		key, action, outcome, prev_hash, hash
+	FROM audit_log
+	ORDER BY id
+	`
+
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec AuditRecord
+		err := rows.Scan(
+			&rec.ID,
+			&rec.Time,
+			&rec.RequestID,
+			&rec.Identity,
+			&rec.ClientIP,
+			&rec.Namespace,
+			&rec.Key,
+			&rec.Action,
+			&rec.Outcome,
+			&rec.PrevHash,
+			&rec.Hash,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
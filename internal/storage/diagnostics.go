@@ -0,0 +1,43 @@
+// Diagnostics exposes database state used by "kvtxt doctor".
+
+package storage
+
+import "database/sql"
+
+// JournalMode returns the SQLite journal mode, e.g. "wal".
+func (s *Storage) JournalMode() (string, error) {
+	var mode string
+	err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode)
+	return mode, err
+}
+
+// SampleEntry returns the most recent unexpired entry, or nil
+// when the store holds none.
+func (s *Storage) SampleEntry(now int64) (*Entry, error) {
+	const q = `
+	SELECT namespace, hash, payload, content_type, created_at, expires_at
+	FROM kv
+	WHERE expires_at IS NULL OR expires_at > ?
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	var e Entry
+	err := s.db.QueryRow(q, now).Scan(
+		&e.Namespace,
+		&e.Hash,
+		&e.Payload,
+		&e.ContentType,
+		&e.CreatedAt,
+		&e.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
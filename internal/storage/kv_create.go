@@ -1,36 +1,135 @@
 // Create inserts a new key-value record into storage.
 // It assumes validation has already been performed by API layer.
+// The namespace quota is checked and usage updated atomically.
+//
+// Inserting a key that is taken fails with a unique constraint
+// error, unless the existing entry has expired.
 
 package storage
 
 import (
 	"database/sql"
+	"errors"
 )
 
 type Entry struct {
+	Namespace   string
 	Hash        string
 	Payload     []byte
 	ContentType string
 	CreatedAt   int64
 	ExpiresAt   sql.NullInt64
+
+	// Version starts at 1 and grows with every update.
+	Version int64
+}
+
+func (s *Storage) Insert(e *Entry, quota Quota) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertEntry(tx, e, quota); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Storage) Insert(e *Entry) error {
+// BatchInsert is one entry of InsertBatch. Rekey, if set, replaces
+// the key after a collision; entries without it fail instead.
+type BatchInsert struct {
+	Entry *Entry
+	Rekey func() (string, error)
+}
+
+// InsertBatch inserts entries in a single transaction. Failures of
+// single entries, such as a taken key or an exceeded quota, are
+// returned per entry and do not affect the others. Any other error
+// aborts the whole batch.
+func (s *Storage) InsertBatch(items []BatchInsert, quota Quota) ([]error, error) {
+	const maxAttempts = 5
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]error, len(items))
+
+	for i, item := range items {
+		for attempt := 1; ; attempt++ {
+			err := insertEntry(tx, item.Entry, quota)
+			if IsUniqueConstraintError(err) && item.Rekey != nil && attempt < maxAttempts {
+				if item.Entry.Hash, err = item.Rekey(); err == nil {
+					continue
+				}
+			}
+
+			if err != nil && !IsUniqueConstraintError(err) && !errors.Is(err, ErrQuotaExceeded) {
+				return nil, err
+			}
+
+			results[i] = err
+			break
+		}
+	}
+
+	return results, tx.Commit()
+}
+
+func insertEntry(tx *sql.Tx, e *Entry, quota Quota) error {
 	const q = `
-	INSERT INTO kv (hash, payload, content_type, created_at, expires_at)
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO kv (namespace, hash, payload, content_type, created_at, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(
+	// An expired entry not yet removed by cleanup frees its key
+	var expiredSize int64
+	err := tx.QueryRow(`
+		SELECT LENGTH(payload)
+		FROM kv
+		WHERE namespace = ? AND hash = ?
+		AND expires_at IS NOT NULL AND expires_at <= ?
+	`, e.Namespace, e.Hash, e.CreatedAt).Scan(&expiredSize)
+
+	switch {
+	case err == nil:
+		if err := removeEntry(tx, e.Namespace, e.Hash, expiredSize); err != nil {
+			return err
+		}
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	size := int64(len(e.Payload))
+
+	if err := checkQuota(tx, e.Namespace, size, 1, e.CreatedAt, quota); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
 		q,
+		e.Namespace,
 		e.Hash,
 		e.Payload,
 		e.ContentType,
 		e.CreatedAt,
 		e.ExpiresAt,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := addUsage(tx, e.Namespace, size, 1); err != nil {
+		return err
+	}
+
+	e.Version = 1
+	return nil
 }
 
 func (e *Entry) ExpiresAtPtr() *int64 {
@@ -1,5 +1,55 @@
+// Delete removes key-value records, either one by key or all
+// expired ones in bulk.
+
 package storage
 
+import "database/sql"
+
+// Delete removes one entry and releases its quota usage.
+// It reports whether the entry existed.
+func (s *Storage) Delete(namespace, hash string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var size int64
+	err = tx.QueryRow(`
+		SELECT LENGTH(payload)
+		FROM kv
+		WHERE namespace = ? AND hash = ?
+	`, namespace, hash).Scan(&size)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := removeEntry(tx, namespace, hash, size); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// removeEntry deletes an entry of the given size and releases
+// its quota usage.
+func removeEntry(tx *sql.Tx, namespace, hash string, size int64) error {
+	if _, err := tx.Exec(`DELETE FROM kv WHERE namespace = ? AND hash = ?`, namespace, hash); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+		UPDATE kv_usage
+		SET bytes = MAX(bytes - ?, 0), entries = MAX(entries - 1, 0)
+		WHERE namespace = ?
+	`, size, namespace)
+	return err
+}
+
 func (s *Storage) DeleteExpired(now int64) (int64, error) {
 	result, err := s.db.Exec(`
 		DELETE FROM kv
@@ -0,0 +1,47 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func TestDelete(t *testing.T) {
+	store := openStore(t)
+
+	for _, ns := range []string{"a", "b"} {
+		if err := store.Insert(entry(ns, "key", 10, time.Hour), storage.Quota{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Insert(entry("a", "old", 20, -time.Minute), storage.Quota{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		namespace, hash string
+		want            bool
+	}{
+		{"a", "key", true},
+		{"a", "key", false},
+		{"a", "missing", false},
+		// Expired entries awaiting cleanup can be deleted
+		{"a", "old", true},
+		{"c", "key", false},
+	}
+	for _, tt := range tests {
+		deleted, err := store.Delete(tt.namespace, tt.hash)
+		if err != nil || deleted != tt.want {
+			t.Errorf("Delete(%s, %s) = %v, %v, want %v", tt.namespace, tt.hash, deleted, err, tt.want)
+		}
+	}
+
+	// Other namespaces keep their entry of the same key
+	if e, err := store.Get("b", "key"); err != nil || e == nil {
+		t.Errorf("Get(b, key) = %+v, %v", e, err)
+	}
+
+	expectUsage(t, store, "a", 0, 0)
+	expectUsage(t, store, "b", 10, 1)
+}
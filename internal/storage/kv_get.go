@@ -1,4 +1,5 @@
 // Get retrieves a key-value record from storage by hashed key.
+// Entries belonging to another namespace are reported as missing.
 
 package storage
 
@@ -6,20 +7,22 @@ import (
 	"database/sql"
 )
 
-func (s *Storage) Get(hash string) (*Entry, error) {
+func (s *Storage) Get(namespace, hash string) (*Entry, error) {
 	const q = `
-	SELECT hash, payload, content_type, created_at, expires_at
+	SELECT namespace, hash, payload, content_type, created_at, expires_at, version
 	FROM kv
-	WHERE hash = ?
+	WHERE namespace = ? AND hash = ?
 	`
 
 	var e Entry
-	err := s.db.QueryRow(q, hash).Scan(
+	err := s.db.QueryRow(q, namespace, hash).Scan(
+		&e.Namespace,
 		&e.Hash,
 		&e.Payload,
 		&e.ContentType,
 		&e.CreatedAt,
 		&e.ExpiresAt,
+		&e.Version,
 	)
 
 	if err == sql.ErrNoRows {
@@ -20,17 +20,28 @@ func applyPragmas(db *sql.DB) error {
 	return nil
 }
 
-func applySchema(db *sql.DB) error {
-	const schema = `
-	CREATE TABLE IF NOT EXISTS kv (
-		hash TEXT PRIMARY KEY,
-		payload BLOB NOT NULL,
-		content_type TEXT NOT NULL,
-		created_at INTEGER NOT NULL,
-		expires_at INTEGER
-	);
-	`
-
-	_, err := db.Exec(schema)
+// ensureColumn adds a column unless it exists, so that migrations
+// can adopt databases created before schema versioning.
+func ensureColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
 	return err
 }
@@ -0,0 +1,89 @@
+// Update rewrites an existing key-value record in place.
+// The caller reads and modifies the entry inside the transaction, so
+// that read-modify-write operations such as counters are atomic.
+// Every successful update increments the entry version.
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrEntryNotFound = errors.New("entry not found")
+	ErrEntryExpired  = errors.New("entry expired")
+)
+
+// Update loads the entry, passes it to modify and stores the result.
+// modify may change Payload, ContentType and ExpiresAt; an error
+// from it aborts the update and is returned unchanged. Growth of the
+// payload is checked against the namespace byte quota.
+func (s *Storage) Update(namespace, hash string, now int64, quota Quota, modify func(e *Entry) error) (*Entry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var e Entry
+	err = tx.QueryRow(`
+		SELECT namespace, hash, payload, content_type, created_at, expires_at, version
+		FROM kv
+		WHERE namespace = ? AND hash = ?
+	`, namespace, hash).Scan(
+		&e.Namespace,
+		&e.Hash,
+		&e.Payload,
+		&e.ContentType,
+		&e.CreatedAt,
+		&e.ExpiresAt,
+		&e.Version,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if e.ExpiresAt.Valid && e.ExpiresAt.Int64 <= now {
+		return nil, ErrEntryExpired
+	}
+
+	oldSize := int64(len(e.Payload))
+
+	if err := modify(&e); err != nil {
+		return nil, err
+	}
+
+	delta := int64(len(e.Payload)) - oldSize
+	if delta > 0 {
+		if err := checkQuota(tx, namespace, delta, 0, now, quota); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE kv
+		SET payload = ?, content_type = ?, expires_at = ?, version = version + 1
+		WHERE namespace = ? AND hash = ?
+	`, e.Payload, e.ContentType, e.ExpiresAt, namespace, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if delta != 0 {
+		if err := addUsage(tx, namespace, delta, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	e.Version++
+	return &e, nil
+}
@@ -0,0 +1,50 @@
+// Maintenance exposes SQLite housekeeping operations for operators.
+
+package storage
+
+// CheckpointResult reports the outcome of a WAL checkpoint in pages.
+type CheckpointResult struct {
+	Busy         bool
+	LogPages     int64
+	Checkpointed int64
+}
+
+// CountExpired returns entries past their expiry that the cleanup
+// worker has not removed yet.
+func (s *Storage) CountExpired(now int64) (int64, error) {
+	var count int64
+
+	err := s.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM kv
+		WHERE expires_at IS NOT NULL
+		AND expires_at <= ?
+	`, now).Scan(&count)
+
+	return count, err
+}
+
+// Checkpoint copies WAL content into the database file and
+// truncates the WAL.
+func (s *Storage) Checkpoint() (CheckpointResult, error) {
+	var (
+		res  CheckpointResult
+		busy int
+	)
+
+	err := s.db.QueryRow(`PRAGMA wal_checkpoint(TRUNCATE)`).Scan(
+		&busy,
+		&res.LogPages,
+		&res.Checkpointed,
+	)
+	res.Busy = busy != 0
+
+	return res, err
+}
+
+// Vacuum rebuilds the database file to reclaim free pages.
+// It blocks writers for the duration of the rebuild.
+func (s *Storage) Vacuum() error {
+	_, err := s.db.Exec(`VACUUM`)
+	return err
+}
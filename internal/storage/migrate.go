@@ -0,0 +1,269 @@
+// Migrate evolves the database schema through numbered migrations.
+//
+// SQL migrations are embedded from migrations/NNNN_name.sql; steps
+// that need logic are registered in goMigrations. Each migration
+// runs in its own transaction together with its schema_migrations
+// row, so a failure leaves the database at the previous version.
+// PRAGMA user_version mirrors the latest applied version.
+
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database was migrated by a
+// newer build than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// goMigrations lists migrations that cannot be expressed in SQL.
+var goMigrations = []migration{
+	{2, "add_kv_namespace", func(tx *sql.Tx) error {
+		// Databases created before namespaces existed lack the column
+		return ensureColumn(tx, "kv", "namespace", "TEXT NOT NULL DEFAULT 'default'")
+	}},
+}
+
+var migrations = mustLoadMigrations()
+
+// SchemaVersion is the schema version written by this build.
+var SchemaVersion = migrations[len(migrations)-1].version
+
+// MigrationStatus describes a migration known to the binary or
+// recorded in the database.
+type MigrationStatus struct {
+	Version int
+	Name    string
+
+	// AppliedAt is a Unix timestamp, zero while pending.
+	AppliedAt int64
+}
+
+func (m MigrationStatus) Pending() bool {
+	return m.AppliedAt == 0
+}
+
+// mustLoadMigrations merges the embedded SQL files with
+// goMigrations. Versions must be unique and contiguous from 1.
+func mustLoadMigrations() []migration {
+	all := append([]migration(nil), goMigrations...)
+
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			panic("storage: invalid migration file name " + entry.Name())
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		query := string(body)
+		all = append(all, migration{version, name, func(tx *sql.Tx) error {
+			_, err := tx.Exec(query)
+			return err
+		}})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].version < all[j].version })
+
+	for i, m := range all {
+		if m.version != i+1 {
+			panic(fmt.Sprintf("storage: migration %04d %s out of sequence", m.version, m.name))
+		}
+	}
+
+	return all
+}
+
+// Migrations reports every known migration in order, followed by
+// any recorded in the database that this binary does not know.
+func (s *Storage) Migrations() ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.version, Name: m.name}
+		if a, ok := applied[m.version]; ok {
+			st.AppliedAt = a.AppliedAt
+			delete(applied, m.version)
+		}
+		list = append(list, st)
+	}
+
+	unknown := make([]MigrationStatus, 0, len(applied))
+	for _, a := range applied {
+		unknown = append(unknown, a)
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+
+	return append(list, unknown...), nil
+}
+
+// Migrated returns the migrations applied when the store was opened.
+func (s *Storage) Migrated() []MigrationStatus {
+	return s.migrated
+}
+
+// Version returns the highest schema version recorded in the
+// database, or zero for a database that was never migrated.
+func (s *Storage) Version() (int, error) {
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// migrate applies all pending migrations in order.
+func migrate(db *sql.DB) ([]MigrationStatus, error) {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for v := range applied {
+		if v > SchemaVersion {
+			return nil, fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, v, SchemaVersion)
+		}
+	}
+
+	var done []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		st, err := applyMigration(db, m)
+		if err != nil {
+			return done, fmt.Errorf("migration %04d %s: %w", m.version, m.name, err)
+		}
+		if st != nil {
+			done = append(done, *st)
+		}
+	}
+
+	return done, nil
+}
+
+// applyMigration runs m unless another process applied it first,
+// in which case it returns nil.
+func applyMigration(db *sql.DB, m migration) (*MigrationStatus, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`,
+		m.version,
+	).Scan(&exists)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	if err := m.up(tx); err != nil {
+		return nil, err
+	}
+
+	st := MigrationStatus{Version: m.version, Name: m.name, AppliedAt: time.Now().Unix()}
+
+	_, err = tx.Exec(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		st.Version, st.Name, st.AppliedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return nil, err
+	}
+
+	return &st, tx.Commit()
+}
+
+// appliedMigrations reads schema_migrations, treating a missing
+// table as an empty one so read-only stores can be inspected.
+func appliedMigrations(db *sql.DB) (map[int]MigrationStatus, error) {
+	var exists bool
+	err := db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`,
+	).Scan(&exists)
+	if err != nil || !exists {
+		return map[int]MigrationStatus{}, err
+	}
+
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationStatus)
+	for rows.Next() {
+		var st MigrationStatus
+		if err := rows.Scan(&st.Version, &st.Name, &st.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[st.Version] = st
+	}
+
+	return applied, rows.Err()
+}
+
+// KnownMigrations lists the migrations of this build, all pending.
+func KnownMigrations() []MigrationStatus {
+	list := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		list[i] = MigrationStatus{Version: m.version, Name: m.name}
+	}
+	return list
+}
@@ -0,0 +1,232 @@
+package storage_test
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// baselineDB creates a database as the first release left it, before
+// migrations were tracked, with two live entries and an expired one.
+func baselineDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE kv (
+			hash TEXT PRIMARY KEY,
+			payload BLOB NOT NULL,
+			content_type TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			expires_at INTEGER
+		)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+
+	rows := []struct {
+		hash, contentType string
+		payload           []byte
+		expiresAt         any
+	}{
+		{"aaaa", "text/plain", []byte{1, 2}, nil},
+		{"bbbb", "application/json", []byte{3, 4, 5}, now + 3600},
+		{"cccc", "text/plain", []byte{6}, now - 60},
+	}
+	for i, r := range rows {
+		_, err := db.Exec(
+			`INSERT INTO kv (hash, payload, content_type, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+			r.hash, r.payload, r.contentType, i+1, r.expiresAt,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	path := baselineDB(t)
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	if got := len(store.Migrated()); got != storage.SchemaVersion {
+		t.Errorf("applied %d migrations, want %d", got, storage.SchemaVersion)
+	}
+	if v, err := store.Version(); err != nil || v != storage.SchemaVersion {
+		t.Errorf("Version = %d, %v, want %d", v, err, storage.SchemaVersion)
+	}
+
+	// Rows move to the default namespace with their content intact
+	tests := []struct {
+		hash, contentType string
+		payload           []byte
+		expires           bool
+	}{
+		{"aaaa", "text/plain", []byte{1, 2}, false},
+		{"bbbb", "application/json", []byte{3, 4, 5}, true},
+	}
+	for i, tt := range tests {
+		e, err := store.Get("default", tt.hash)
+		if err != nil || e == nil {
+			t.Fatalf("Get(%s) = %+v, %v", tt.hash, e, err)
+		}
+		if !bytes.Equal(e.Payload, tt.payload) || e.ContentType != tt.contentType ||
+			e.CreatedAt != int64(i+1) || e.ExpiresAt.Valid != tt.expires || e.Version != 1 {
+			t.Errorf("Get(%s) = %+v", tt.hash, e)
+		}
+	}
+
+	// Usage is counted for the migrated rows, expired ones included
+	// until cleanup removes them
+	expectUsage(t, store, "default", 6, 3)
+
+	// Keys are unique per namespace after the primary key rebuild
+	if err := store.Insert(entry("team", "aaaa", 1, time.Hour), storage.Quota{}); err != nil {
+		t.Errorf("insert of a migrated key in another namespace: %v", err)
+	}
+	if err := store.Insert(entry("default", "aaaa", 1, time.Hour), storage.Quota{}); err == nil {
+		t.Error("insert of a taken key succeeded")
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := baselineDB(t)
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if got := store.Migrated(); len(got) != 0 {
+		t.Errorf("reopening applied %v", got)
+	}
+}
+
+// TestMigrationStatus checks that a database can be inspected
+// read-only before and after migrating.
+func TestMigrationStatus(t *testing.T) {
+	path := baselineDB(t)
+
+	status := func() []storage.MigrationStatus {
+		t.Helper()
+
+		store, err := storage.OpenReadOnly(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		list, err := store.Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := status()
+	if len(list) != storage.SchemaVersion {
+		t.Fatalf("Migrations = %v", list)
+	}
+	for i, m := range list {
+		if m.Version != i+1 || m.Name == "" || !m.Pending() {
+			t.Errorf("before migrating: %+v", m)
+		}
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("inspecting the database changed it")
+	}
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	for _, m := range status() {
+		if m.Pending() {
+			t.Errorf("after migrating: %+v", m)
+		}
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from_the_future', 1)`,
+		storage.SchemaVersion+1,
+	)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := storage.Open(path); !errors.Is(err, storage.ErrSchemaTooNew) {
+		t.Fatalf("Open = %v, want ErrSchemaTooNew", err)
+	}
+
+	// Status still lists the unknown migration after the known ones
+	ro, err := storage.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+
+	list, err := ro.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := list[len(list)-1]
+	if len(list) != storage.SchemaVersion+1 || last.Name != "from_the_future" || last.Pending() {
+		t.Errorf("Migrations = %+v", list)
+	}
+}
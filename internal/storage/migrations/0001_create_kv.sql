@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS kv (
+	hash TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	content_type TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	expires_at INTEGER
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS kv_usage (
+	namespace TEXT PRIMARY KEY,
+	bytes INTEGER NOT NULL,
+	entries INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS kv_quotas (
+	namespace TEXT PRIMARY KEY,
+	max_bytes INTEGER NOT NULL,
+	max_entries INTEGER NOT NULL
+);
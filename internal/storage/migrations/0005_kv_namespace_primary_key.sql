@@ -0,0 +1,18 @@
+-- Keys are unique per namespace rather than globally, so that
+-- client-chosen keys in one namespace never collide with another
+CREATE TABLE kv_new (
+	namespace TEXT NOT NULL DEFAULT 'default',
+	hash TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	content_type TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	expires_at INTEGER,
+	PRIMARY KEY (namespace, hash)
+);
+
+INSERT INTO kv_new (namespace, hash, payload, content_type, created_at, expires_at)
+SELECT namespace, hash, payload, content_type, created_at, expires_at FROM kv;
+
+DROP TABLE kv;
+
+ALTER TABLE kv_new RENAME TO kv;
@@ -0,0 +1,2 @@
+-- Every update increments the version, for conditional updates
+ALTER TABLE kv ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
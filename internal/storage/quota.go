@@ -0,0 +1,244 @@
+// Quota tracks bytes and entries stored per namespace and
+// enforces limits on insert.
+//
+// Usage counters are maintained transactionally on insert and
+// recomputed from the kv table after expired rows are deleted.
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota limits the storage a namespace may consume.
+// Zero values mean unlimited.
+type Quota struct {
+	MaxBytes   int64
+	MaxEntries int64
+}
+
+type Usage struct {
+	Namespace string
+	Bytes     int64
+	Entries   int64
+}
+
+// checkQuota verifies that adding size bytes and the given number of
+// entries keeps the namespace within its limits. An admin override
+// replaces the given defaults.
+//
+// Entries that expired by now but were not yet removed by cleanup
+// are deleted before a request is rejected, so that they do not hold
+// on to the quota.
+func checkQuota(tx *sql.Tx, namespace string, size, entries, now int64, defaults Quota) error {
+	quota := defaults
+
+	err := tx.QueryRow(
+		`SELECT max_bytes, max_entries FROM kv_quotas WHERE namespace = ?`,
+		namespace,
+	).Scan(&quota.MaxBytes, &quota.MaxEntries)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	used, err := usage(tx, namespace)
+	if err != nil {
+		return err
+	}
+
+	if quota.allows(used, size, entries) {
+		return nil
+	}
+
+	purged, err := purgeExpired(tx, namespace, now)
+	if err != nil {
+		return err
+	}
+
+	if purged == 0 {
+		return ErrQuotaExceeded
+	}
+
+	if used, err = usage(tx, namespace); err != nil {
+		return err
+	}
+
+	if !quota.allows(used, size, entries) {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+func (q Quota) allows(used Usage, size, entries int64) bool {
+	if q.MaxBytes > 0 && used.Bytes+size > q.MaxBytes {
+		return false
+	}
+
+	if q.MaxEntries > 0 && entries > 0 && used.Entries+entries > q.MaxEntries {
+		return false
+	}
+
+	return true
+}
+
+func usage(tx *sql.Tx, namespace string) (Usage, error) {
+	used := Usage{Namespace: namespace}
+
+	err := tx.QueryRow(
+		`SELECT bytes, entries FROM kv_usage WHERE namespace = ?`,
+		namespace,
+	).Scan(&used.Bytes, &used.Entries)
+	if err != nil && err != sql.ErrNoRows {
+		return used, err
+	}
+
+	return used, nil
+}
+
+// purgeExpired deletes the expired entries of a namespace and
+// releases their usage. It returns the number of entries removed.
+func purgeExpired(tx *sql.Tx, namespace string, now int64) (int64, error) {
+	var count, size int64
+
+	err := tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(LENGTH(payload)), 0)
+		FROM kv
+		WHERE namespace = ? AND expires_at IS NOT NULL AND expires_at <= ?
+	`, namespace, now).Scan(&count, &size)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM kv
+		WHERE namespace = ? AND expires_at IS NOT NULL AND expires_at <= ?
+	`, namespace, now)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE kv_usage
+		SET bytes = MAX(bytes - ?, 0), entries = MAX(entries - ?, 0)
+		WHERE namespace = ?
+	`, size, count, namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func addUsage(tx *sql.Tx, namespace string, size, entries int64) error {
+	const q = `
+	INSERT INTO kv_usage (namespace, bytes, entries)
+	VALUES (?, ?, ?)
+	ON CONFLICT (namespace) DO UPDATE SET
+		bytes = MAX(bytes + excluded.bytes, 0),
+		entries = entries + excluded.entries
+	`
+
+	_, err := tx.Exec(q, namespace, size, entries)
+	return err
+}
+
+// RecomputeUsage rebuilds usage counters from the kv table.
+func (s *Storage) RecomputeUsage() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM kv_usage`); err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO kv_usage (namespace, bytes, entries)
+	SELECT namespace, SUM(LENGTH(payload)), COUNT(*)
+	FROM kv
+	GROUP BY namespace
+	`
+
+	if _, err := tx.Exec(q); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) Usage(namespace string) (Usage, error) {
+	u := Usage{Namespace: namespace}
+
+	err := s.db.QueryRow(
+		`SELECT bytes, entries FROM kv_usage WHERE namespace = ?`,
+		namespace,
+	).Scan(&u.Bytes, &u.Entries)
+
+	if err != nil && err != sql.ErrNoRows {
+		return u, err
+	}
+
+	return u, nil
+}
+
+func (s *Storage) ListUsage() ([]Usage, error) {
+	rows, err := s.db.Query(`SELECT namespace, bytes, entries FROM kv_usage ORDER BY namespace`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Namespace, &u.Bytes, &u.Entries); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+
+	return usage, rows.Err()
+}
+
+// QuotaOverride returns the admin-defined quota of a namespace, if any.
+func (s *Storage) QuotaOverride(namespace string) (*Quota, error) {
+	var q Quota
+
+	err := s.db.QueryRow(
+		`SELECT max_bytes, max_entries FROM kv_quotas WHERE namespace = ?`,
+		namespace,
+	).Scan(&q.MaxBytes, &q.MaxEntries)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+func (s *Storage) SetQuotaOverride(namespace string, q Quota) error {
+	const stmt = `
+	INSERT INTO kv_quotas (namespace, max_bytes, max_entries)
+	VALUES (?, ?, ?)
+	ON CONFLICT (namespace) DO UPDATE SET
+		max_bytes = excluded.max_bytes,
+		max_entries = excluded.max_entries
+	`
+
+	_, err := s.db.Exec(stmt, namespace, q.MaxBytes, q.MaxEntries)
+	return err
+}
+
+func (s *Storage) DeleteQuotaOverride(namespace string) error {
+	_, err := s.db.Exec(`DELETE FROM kv_quotas WHERE namespace = ?`, namespace)
+	return err
+}
@@ -0,0 +1,187 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+func openStore(t *testing.T) *storage.Storage {
+	t.Helper()
+
+	store, err := storage.Open(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// entry returns an entry of size bytes created now and expiring
+// after ttl, or already expired for a negative ttl.
+func entry(namespace, hash string, size int, ttl time.Duration) *storage.Entry {
+	now := time.Now()
+	return &storage.Entry{
+		Namespace:   namespace,
+		Hash:        hash,
+		Payload:     make([]byte, size),
+		ContentType: "text/plain",
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   sql.NullInt64{Int64: now.Add(ttl).Unix(), Valid: true},
+	}
+}
+
+func expectUsage(t *testing.T, store *storage.Storage, namespace string, bytes, entries int64) {
+	t.Helper()
+
+	u, err := store.Usage(namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Bytes != bytes || u.Entries != entries {
+		t.Errorf("%s usage = %d bytes, %d entries, want %d, %d", namespace, u.Bytes, u.Entries, bytes, entries)
+	}
+}
+
+func TestQuotaEntries(t *testing.T) {
+	store := openStore(t)
+	quota := storage.Quota{MaxEntries: 2}
+
+	for i := 0; i < 2; i++ {
+		if err := store.Insert(entry("a", fmt.Sprint(i), 10, time.Hour), quota); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+
+	if err := store.Insert(entry("a", "2", 10, time.Hour), quota); !errors.Is(err, storage.ErrQuotaExceeded) {
+		t.Fatalf("insert over quota = %v", err)
+	}
+
+	// Other namespaces are counted separately
+	if err := store.Insert(entry("b", "0", 10, time.Hour), quota); err != nil {
+		t.Fatalf("insert into b: %v", err)
+	}
+
+	expectUsage(t, store, "a", 20, 2)
+	expectUsage(t, store, "b", 10, 1)
+
+	// Deleting releases the usage
+	if _, err := store.Delete("a", "0"); err != nil {
+		t.Fatal(err)
+	}
+	expectUsage(t, store, "a", 10, 1)
+
+	if err := store.Insert(entry("a", "2", 10, time.Hour), quota); err != nil {
+		t.Fatalf("insert after delete: %v", err)
+	}
+}
+
+func TestQuotaBytes(t *testing.T) {
+	store := openStore(t)
+	quota := storage.Quota{MaxBytes: 100}
+
+	if err := store.Insert(entry("a", "0", 60, time.Hour), quota); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(entry("a", "1", 41, time.Hour), quota); !errors.Is(err, storage.ErrQuotaExceeded) {
+		t.Fatalf("insert over quota = %v", err)
+	}
+	if err := store.Insert(entry("a", "1", 40, time.Hour), quota); err != nil {
+		t.Fatalf("insert up to quota: %v", err)
+	}
+
+	expectUsage(t, store, "a", 100, 2)
+}
+
+// TestQuotaIgnoresExpired checks that entries which expired but were
+// not yet cleaned up do not count against the quota.
+func TestQuotaIgnoresExpired(t *testing.T) {
+	store := openStore(t)
+	quota := storage.Quota{MaxEntries: 2, MaxBytes: 100}
+
+	if err := store.Insert(entry("a", "live", 40, time.Hour), quota); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(entry("a", "old", 60, -time.Minute), quota); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(entry("b", "old", 60, -time.Minute), quota); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Insert(entry("a", "new", 60, time.Hour), quota); err != nil {
+		t.Fatalf("insert with expired entries holding the quota: %v", err)
+	}
+	expectUsage(t, store, "a", 100, 2)
+
+	if e, err := store.Get("a", "old"); err != nil || e != nil {
+		t.Errorf("expired entry of a = %+v, %v", e, err)
+	}
+
+	// Expired entries of other namespaces are left to cleanup
+	expectUsage(t, store, "b", 60, 1)
+
+	// Live entries still count
+	if err := store.Insert(entry("a", "more", 1, time.Hour), quota); !errors.Is(err, storage.ErrQuotaExceeded) {
+		t.Fatalf("insert over quota = %v", err)
+	}
+}
+
+func TestQuotaOverride(t *testing.T) {
+	store := openStore(t)
+	defaults := storage.Quota{MaxEntries: 1}
+
+	if err := store.SetQuotaOverride("a", storage.Quota{MaxEntries: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := store.QuotaOverride("a")
+	if err != nil || q == nil || q.MaxEntries != 3 {
+		t.Fatalf("QuotaOverride = %+v, %v", q, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.Insert(entry("a", fmt.Sprint(i), 1, time.Hour), defaults); err != nil {
+			t.Fatalf("insert %d under the override: %v", i, err)
+		}
+	}
+	if err := store.Insert(entry("a", "3", 1, time.Hour), defaults); !errors.Is(err, storage.ErrQuotaExceeded) {
+		t.Fatalf("insert over the override = %v", err)
+	}
+
+	if err := store.DeleteQuotaOverride("a"); err != nil {
+		t.Fatal(err)
+	}
+	if q, err := store.QuotaOverride("a"); err != nil || q != nil {
+		t.Fatalf("QuotaOverride after delete = %+v, %v", q, err)
+	}
+}
+
+func TestRecomputeUsage(t *testing.T) {
+	store := openStore(t)
+
+	store.Insert(entry("a", "live", 10, time.Hour), storage.Quota{})
+	store.Insert(entry("a", "old", 20, -time.Minute), storage.Quota{})
+	store.Insert(entry("b", "old", 30, -time.Minute), storage.Quota{})
+
+	deleted, err := store.DeleteExpired(time.Now().Unix())
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteExpired = %d, %v", deleted, err)
+	}
+	if err := store.RecomputeUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectUsage(t, store, "a", 10, 1)
+	expectUsage(t, store, "b", 0, 0)
+
+	usage, err := store.ListUsage()
+	if err != nil || len(usage) != 1 || usage[0].Namespace != "a" {
+		t.Errorf("ListUsage = %+v, %v", usage, err)
+	}
+}
@@ -0,0 +1,43 @@
+// Stats exposes database-level figures for monitoring.
+
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Size returns the on-disk size of the database file and its WAL.
+func (s *Storage) Size() (dbBytes int64, walBytes int64, err error) {
+	dbBytes, err = fileSize(s.path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	walBytes, err = fileSize(s.path + "-wal")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return dbBytes, walBytes, nil
+}
+
+// EntryCount returns the number of stored entries, including
+// expired ones not yet removed by the cleanup worker.
+func (s *Storage) EntryCount() (int64, error) {
+	var count int64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(entries), 0) FROM kv_usage`).Scan(&count)
+	return count, err
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
@@ -18,11 +18,13 @@ import (
 
 // Storage represents database connection and configuration.
 type Storage struct {
-	db *sql.DB
+	db       *sql.DB
+	path     string
+	migrated []MigrationStatus
 }
 
 func Open(path string) (*Storage, error) {
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", dsn(path))
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
@@ -35,11 +37,46 @@ func Open(path string) (*Storage, error) {
 		return nil, err
 	}
 
-	if err := applySchema(db); err != nil {
+	migrated, err := migrate(db)
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return &Storage{db: db}, nil
+	s := &Storage{db: db, path: path, migrated: migrated}
+
+	if err := s.RecomputeUsage(); err != nil {
+		return nil, fmt.Errorf("recompute usage: %w", err)
+	}
+
+	return s, nil
+}
+
+// OpenReadOnly opens an existing database for inspection without
+// creating it or applying schema changes.
+func OpenReadOnly(path string) (*Storage, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping sqlite: %w", err)
+	}
+
+	return &Storage{db: db, path: path}, nil
+}
+
+// dsn adds per-connection options to the database path.
+// Write transactions take the lock immediately so that concurrent
+// writers wait on busy_timeout instead of failing on lock upgrade.
+func dsn(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=busy_timeout(5000)&_txlock=immediate"
 }
 
 func (s *Storage) Close() error {
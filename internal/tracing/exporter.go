@@ -0,0 +1,250 @@
+// Exporter batches finished spans and sends them to an OTLP/HTTP
+// endpoint using the JSON protobuf encoding.
+//
+// Export is best effort: when the queue is full, spans are dropped
+// rather than slowing down request handling.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	queueSize     = 2048
+	maxBatchSize  = 512
+	flushInterval = 5 * time.Second
+)
+
+type Exporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+
+	// mu guards sends on queue against Shutdown closing it
+	mu     sync.RWMutex
+	closed bool
+
+	queue chan *Span
+	done  chan struct{}
+}
+
+// NewExporter starts a background exporter posting to endpoint,
+// typically http://collector:4318/v1/traces.
+func NewExporter(endpoint, serviceName string) *Exporter {
+	e := &Exporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, queueSize),
+		done:        make(chan struct{}),
+	}
+
+	go e.run()
+	return e
+}
+
+func (e *Exporter) enqueue(s *Span) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return
+	}
+
+	select {
+	case e.queue <- s:
+	default:
+		slog.Warn("trace queue full, span dropped", "span", s.name)
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSize)
+
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				e.export(batch)
+				return
+			}
+
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				e.export(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			e.export(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// Shutdown flushes queued spans and stops the exporter.
+// Spans ended after Shutdown is called are dropped.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Exporter) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		slog.Error("trace encode failed", "error", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Error("trace export failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		slog.Error("trace export rejected", "status", resp.StatusCode)
+	}
+}
+
+// OTLP JSON mapping, see opentelemetry-proto trace/v1.
+// Trace and span IDs are hex encoded, 64-bit integers are strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (e *Exporter) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+
+	for _, s := range batch {
+		s.mu.Lock()
+
+		span := otlpSpan{
+			TraceID:           s.ctx.TraceID.String(),
+			SpanID:            s.ctx.SpanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+
+		if s.parent != (SpanID{}) {
+			span.ParentSpanID = s.parent.String()
+		}
+
+		for _, a := range s.attrs {
+			span.Attributes = append(span.Attributes, keyValue(a.key, a.value))
+		}
+
+		if s.failed {
+			span.Status = otlpStatus{Code: 2, Message: s.errorMsg}
+		}
+
+		s.mu.Unlock()
+		spans = append(spans, span)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{keyValue("service.name", e.serviceName)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "kvtxt"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func keyValue(key string, value any) otlpKeyValue {
+	var v map[string]any
+
+	switch val := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": val}
+	case bool:
+		v = map[string]any{"boolValue": val}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(val)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": val}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(val)}
+	}
+
+	return otlpKeyValue{Key: key, Value: v}
+}
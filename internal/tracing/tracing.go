@@ -0,0 +1,224 @@
+// Package tracing provides minimal distributed tracing compatible
+// with OpenTelemetry.
+//
+// Trace context is propagated with the W3C traceparent header and
+// finished spans are exported over OTLP/HTTP (JSON encoding).
+// Each server span carries the exporter it was started with, and its
+// children inherit it, so servers in one process can export to
+// different collectors. Without an exporter, StartServer and Start
+// return nil spans and all span methods are no-ops, so
+// instrumentation costs nothing.
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+type SpanKind int
+
+// Values follow the OTLP SpanKind enumeration.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Span is a timed operation within a trace.
+type Span struct {
+	mu       sync.Mutex
+	name     string
+	kind     SpanKind
+	ctx      SpanContext
+	parent   SpanID
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	errorMsg string
+	failed   bool
+	ended    bool
+	exporter *Exporter
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+type spanKey struct{}
+
+// Start begins a child of the span in ctx, exported with it.
+// Without a span in ctx the operation is not traced.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return start(ctx, parent.exporter, name, KindInternal, parent.ctx)
+}
+
+// StartServer begins a server span continuing a remote parent.
+// Passing a nil exporter disables tracing for the request.
+func StartServer(ctx context.Context, e *Exporter, name string, remote SpanContext) (context.Context, *Span) {
+	if e == nil {
+		return ctx, nil
+	}
+	return start(ctx, e, name, KindServer, remote)
+}
+
+func start(ctx context.Context, e *Exporter, name string, kind SpanKind, parent SpanContext) (context.Context, *Span) {
+	span := &Span{
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		exporter: e,
+	}
+
+	if parent.IsValid() {
+		span.ctx.TraceID = parent.TraceID
+		span.ctx.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.ctx.TraceID[:])
+		span.ctx.Sampled = true
+	}
+	rand.Read(span.ctx.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceIDFromContext returns the trace ID of the span in ctx, or an empty
+// string when the request is not traced. Used to correlate logs.
+func TraceIDFromContext(ctx context.Context) string {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return span.ctx.TraceID.String()
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed.
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed = true
+	s.errorMsg = msg
+	s.mu.Unlock()
+}
+
+// End finishes the span and hands it to the exporter.
+// Spans from unsampled traces are dropped.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if !s.ctx.Sampled {
+		return
+	}
+
+	s.exporter.enqueue(s)
+}
+
+// ParseTraceparent decodes a W3C traceparent header value:
+// version-traceid-parentid-flags, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	// Version 00 defines exactly four fields
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
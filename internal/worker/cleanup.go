@@ -6,19 +6,43 @@ package worker
 import (
 	"context"
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/hritikkanojiya/kvtxt/internal/metrics"
 	"github.com/hritikkanojiya/kvtxt/internal/storage"
 )
 
-func StartCleanupWorker(
-	ctx context.Context,
-	store *storage.Storage,
-	interval time.Duration,
-) {
+type CleanupWorker struct {
+	store   *storage.Storage
+	metrics *metrics.Cleanup
+
+	// intervalMu guards interval and ticker, which SetInterval
+	// may change while the worker is running
+	intervalMu sync.Mutex
+	interval   time.Duration
+	ticker     *time.Ticker
+
+	// mu serializes scheduled and on-demand runs
+	mu sync.Mutex
+}
+
+// NewCleanupWorker creates a worker that reports its runs to reg.
+func NewCleanupWorker(store *storage.Storage, interval time.Duration, reg *metrics.Registry) *CleanupWorker {
+	return &CleanupWorker{
+		store:    store,
+		metrics:  metrics.NewCleanup(reg),
+		interval: interval,
+	}
+}
+
+func (cw *CleanupWorker) Start(ctx context.Context) {
 
 	// Run cleanup at fixed interval
-	ticker := time.NewTicker(interval)
+	cw.intervalMu.Lock()
+	ticker := time.NewTicker(cw.interval)
+	cw.ticker = ticker
+	cw.intervalMu.Unlock()
 
 	go func() {
 		defer ticker.Stop()
@@ -30,20 +54,58 @@ func StartCleanupWorker(
 				return
 
 			case <-ticker.C:
-				now := time.Now().Unix()
-
-				deleted, err := store.DeleteExpired(now)
-				if err != nil {
+				if _, err := cw.RunOnce(); err != nil {
 					slog.Error("cleanup failed", "error", err)
-					continue
-				}
-
-				if deleted > 0 {
-					slog.Info("expired entries cleaned",
-						"count", deleted,
-					)
 				}
 			}
 		}
 	}()
 }
+
+// SetInterval changes the time between scheduled runs.
+// The next run happens one full interval after the change.
+func (cw *CleanupWorker) SetInterval(interval time.Duration) {
+	cw.intervalMu.Lock()
+	defer cw.intervalMu.Unlock()
+
+	if interval == cw.interval {
+		return
+	}
+
+	cw.interval = interval
+	if cw.ticker != nil {
+		cw.ticker.Reset(interval)
+	}
+}
+
+// RunOnce deletes expired entries immediately and returns the
+// number of rows removed. It is safe to call concurrently with
+// the scheduled runs.
+func (cw *CleanupWorker) RunOnce() (int64, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	now := time.Now().Unix()
+
+	deleted, err := cw.store.DeleteExpired(now)
+	if err != nil {
+		cw.metrics.Runs.Inc("error")
+		return 0, err
+	}
+
+	cw.metrics.Runs.Inc("ok")
+	cw.metrics.Deleted.Add(float64(deleted))
+
+	if deleted > 0 {
+		slog.Info("expired entries cleaned",
+			"count", deleted,
+		)
+
+		// Release quota held by the deleted entries
+		if err := cw.store.RecomputeUsage(); err != nil {
+			slog.Error("usage recompute failed", "error", err)
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,81 @@
+// Package kvtxt embeds the kvtxt server in another Go program.
+//
+// NewServer returns an http.Handler serving the same routes, with the
+// same middleware, as the kvtxt binary. Callers supply storage, crypto
+// and cache, so the handler can be mounted under a sub-path of an
+// existing service or started in-process for integration tests:
+//
+//	store, _ := kvtxt.OpenStorage("kv.db")
+//	crypt, _ := kvtxt.NewCrypto(key)
+//	srv, _ := kvtxt.NewServer(kvtxt.Options{Storage: store, Crypto: crypt})
+//	srv.StartCleanup(ctx)
+//	mux.Handle("/kv/", http.StripPrefix("/kv", srv))
+package kvtxt
+
+import (
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/config"
+	"github.com/hritikkanojiya/kvtxt/internal/crypto"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+	"github.com/hritikkanojiya/kvtxt/internal/tracing"
+)
+
+type (
+	// Config holds server settings; see DefaultConfig.
+	Config = config.Config
+
+	// Namespace defines a tenant within Config.
+	Namespace = config.Namespace
+
+	// Storage is the SQLite-backed entry store.
+	Storage = storage.Storage
+
+	// Crypto encrypts values at rest.
+	Crypto = crypto.Crypto
+
+	// Cache is the in-memory LRU cache of decrypted values.
+	Cache = cache.Cache
+
+	// TraceExporter sends request spans to an OTLP/HTTP collector.
+	TraceExporter = tracing.Exporter
+)
+
+// DefaultConfig returns the settings the binary uses when nothing
+// is configured. Storage and key settings are ignored by NewServer.
+func DefaultConfig() *Config {
+	return config.Defaults()
+}
+
+// LoadConfig reads configuration like the binary does, from args,
+// the environment and an optional --config file.
+func LoadConfig(args []string) (*Config, error) {
+	return config.Load(args)
+}
+
+// OpenStorage opens or creates the database at path and applies
+// schema changes.
+func OpenStorage(path string) (*Storage, error) {
+	return storage.Open(path)
+}
+
+// NewCrypto creates a cipher from a base64 encoded 32-byte key.
+func NewCrypto(keyB64 string) (*Crypto, error) {
+	return crypto.New(keyB64)
+}
+
+// GenerateKey returns a new random key for NewCrypto.
+func GenerateKey() (string, error) {
+	return crypto.GenerateKey()
+}
+
+// NewCache creates a cache holding up to maxSize values.
+func NewCache(maxSize int) *Cache {
+	return cache.New(maxSize)
+}
+
+// NewTraceExporter starts exporting spans to an OTLP/HTTP endpoint,
+// typically http://collector:4318/v1/traces. Call Shutdown to flush
+// it.
+func NewTraceExporter(endpoint, serviceName string) *TraceExporter {
+	return tracing.NewExporter(endpoint, serviceName)
+}
@@ -0,0 +1,49 @@
+package kvtxt
+
+import (
+	"log/slog"
+
+	"github.com/hritikkanojiya/kvtxt/internal/cache"
+	"github.com/hritikkanojiya/kvtxt/internal/metrics"
+	"github.com/hritikkanojiya/kvtxt/internal/storage"
+)
+
+// registerRuntimeMetrics exposes cache and storage figures that
+// are read from their owners at scrape time.
+func registerRuntimeMetrics(reg *metrics.Registry, store *storage.Storage, c *cache.Cache) {
+	reg.NewCounterFunc("kvtxt_cache_hits_total", "Cache lookups served from memory.", func() float64 {
+		return float64(c.Stats().Hits)
+	})
+
+	reg.NewCounterFunc("kvtxt_cache_misses_total", "Cache lookups that fell through to storage.", func() float64 {
+		return float64(c.Stats().Misses)
+	})
+
+	reg.NewCounterFunc("kvtxt_cache_evictions_total", "Entries evicted by the LRU policy.", func() float64 {
+		return float64(c.Stats().Evictions)
+	})
+
+	reg.NewGaugeFunc("kvtxt_cache_entries", "Entries currently held in the cache.", func() float64 {
+		return float64(c.Stats().Size)
+	})
+
+	// One stat call serves both file sizes
+	reg.NewGaugeGroupFunc([]metrics.GaugeDesc{
+		{Name: "kvtxt_db_size_bytes", Help: "Size of the SQLite database file."},
+		{Name: "kvtxt_db_wal_size_bytes", Help: "Size of the SQLite write-ahead log."},
+	}, func() []float64 {
+		dbBytes, walBytes, err := store.Size()
+		if err != nil {
+			slog.Error("db size metric failed", "error", err)
+		}
+		return []float64{float64(dbBytes), float64(walBytes)}
+	})
+
+	reg.NewGaugeFunc("kvtxt_entries", "Stored entries, including expired ones not yet cleaned.", func() float64 {
+		count, err := store.EntryCount()
+		if err != nil {
+			slog.Error("entry count metric failed", "error", err)
+		}
+		return float64(count)
+	})
+}
@@ -0,0 +1,476 @@
+// Package client is a Go SDK for the kvtxt HTTP API.
+//
+//	c := client.New("https://kv.example.com", client.WithAPIKey(key))
+//	entry, err := c.Create(ctx, []byte("secret"), client.WithTTL(time.Hour))
+//	value, err := c.Get(ctx, entry.Key)
+//
+// Failed requests are retried with exponential backoff when that
+// cannot apply them twice. Get, Meta and Delete are retried after 429,
+// 5xx and network errors. Create and Increment are not idempotent, so
+// they are only retried when the server did not process them: after
+// 429, 503 or a failure to connect.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+)
+
+// Client talks to one kvtxt server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with a namespace API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces the default client, which has a 30s timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how often a failed request is retried and the
+// initial backoff, which doubles on each attempt. Zero disables retries.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max(maxRetries, 0)
+		c.backoff = backoff
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// BaseURL returns the server URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// EntryURL returns the URL under which key is served.
+func (c *Client) EntryURL(key string) string {
+	// Slashes in chosen keys separate path segments
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return c.baseURL + "/v1/kv/" + strings.Join(segments, "/")
+}
+
+// Entry identifies a stored value.
+type Entry struct {
+	Key       string
+	URL       string
+	ExpiresAt *time.Time
+}
+
+// Value is a stored value with its content type.
+type Value struct {
+	Data        []byte
+	ContentType string
+}
+
+// Meta describes a stored value without transferring it.
+type Meta struct {
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time
+}
+
+type createOptions struct {
+	key         string
+	ttl         time.Duration
+	contentType string
+	schemaKey   string
+}
+
+type CreateOption func(*createOptions)
+
+// WithTTL sets the entry lifetime, rounded down to whole seconds.
+// Without it the server default applies.
+func WithTTL(ttl time.Duration) CreateOption {
+	return func(o *createOptions) { o.ttl = ttl }
+}
+
+// WithKey stores the value under a chosen key, such as
+// "team-a/staging-db-creds", instead of a generated one. Creating a
+// key that is taken fails with ErrConflict.
+func WithKey(key string) CreateOption {
+	return func(o *createOptions) { o.key = key }
+}
+
+// WithContentType sets the content type; the default is plain text.
+// For application/json the value must be valid JSON.
+func WithContentType(contentType string) CreateOption {
+	return func(o *createOptions) { o.contentType = contentType }
+}
+
+// WithSchemaKey validates the value against the JSON Schema stored
+// under key. A value that does not conform fails with
+// ErrSchemaViolation.
+func WithSchemaKey(key string) CreateOption {
+	return func(o *createOptions) { o.schemaKey = key }
+}
+
+// Create stores value and returns its key.
+func (c *Client) Create(ctx context.Context, value []byte, opts ...CreateOption) (*Entry, error) {
+	o := createOptions{contentType: "text/plain; charset=utf-8"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	req := map[string]any{"content_type": o.contentType}
+
+	// JSON values are sent as-is, everything else as a JSON string
+	if isJSON(o.contentType) {
+		if !json.Valid(value) {
+			return nil, errors.New("kvtxt: value is not valid JSON")
+		}
+		req["text"] = json.RawMessage(value)
+	} else {
+		req["text"] = string(value)
+	}
+
+	if o.key != "" {
+		req["key"] = o.key
+	}
+
+	if o.schemaKey != "" {
+		req["schema_key"] = o.schemaKey
+	}
+
+	if o.ttl > 0 {
+		req["ttl_seconds"] = int64(o.ttl / time.Second)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.baseURL+"/v1/kv", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		Key       string `json:"key"`
+		ExpiresAt *int64 `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Key:       created.Key,
+		URL:       c.EntryURL(created.Key),
+		ExpiresAt: unixTime(created.ExpiresAt),
+	}, nil
+}
+
+// Get fetches and returns a stored value.
+func (c *Client) Get(ctx context.Context, key string) (*Value, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.EntryURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ct := resp.Header.Get("Content-Type")
+
+	// Text values are served as the JSON string they were sent as,
+	// YAML and XML as the document the string held
+	if !isJSON(ct) && !isDocument(ct) {
+		var text string
+		if json.Unmarshal(data, &text) == nil {
+			data = []byte(text)
+		}
+	}
+
+	return &Value{Data: data, ContentType: ct}, nil
+}
+
+// Delete removes a stored value.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.EntryURL(key), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Increment atomically adds by, which may be negative, to an integer
+// value and returns the result. The entry must already hold an
+// integer; anything else fails with ErrTypeMismatch. Like Create, a
+// retried Increment may be applied twice.
+func (c *Client) Increment(ctx context.Context, key string, by int64) (int64, error) {
+	body, err := json.Marshal(map[string]int64{"by": by})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.EntryURL(key)+":increment", body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var updated struct {
+		Value int64 `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return 0, err
+	}
+
+	return updated.Value, nil
+}
+
+// Meta returns metadata of a stored value without fetching it.
+func (c *Client) Meta(ctx context.Context, key string) (*Meta, error) {
+	resp, err := c.do(ctx, http.MethodHead, c.EntryURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	m := &Meta{
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+
+	if v, err := strconv.ParseInt(resp.Header.Get("X-Kvtxt-Created-At"), 10, 64); err == nil {
+		m.CreatedAt = time.Unix(v, 0)
+	}
+
+	if v, err := strconv.ParseInt(resp.Header.Get("X-Kvtxt-Expires-At"), 10, 64); err == nil {
+		m.ExpiresAt = unixTime(&v)
+	}
+
+	return m, nil
+}
+
+// do sends a request, retrying transient failures, and converts
+// error responses into *Error.
+func (c *Client) do(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.delay(attempt, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(ctx, method, target, body)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil || !retryable(method, err) || attempt >= c.maxRetries {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	return nil, decodeError(resp)
+}
+
+// decodeError reads both the native error body and RFC 9457
+// problem details, which carry the code at the top level.
+func decodeError(resp *http.Response) error {
+	var body struct {
+		Error struct {
+			Code       ErrorCode   `json:"code"`
+			Message    string      `json:"message"`
+			Violations []Violation `json:"violations"`
+		} `json:"error"`
+		Code       ErrorCode   `json:"code"`
+		Detail     string      `json:"detail"`
+		Violations []Violation `json:"violations"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Code:       body.Error.Code,
+		Message:    body.Error.Message,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		Violations: body.Error.Violations,
+	}
+
+	if e.Code == "" {
+		e.Code = body.Code
+		e.Message = body.Detail
+		e.Violations = body.Violations
+	}
+
+	// Older servers reported expired keys as a generic conflict
+	if e.Code == "" || (resp.StatusCode == http.StatusGone && e.Code == CodeConflict) {
+		e.Code = codeForStatus(resp.StatusCode)
+	}
+
+	if ra, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(ra) * time.Second
+	}
+
+	return e
+}
+
+// transportError marks failures where no response was received.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// retryable reports whether a request that failed with err can be
+// sent again without risking applying it twice.
+func retryable(method string, err error) bool {
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+
+	var te *transportError
+	if errors.As(err, &te) {
+		// A request that never reached the server is safe to resend
+		var op *net.OpError
+		return idempotent || (errors.As(err, &op) && op.Op == "dial")
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		switch {
+		case e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable:
+			return true
+		case e.StatusCode >= 500:
+			return idempotent
+		}
+	}
+
+	return false
+}
+
+// delay returns the wait before the given attempt: exponential
+// backoff with jitter, or the server's Retry-After if longer.
+func (c *Client) delay(attempt int, lastErr error) time.Duration {
+	d := min(c.backoff<<(attempt-1), maxBackoff)
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+
+	var e *Error
+	if errors.As(lastErr, &e) && e.RetryAfter > d {
+		d = e.RetryAfter
+	}
+
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func unixTime(v *int64) *time.Time {
+	if v == nil {
+		return nil
+	}
+	t := time.Unix(*v, 0)
+	return &t
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mediaType) == "application/json"
+}
+
+// isDocument reports whether contentType is a YAML or XML type.
+func isDocument(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", "application/xml", "text/xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+yaml") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,458 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/pkg/client"
+	"github.com/hritikkanojiya/kvtxt/pkg/client/clienttest"
+)
+
+func newFake(t *testing.T, apiKeys ...string) (*clienttest.Server, *client.Client) {
+	t.Helper()
+
+	srv := clienttest.NewServer(apiKeys...)
+	t.Cleanup(srv.Close)
+
+	return srv, srv.Client(client.WithRetries(3, time.Millisecond))
+}
+
+func TestCreateGetMetaDelete(t *testing.T) {
+	srv, c := newFake(t)
+	ctx := context.Background()
+
+	entry, err := c.Create(ctx, []byte(`he said "hi"`), client.WithTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if entry.Key == "" || entry.URL != srv.URL+"/v1/kv/"+entry.Key {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.ExpiresAt == nil || time.Until(*entry.ExpiresAt) > time.Hour {
+		t.Errorf("ExpiresAt = %v", entry.ExpiresAt)
+	}
+
+	value, err := c.Get(ctx, entry.Key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(value.Data) != `he said "hi"` {
+		t.Errorf("Get = %q, want the text unquoted", value.Data)
+	}
+
+	meta, err := c.Meta(ctx, entry.Key)
+	if err != nil {
+		t.Fatalf("Meta: %v", err)
+	}
+	if meta.ContentType != "text/plain; charset=utf-8" || meta.ExpiresAt == nil || meta.CreatedAt.IsZero() {
+		t.Errorf("Meta = %+v", meta)
+	}
+
+	if err := c.Delete(ctx, entry.Key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if srv.Len() != 0 {
+		t.Errorf("Len = %d after Delete", srv.Len())
+	}
+}
+
+func TestCreateJSON(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	entry, err := c.Create(ctx, []byte(`{"a":1}`), client.WithContentType("application/json"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	value, err := c.Get(ctx, entry.Key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(value.Data) != `{"a":1}` || value.ContentType != "application/json" {
+		t.Errorf("Get = %q %q", value.Data, value.ContentType)
+	}
+
+	if _, err := c.Create(ctx, []byte(`{`), client.WithContentType("application/json")); err == nil {
+		t.Error("Create accepted invalid JSON")
+	}
+}
+
+func TestCreateDocuments(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	// A YAML or XML document is returned as it was given, even when
+	// it looks like a JSON string
+	for _, tt := range []struct{ value, contentType string }{
+		{"\"quoted\"\n", "application/yaml"},
+		{"a: [1, 2]\n", "text/yaml; charset=utf-8"},
+		{`<a href="x">&amp;</a>`, "application/xml"},
+		{"\"text\"", "text/plain; charset=utf-8"},
+	} {
+		entry, err := c.Create(ctx, []byte(tt.value), client.WithContentType(tt.contentType))
+		if err != nil {
+			t.Fatalf("Create %s: %v", tt.contentType, err)
+		}
+
+		value, err := c.Get(ctx, entry.Key)
+		if err != nil || string(value.Data) != tt.value {
+			t.Errorf("Get %s = %q, %v; want %q", tt.contentType, value.Data, err, tt.value)
+		}
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	// Counters are JSON numbers; text values are stored quoted
+	if _, err := c.Create(ctx, []byte("41"), client.WithKey("counter"), client.WithContentType("application/json")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for _, tt := range []struct{ by, want int64 }{{1, 42}, {-50, -8}} {
+		got, err := c.Increment(ctx, "counter", tt.by)
+		if err != nil || got != tt.want {
+			t.Errorf("Increment(%d) = %d, %v; want %d", tt.by, got, err, tt.want)
+		}
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		call   func(srv *clienttest.Server, c *client.Client) error
+		want   error
+		status int
+	}{
+		{
+			name: "get missing",
+			call: func(_ *clienttest.Server, c *client.Client) error {
+				_, err := c.Get(ctx, "missing")
+				return err
+			},
+			want:   client.ErrNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "meta missing",
+			call: func(_ *clienttest.Server, c *client.Client) error {
+				_, err := c.Meta(ctx, "missing")
+				return err
+			},
+			want:   client.ErrNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "get expired",
+			call: func(srv *clienttest.Server, c *client.Client) error {
+				e, err := c.Create(ctx, []byte("x"))
+				if err != nil {
+					return err
+				}
+				srv.Expire(e.Key)
+				_, err = c.Get(ctx, e.Key)
+				return err
+			},
+			want:   client.ErrExpired,
+			status: http.StatusGone,
+		},
+		{
+			name: "meta expired",
+			call: func(srv *clienttest.Server, c *client.Client) error {
+				e, err := c.Create(ctx, []byte("x"))
+				if err != nil {
+					return err
+				}
+				srv.Expire(e.Key)
+				_, err = c.Meta(ctx, e.Key)
+				return err
+			},
+			want:   client.ErrExpired,
+			status: http.StatusGone,
+		},
+		{
+			name: "mistyped key",
+			call: func(srv *clienttest.Server, c *client.Client) error {
+				srv.FailNextError(http.StatusNotFound, client.CodeKeyMistyped)
+				_, err := c.Get(ctx, "abc")
+				return err
+			},
+			want:   client.ErrKeyMistyped,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "chosen key taken",
+			call: func(_ *clienttest.Server, c *client.Client) error {
+				if _, err := c.Create(ctx, []byte("x"), client.WithKey("taken")); err != nil {
+					return err
+				}
+				_, err := c.Create(ctx, []byte("y"), client.WithKey("taken"))
+				return err
+			},
+			want:   client.ErrConflict,
+			status: http.StatusConflict,
+		},
+		{
+			name: "increment non-integer",
+			call: func(_ *clienttest.Server, c *client.Client) error {
+				if _, err := c.Create(ctx, []byte("abc"), client.WithKey("text")); err != nil {
+					return err
+				}
+				_, err := c.Increment(ctx, "text", 1)
+				return err
+			},
+			want:   client.ErrTypeMismatch,
+			status: http.StatusConflict,
+		},
+		{
+			name: "lost update",
+			call: func(srv *clienttest.Server, c *client.Client) error {
+				srv.FailNextError(http.StatusPreconditionFailed, client.CodePreconditionFailed)
+				return c.Delete(ctx, "abc")
+			},
+			want:   client.ErrPreconditionFailed,
+			status: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := newFake(t)
+
+			err := tt.call(srv, c)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+
+			var e *client.Error
+			if !errors.As(err, &e) || e.StatusCode != tt.status {
+				t.Errorf("error = %#v, want status %d", err, tt.status)
+			}
+
+			// Sentinels match by code only
+			if tt.want != client.ErrConflict && errors.Is(err, client.ErrConflict) {
+				t.Errorf("%v also matches ErrConflict", err)
+			}
+		})
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	srv, _ := newFake(t, "secret-api-key-0123456789")
+
+	c := client.New(srv.URL, client.WithAPIKey("wrong"), client.WithRetries(3, time.Millisecond))
+	_, err := c.Get(context.Background(), "abc")
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("error = %v, want ErrUnauthorized", err)
+	}
+
+	if _, err := srv.Client().Create(context.Background(), []byte("x")); err != nil {
+		t.Errorf("Create with the configured key: %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		failures []int
+		wantErr  bool
+		attempts int
+	}{
+		{"503 then success", []int{503}, false, 2},
+		{"500 502 504 then success", []int{500, 502, 504}, false, 4},
+		{"429 then success", []int{429}, false, 2},
+		{"retries exhausted", []int{503, 503, 503, 503}, true, 4},
+		{"400 not retried", []int{400}, true, 1},
+		{"404 not retried", []int{404}, true, 1},
+		{"409 not retried", []int{409}, true, 1},
+		{"412 not retried", []int{412}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := newFake(t)
+
+			entry, err := c.Create(ctx, []byte("x"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := srv.Requests()
+
+			srv.FailNext(tt.failures...)
+			_, err = c.Get(ctx, entry.Key)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := srv.Requests() - before; got != tt.attempts {
+				t.Errorf("requests = %d, want %d", got, tt.attempts)
+			}
+
+			var e *client.Error
+			if tt.wantErr && (!errors.As(err, &e) || e.StatusCode != tt.failures[len(tt.failures)-1]) {
+				t.Errorf("error = %#v, want the last injected status", err)
+			}
+		})
+	}
+}
+
+// TestRetriesNotIdempotent checks that Create and Increment are only
+// retried when the server rejected them without processing them.
+func TestRetriesNotIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		status int
+		retry  bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusInternalServerError, false},
+		{http.StatusBadGateway, false},
+		{http.StatusGatewayTimeout, false},
+	}
+
+	for _, tt := range tests {
+		srv, c := newFake(t)
+
+		entry, err := c.Create(ctx, []byte("0"), client.WithContentType("application/json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := map[string]func() error{
+			"Create": func() error {
+				_, err := c.Create(ctx, []byte("x"))
+				return err
+			},
+			"Increment": func() error {
+				_, err := c.Increment(ctx, entry.Key, 1)
+				return err
+			},
+		}
+
+		for name, call := range calls {
+			before := srv.Requests()
+			srv.FailNext(tt.status)
+
+			err := call()
+			attempts := srv.Requests() - before
+
+			if tt.retry && (err != nil || attempts != 2) {
+				t.Errorf("%s after %d = %v in %d requests, want a retry", name, tt.status, err, attempts)
+			}
+			if !tt.retry && (err == nil || attempts != 1) {
+				t.Errorf("%s after %d = %v in %d requests, want no retry", name, tt.status, err, attempts)
+			}
+		}
+	}
+}
+
+// TestRetriesLostResponse checks that a request whose response was
+// lost is only resent when it is idempotent.
+func TestRetriesLostResponse(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		// Drop the connection after the request arrived
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL, client.WithRetries(2, time.Millisecond))
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := requests
+		requests = 0
+		return n
+	}
+
+	if _, err := c.Get(context.Background(), "abc"); err == nil {
+		t.Fatal("Get succeeded")
+	}
+	if n := count(); n != 3 {
+		t.Errorf("Get sent %d requests, want 3", n)
+	}
+
+	if _, err := c.Create(context.Background(), []byte("x")); err == nil {
+		t.Fatal("Create succeeded")
+	}
+	if n := count(); n != 1 {
+		t.Errorf("Create sent %d requests, want 1", n)
+	}
+
+	// Requests to a server that cannot be reached were never sent
+	attempts := 0
+	refused := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		attempts++
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+
+	c = client.New("http://kv.invalid", client.WithRetries(2, time.Millisecond), client.WithHTTPClient(&http.Client{Transport: refused}))
+	if _, err := c.Create(context.Background(), []byte("x")); err == nil {
+		t.Fatal("Create succeeded against an unreachable server")
+	}
+	if attempts != 3 {
+		t.Errorf("Create tried to connect %d times, want 3", attempts)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRetriesDisabled(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+
+	c := srv.Client(client.WithRetries(0, 0))
+
+	srv.FailNext(http.StatusServiceUnavailable)
+	if _, err := c.Create(context.Background(), []byte("x")); err == nil {
+		t.Fatal("Create succeeded despite an injected 503")
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+
+	c := srv.Client(client.WithRetries(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	srv.FailNext(http.StatusServiceUnavailable)
+	_, err := c.Get(ctx, "abc")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
@@ -0,0 +1,336 @@
+// Package clienttest provides an in-memory fake kvtxt server for
+// testing code that uses package client.
+//
+//	srv := clienttest.NewServer()
+//	defer srv.Close()
+//	c := srv.Client()
+//
+// The fake implements create, get, head, delete and increment with
+// the same status codes and error bodies as kvtxt. It keeps no namespaces:
+// any configured API key sees every entry, and chosen keys are not
+// checked against the server's charset rules.
+package clienttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hritikkanojiya/kvtxt/internal/contenttype"
+	"github.com/hritikkanojiya/kvtxt/pkg/client"
+)
+
+const defaultTTL = 60 * 24 * time.Hour
+
+type entry struct {
+	text        []byte
+	contentType string
+	createdAt   time.Time
+	expiresAt   time.Time
+}
+
+// Server is a fake kvtxt server backed by httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	apiKeys  map[string]struct{}
+	failures []failure
+	requests int
+	nextKey  int
+}
+
+type failure struct {
+	status int
+	code   client.ErrorCode
+}
+
+// NewServer starts a fake server. With apiKeys given, requests must
+// authenticate with one of them.
+func NewServer(apiKeys ...string) *Server {
+	s := &Server{
+		entries: make(map[string]*entry),
+		apiKeys: make(map[string]struct{}),
+	}
+
+	for _, k := range apiKeys {
+		s.apiKeys[k] = struct{}{}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client for the fake, authenticated with the first
+// API key if any were configured.
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	s.mu.Lock()
+	for k := range s.apiKeys {
+		opts = append([]client.Option{client.WithAPIKey(k)}, opts...)
+		break
+	}
+	s.mu.Unlock()
+
+	return client.New(s.URL, opts...)
+}
+
+// FailNext makes the next len(statuses) requests fail with the given
+// status codes, for exercising retries.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, status := range statuses {
+		s.failures = append(s.failures, failure{status: status, code: client.CodeInternal})
+	}
+}
+
+// FailNextError makes the next request fail with the given status
+// and error code, for responses the fake cannot otherwise produce,
+// such as KEY_MISTYPED.
+func (s *Server) FailNextError(status int, code client.ErrorCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, code: code})
+}
+
+// Requests returns the number of requests received, injected
+// failures included.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Expire makes an entry expired, as if its TTL had passed.
+func (s *Server) Expire(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.expiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+// Len returns the number of stored entries, expired ones included.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, f.status, f.code, "Injected failure")
+		return
+	}
+
+	if len(s.apiKeys) > 0 {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, ok := s.apiKeys[token]; !ok {
+			writeError(w, http.StatusUnauthorized, client.CodeUnauthorized, "Invalid or missing API key")
+			return
+		}
+	}
+
+	if r.URL.Path == "/v1/kv" && r.Method == http.MethodPost {
+		s.create(w, r)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if !ok || key == "" {
+		writeError(w, http.StatusNotFound, client.CodeNotFound, "Not found")
+		return
+	}
+
+	if k, ok := strings.CutSuffix(key, ":increment"); ok && r.Method == http.MethodPost {
+		s.increment(w, r, k)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.get(w, r, key)
+	case http.MethodDelete:
+		s.delete(w, key)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, client.CodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key         string          `json:"key"`
+		Text        json.RawMessage `json:"text"`
+		ContentType string          `json:"content_type"`
+		TTLSeconds  *int64          `json:"ttl_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, client.CodeInvalidJSON, "Invalid JSON body")
+		return
+	}
+
+	if len(req.Text) == 0 {
+		writeError(w, http.StatusBadRequest, client.CodeTextRequired, "Text is required")
+		return
+	}
+
+	if req.ContentType == "" {
+		req.ContentType = "text/plain; charset=utf-8"
+	}
+
+	// YAML and XML are sent as a string and stored as the document
+	text := []byte(req.Text)
+	switch contenttype.KindOf(contenttype.MediaType(req.ContentType)) {
+	case contenttype.YAML, contenttype.XML:
+		var doc string
+		if err := json.Unmarshal(req.Text, &doc); err != nil {
+			writeError(w, http.StatusBadRequest, client.CodeInvalidText, "Text must be a string for "+req.ContentType)
+			return
+		}
+		text = []byte(doc)
+	}
+
+	ttl := defaultTTL
+	if req.TTLSeconds != nil {
+		if *req.TTLSeconds < 1 {
+			writeError(w, http.StatusBadRequest, client.CodeTTLOutOfRange, "TTL is below minimum allowed")
+			return
+		}
+		ttl = time.Duration(*req.TTLSeconds) * time.Second
+	}
+
+	now := time.Now()
+
+	key := req.Key
+	if key == "" {
+		s.nextKey++
+		key = "k" + strconv.Itoa(s.nextKey)
+	} else if e, ok := s.entries[key]; ok && e.expiresAt.After(now) {
+		writeError(w, http.StatusConflict, client.CodeConflict, "Key already exists")
+		return
+	}
+
+	s.entries[key] = &entry{
+		text:        text,
+		contentType: req.ContentType,
+		createdAt:   now,
+		expiresAt:   now.Add(ttl),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"key":        key,
+		"expires_at": now.Add(ttl).Unix(),
+	})
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	e, ok := s.entries[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, client.CodeNotFound, "Not found")
+		return
+	}
+
+	if !e.expiresAt.After(time.Now()) {
+		writeError(w, http.StatusGone, client.CodeKeyExpired, "Key expired")
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", e.contentType)
+	h.Set("Content-Length", strconv.Itoa(len(e.text)))
+	h.Set("X-Kvtxt-Created-At", strconv.FormatInt(e.createdAt.Unix(), 10))
+	h.Set("X-Kvtxt-Expires-At", strconv.FormatInt(e.expiresAt.Unix(), 10))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodGet {
+		w.Write(e.text)
+	}
+}
+
+func (s *Server) delete(w http.ResponseWriter, key string) {
+	if _, ok := s.entries[key]; !ok {
+		writeError(w, http.StatusNotFound, client.CodeNotFound, "Not found")
+		return
+	}
+
+	delete(s.entries, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) increment(w http.ResponseWriter, r *http.Request, key string) {
+	var req struct {
+		By *int64 `json:"by"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, client.CodeInvalidJSON, "Invalid JSON body")
+			return
+		}
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, client.CodeNotFound, "Not found")
+		return
+	}
+
+	if !e.expiresAt.After(time.Now()) {
+		writeError(w, http.StatusGone, client.CodeKeyExpired, "Key expired")
+		return
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(string(e.text)), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusConflict, client.CodeTypeMismatch, "Value is not an integer")
+		return
+	}
+
+	by := int64(1)
+	if req.By != nil {
+		by = *req.By
+	}
+
+	n += by
+	e.text = []byte(strconv.FormatInt(n, 10))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"key":        key,
+		"value":      n,
+		"expires_at": e.expiresAt.Unix(),
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, code client.ErrorCode, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	var body struct {
+		Error struct {
+			Code    client.ErrorCode `json:"code"`
+			Message string           `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = code
+	body.Error.Message = msg
+
+	json.NewEncoder(w).Encode(body)
+}
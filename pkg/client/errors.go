@@ -0,0 +1,146 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCode is the machine-readable code in kvtxt error responses.
+type ErrorCode string
+
+const (
+	CodeBadRequest          ErrorCode = "BAD_REQUEST"
+	CodeInvalidJSON         ErrorCode = "INVALID_JSON"
+	CodeTextRequired        ErrorCode = "TEXT_REQUIRED"
+	CodeInvalidText         ErrorCode = "INVALID_TEXT"
+	CodeInvalidKey          ErrorCode = "INVALID_KEY"
+	CodeInvalidPath         ErrorCode = "INVALID_PATH"
+	CodeInvalidPatch        ErrorCode = "INVALID_PATCH"
+	CodeInvalidSchema       ErrorCode = "INVALID_SCHEMA"
+	CodeKeyMistyped         ErrorCode = "KEY_MISTYPED"
+	CodeTTLOutOfRange       ErrorCode = "TTL_OUT_OF_RANGE"
+	CodePayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedType     ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	CodeMethodNotAllowed    ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable       ErrorCode = "NOT_ACCEPTABLE"
+	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeNotFound            ErrorCode = "NOT_FOUND"
+	CodePathNotFound        ErrorCode = "PATH_NOT_FOUND"
+	CodeKeyExpired          ErrorCode = "KEY_EXPIRED"
+	CodeConflict            ErrorCode = "CONFLICT"
+	CodeTypeMismatch        ErrorCode = "TYPE_MISMATCH"
+	CodePatchFailed         ErrorCode = "PATCH_FAILED"
+	CodeSchemaViolation     ErrorCode = "SCHEMA_VIOLATION"
+	CodePreconditionFailed  ErrorCode = "PRECONDITION_FAILED"
+	CodePreconditionNeeded  ErrorCode = "PRECONDITION_REQUIRED"
+	CodeQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	CodeEncryptionFailed    ErrorCode = "ENCRYPTION_FAILED"
+	CodeDecryptionFailed    ErrorCode = "DECRYPTION_FAILED"
+	CodeKeyGenerationFailed ErrorCode = "KEY_GENERATION_FAILED"
+	CodeStorage             ErrorCode = "STORAGE_ERROR"
+	CodeNotReady            ErrorCode = "NOT_READY"
+	CodeInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+// Error is returned for any non-2xx response.
+// Use errors.Is with the sentinel values below to test for a kind
+// of failure, or errors.As to inspect the details.
+type Error struct {
+	StatusCode int
+	Code       ErrorCode
+	Message    string
+	RequestID  string
+
+	// RetryAfter is the server's Retry-After hint, if any.
+	RetryAfter time.Duration
+
+	// Violations lists why a value did not conform to its schema.
+	Violations []Violation
+}
+
+// Violation is a failed schema constraint. Path is a JSON Pointer
+// into the value, "" for the whole value.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kvtxt: %d %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("kvtxt: %s: %s", e.Code, e.Message)
+}
+
+// Is matches sentinels by code, or by status when the sentinel has
+// no code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Code != "" {
+		return t.Code == e.Code
+	}
+	return t.StatusCode == e.StatusCode
+}
+
+var (
+	ErrBadRequest       = &Error{Code: CodeBadRequest}
+	ErrInvalidJSON      = &Error{Code: CodeInvalidJSON}
+	ErrInvalidKey       = &Error{Code: CodeInvalidKey}
+	ErrKeyMistyped      = &Error{Code: CodeKeyMistyped}
+	ErrTTLOutOfRange    = &Error{Code: CodeTTLOutOfRange}
+	ErrPayloadTooLarge  = &Error{Code: CodePayloadTooLarge}
+	ErrMethodNotAllowed = &Error{Code: CodeMethodNotAllowed}
+	ErrUnauthorized     = &Error{Code: CodeUnauthorized}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrConflict         = &Error{Code: CodeConflict}
+	ErrTypeMismatch     = &Error{Code: CodeTypeMismatch}
+	ErrQuotaExceeded    = &Error{Code: CodeQuotaExceeded}
+	ErrInternal         = &Error{Code: CodeInternal}
+
+	// ErrExpired matches entries whose TTL has passed.
+	ErrExpired = &Error{Code: CodeKeyExpired}
+
+	// ErrPreconditionFailed matches conditional updates that lost a
+	// race: the entry changed since it was read.
+	ErrPreconditionFailed = &Error{Code: CodePreconditionFailed}
+
+	// ErrSchemaViolation matches values rejected by a JSON Schema;
+	// the Violations field of the error lists the failed paths.
+	ErrSchemaViolation = &Error{Code: CodeSchemaViolation}
+)
+
+// codeForStatus fills in a code when the response carried no body,
+// as is always the case for HEAD requests.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeQuotaExceeded
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusNotAcceptable:
+		return CodeNotAcceptable
+	case http.StatusGone:
+		return CodeKeyExpired
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedType
+	case http.StatusUnprocessableEntity:
+		return CodeSchemaViolation
+	case http.StatusServiceUnavailable:
+		return CodeNotReady
+	}
+	return CodeInternal
+}
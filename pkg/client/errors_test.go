@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		want       *Error
+		violations int
+	}{
+		{
+			name:   "native body",
+			status: http.StatusNotFound,
+			body:   `{"error":{"code":"KEY_MISTYPED","message":"Key has a typo"}}`,
+			want:   &Error{StatusCode: 404, Code: CodeKeyMistyped, Message: "Key has a typo"},
+		},
+		{
+			name:   "problem details",
+			status: http.StatusConflict,
+			header: http.Header{"Content-Type": {"application/problem+json"}},
+			body:   `{"type":"about:blank","title":"Conflict","status":409,"code":"CONFLICT","detail":"Key already exists"}`,
+			want:   &Error{StatusCode: 409, Code: CodeConflict, Message: "Key already exists"},
+		},
+		{
+			name:       "violations",
+			status:     http.StatusUnprocessableEntity,
+			body:       `{"error":{"code":"SCHEMA_VIOLATION","message":"Value does not conform","violations":[{"path":"/a","message":"bad"}]}}`,
+			want:       &Error{StatusCode: 422, Code: CodeSchemaViolation, Message: "Value does not conform"},
+			violations: 1,
+		},
+		{
+			name:       "problem violations",
+			status:     http.StatusUnprocessableEntity,
+			body:       `{"status":422,"code":"SCHEMA_VIOLATION","detail":"no","violations":[{"path":"","message":"x"},{"path":"/b","message":"y"}]}`,
+			want:       &Error{StatusCode: 422, Code: CodeSchemaViolation, Message: "no"},
+			violations: 2,
+		},
+		{
+			name:   "empty body",
+			status: http.StatusGone,
+			want:   &Error{StatusCode: 410, Code: CodeKeyExpired},
+		},
+		{
+			name:   "legacy expired conflict",
+			status: http.StatusGone,
+			body:   `{"error":{"code":"CONFLICT","message":"Key expired"}}`,
+			want:   &Error{StatusCode: 410, Code: CodeKeyExpired, Message: "Key expired"},
+		},
+		{
+			name:   "unknown status",
+			status: http.StatusTeapot,
+			body:   `not json`,
+			want:   &Error{StatusCode: 418, Code: CodeInternal},
+		},
+		{
+			name:   "retry after and request ID",
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": {"7"}, "X-Request-Id": {"req-1"}},
+			body:   `{"error":{"code":"INTERNAL_ERROR","message":"slow down"}}`,
+			want: &Error{StatusCode: 429, Code: CodeInternal, Message: "slow down",
+				RequestID: "req-1", RetryAfter: 7 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			for k, v := range tt.header {
+				rec.Header()[k] = v
+			}
+			rec.WriteHeader(tt.status)
+			rec.WriteString(tt.body)
+
+			err := decodeError(rec.Result())
+
+			var got *Error
+			if !errors.As(err, &got) {
+				t.Fatalf("decodeError returned %T", err)
+			}
+
+			if got.StatusCode != tt.want.StatusCode || got.Code != tt.want.Code || got.Message != tt.want.Message ||
+				got.RequestID != tt.want.RequestID || got.RetryAfter != tt.want.RetryAfter {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if len(got.Violations) != tt.violations {
+				t.Errorf("violations = %v, want %d", got.Violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := error(&Error{StatusCode: 404, Code: CodeNotFound})
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("not ErrNotFound")
+	}
+	if errors.Is(err, ErrKeyMistyped) || errors.Is(err, ErrExpired) {
+		t.Error("matches a sentinel of another code")
+	}
+	if !errors.Is(err, &Error{StatusCode: 404}) {
+		t.Error("does not match a status-only target")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	c := New("http://kv", WithRetries(5, 100*time.Millisecond))
+
+	for attempt := 1; attempt <= 8; attempt++ {
+		full := min(100*time.Millisecond<<(attempt-1), maxBackoff)
+		d := c.delay(attempt, errors.New("x"))
+		if d < full/2 || d > full {
+			t.Errorf("delay(%d) = %v, want within [%v, %v]", attempt, d, full/2, full)
+		}
+	}
+
+	if d := c.delay(1, &Error{RetryAfter: time.Minute}); d != time.Minute {
+		t.Errorf("delay with Retry-After = %v, want 1m", d)
+	}
+}
@@ -0,0 +1,244 @@
+// Server wires routes and middleware around caller-supplied
+// storage, crypto and cache instances.
+
+package kvtxt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/hritikkanojiya/kvtxt/internal/api"
+	"github.com/hritikkanojiya/kvtxt/internal/audit"
+	"github.com/hritikkanojiya/kvtxt/internal/keylock"
+	"github.com/hritikkanojiya/kvtxt/internal/metrics"
+	"github.com/hritikkanojiya/kvtxt/internal/namespace"
+	"github.com/hritikkanojiya/kvtxt/internal/worker"
+)
+
+// Options configures NewServer. Storage and Crypto are required.
+type Options struct {
+	Storage *Storage
+	Crypto  *Crypto
+
+	// Cache defaults to a new cache sized by Config.CacheSize.
+	Cache *Cache
+
+	// Config defaults to DefaultConfig. Namespaces, limits, admin key,
+	// audit and trusted proxies are taken from it.
+	Config *Config
+
+	// Tracing receives the spans of this server's requests. Without
+	// it requests are not traced.
+	Tracing *TraceExporter
+}
+
+// Server is an http.Handler serving the kvtxt API.
+type Server struct {
+	handler  http.Handler
+	mux      *api.Router
+	registry *namespace.Registry
+	cache    *Cache
+	cleanup  *worker.CleanupWorker
+	maxBatch atomic.Int64
+}
+
+func NewServer(opts Options) (*Server, error) {
+	if opts.Storage == nil || opts.Crypto == nil {
+		return nil, errors.New("kvtxt: Storage and Crypto are required")
+	}
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	c := opts.Cache
+	if c == nil {
+		c = NewCache(cfg.CacheSize)
+	}
+
+	store := opts.Storage
+
+	registry, err := namespace.NewRegistry(cfg, opts.Crypto)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each server reports its own figures, so that several can run
+	// in one process
+	reg := metrics.NewRegistry()
+
+	s := &Server{
+		registry: registry,
+		cache:    c,
+		cleanup:  worker.NewCleanupWorker(store, cfg.CleanupInterval, reg),
+	}
+	s.maxBatch.Store(int64(cfg.MaxBatchSize))
+
+	// Audit is optional; without it the middleware is a no-op
+	withAudit := func(action audit.Action) func(api.HandlerFunc) api.HandlerFunc {
+		return func(h api.HandlerFunc) api.HandlerFunc { return h }
+	}
+
+	if cfg.AuditEnabled {
+		auditLog, err := audit.New(store)
+		if err != nil {
+			return nil, err
+		}
+
+		withAudit = func(action audit.Action) func(api.HandlerFunc) api.HandlerFunc {
+			return api.Audit(auditLog, action)
+		}
+	}
+
+	// Serializes updates of a key with the cache writes around them
+	locks := keylock.New()
+
+	mux := api.NewRouter()
+
+	api.RegisterRoute(
+		mux,
+		"/liveness",
+		http.MethodGet,
+		api.Liveness(),
+	)
+
+	api.RegisterRoute(
+		mux,
+		"/readiness",
+		http.MethodGet,
+		api.Readiness(store),
+	)
+
+	api.RegisterRoute(
+		mux,
+		"/metrics",
+		http.MethodGet,
+		api.MetricsHandler(reg),
+	)
+
+	api.RegisterRoute(
+		mux,
+		"/openapi.json",
+		http.MethodGet,
+		api.OpenAPI(),
+	)
+
+	mux.HandleMethods(
+		"/v1/kv",
+		withAudit(audit.ActionCreate)(
+			api.Authenticate(registry)(
+				api.CreateKV(store, c, locks),
+			),
+		),
+		http.MethodPost,
+	)
+
+	mux.HandleMethods(
+		"/v1/kv:batch",
+		withAudit(audit.ActionCreate)(
+			api.Authenticate(registry)(
+				api.CreateBatch(store, c, locks, s.maxBatchSize),
+			),
+		),
+		http.MethodPost,
+	)
+
+	mux.HandleMethods(
+		"/v1/kv:batchGet",
+		withAudit(audit.ActionRead)(
+			api.Authenticate(registry)(
+				api.GetBatch(store, c, locks, s.maxBatchSize),
+			),
+		),
+		http.MethodPost,
+	)
+
+	mux.HandleByMethod(
+		"/v1/kv/",
+		map[string]api.HandlerFunc{
+			http.MethodGet: withAudit(audit.ActionRead)(
+				api.Authenticate(registry)(
+					api.GetKV(store, c, locks),
+				),
+			),
+			http.MethodHead: withAudit(audit.ActionRead)(
+				api.Authenticate(registry)(
+					api.MetaKV(store),
+				),
+			),
+			http.MethodPut: withAudit(audit.ActionUpdate)(
+				api.Authenticate(registry)(
+					api.ReplaceKV(store, c, locks),
+				),
+			),
+			http.MethodPatch: withAudit(audit.ActionUpdate)(
+				api.Authenticate(registry)(
+					api.PatchKV(store, c, locks),
+				),
+			),
+			http.MethodPost: withAudit(audit.ActionUpdate)(
+				api.Authenticate(registry)(
+					api.OperateKV(store, c, locks),
+				),
+			),
+			http.MethodDelete: withAudit(audit.ActionDelete)(
+				api.Authenticate(registry)(
+					api.DeleteKV(store, c, locks),
+				),
+			),
+		},
+	)
+
+	if cfg.AdminKey != "" {
+		registerAdminRoutes(mux, cfg.AdminKey, store, registry, c, s.cleanup)
+	}
+
+	reg.Register(opts.Crypto.Metrics())
+	registerRuntimeMetrics(reg, store, c)
+
+	var handler http.Handler = mux
+	handler = api.Metrics(metrics.NewHTTP(reg))(handler)
+	handler = api.MaxPayloadSize(registry.MaxPayloadSize)(handler)
+	handler = api.Logging(handler)
+	handler = api.ClientIP(cfg.TrustedProxies)(handler)
+	handler = api.ErrorFormat(cfg.ErrorFormat)(handler)
+	handler = api.Tracing(opts.Tracing)(handler)
+	handler = api.RequestID(handler)
+
+	s.handler = handler
+	s.mux = mux
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) maxBatchSize() int {
+	return int(s.maxBatch.Load())
+}
+
+// StartCleanup removes expired entries periodically until ctx is done.
+func (s *Server) StartCleanup(ctx context.Context) {
+	s.cleanup.Start(ctx)
+}
+
+// Reload applies the reloadable parts of cfg: namespaces and their
+// limits, TTL bounds, key generation, batch size, cache size and
+// cleanup interval.
+// On error the running settings are kept.
+func (s *Server) Reload(cfg *Config) error {
+	if err := s.registry.Reload(cfg); err != nil {
+		return err
+	}
+
+	s.cache.Resize(cfg.CacheSize)
+	s.cleanup.SetInterval(cfg.CleanupInterval)
+	s.maxBatch.Store(int64(cfg.MaxBatchSize))
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package kvtxt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, cfg *Config) *Server {
+	t.Helper()
+
+	store, err := OpenStorage(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("OpenStorage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypt, err := NewCrypto(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer(Options{Storage: store, Crypto: crypt, Config: cfg})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return srv
+}
+
+// TestRoutesDocumented checks that every route and method the server
+// registers is described in the OpenAPI document it serves.
+func TestRoutesDocumented(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminKey = "admin-key-0123456789abcdef"
+
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d", rec.Code)
+	}
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("parse openapi.json: %v", err)
+	}
+
+	routes := srv.mux.Routes()
+	if len(routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+
+	admin := false
+	for _, route := range routes {
+		admin = admin || strings.HasPrefix(route.Pattern, "/admin/")
+
+		if len(route.Methods) == 0 {
+			t.Errorf("%s: no methods registered", route.Pattern)
+		}
+
+		for _, method := range route.Methods {
+			if !documented(spec.Paths, route.Pattern, strings.ToLower(method)) {
+				t.Errorf("%s %s is not described in openapi.json", method, route.Pattern)
+			}
+		}
+	}
+
+	if !admin {
+		t.Error("admin routes were not registered")
+	}
+}
+
+// documented reports whether a path of the document serves method for
+// pattern. A subtree pattern such as "/v1/kv/" is matched by the
+// templated paths below it.
+func documented(paths map[string]map[string]json.RawMessage, pattern, method string) bool {
+	if ops, ok := paths[pattern]; ok {
+		_, ok := ops[method]
+		return ok
+	}
+
+	if !strings.HasSuffix(pattern, "/") {
+		return false
+	}
+
+	for path, ops := range paths {
+		if strings.HasPrefix(path, pattern) && len(path) > len(pattern) {
+			if _, ok := ops[method]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func TestMetricsIncludeRuntimeFigures(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, name := range []string{"kvtxt_cache_hits_total", "kvtxt_db_size_bytes", "kvtxt_entries", "kvtxt_http_requests_total"} {
+		if n := strings.Count(body, "# TYPE "+name+" "); n != 1 {
+			t.Errorf("%s declared %d times, want 1", name, n)
+		}
+	}
+}
+
+// TestServersHaveSeparateMetrics checks that servers in one process
+// report only their own requests.
+func TestServersHaveSeparateMetrics(t *testing.T) {
+	a := newTestServer(t, nil)
+	b := newTestServer(t, nil)
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	scrape := func(srv *Server) string {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	if body := scrape(a); !strings.Contains(body, `route="/openapi.json"`) {
+		t.Error("server a did not count its request")
+	}
+	if body := scrape(b); strings.Contains(body, `route="/openapi.json"`) {
+		t.Error("server b counted a request made to server a")
+	}
+}
+
+func TestReload(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	cfg := DefaultConfig()
+	cfg.MaxBatchSize = 7
+	cfg.CacheSize = 3
+	if err := srv.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.maxBatchSize(); got != 7 {
+		t.Errorf("max batch size = %d, want 7", got)
+	}
+	if got := srv.cache.Stats().MaxSize; got != 3 {
+		t.Errorf("cache size = %d, want 3", got)
+	}
+
+	// A rejected configuration changes nothing
+	bad := DefaultConfig()
+	bad.MaxBatchSize = 9
+	bad.KeyStrategy = "unknown"
+	if err := srv.Reload(bad); err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+	if got := srv.maxBatchSize(); got != 7 {
+		t.Errorf("max batch size after rejected reload = %d, want 7", got)
+	}
+}